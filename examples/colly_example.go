@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"strings"
 	"time"
@@ -18,13 +19,15 @@ func main() {
 
 	fmt.Println("=== Тестирование интеграции Colly с go-readability ===\n")
 
+	ctx := context.Background()
+
 	for i, url := range testURLs {
 		fmt.Printf("Тест %d: %s\n", i+1, url)
 		fmt.Println("---")
 
 		// Тест 1: Использование Colly по умолчанию
 		fmt.Println("1. Использование Colly с настройками по умолчанию:")
-		content, err := internal.ExtractContent(url)
+		content, err := internal.ExtractContent(ctx, url)
 		if err != nil {
 			fmt.Printf("   Ошибка: %v\n", err)
 		} else {
@@ -48,7 +51,7 @@ func main() {
 			FollowRedirect: true,
 			RespectRobots:  false,
 		}
-		content2, err := internal.ExtractContentWithConfig(url, config)
+		content2, err := internal.ExtractContentWithConfig(ctx, url, config)
 		if err != nil {
 			fmt.Printf("   Ошибка: %v\n", err)
 		} else {
@@ -62,7 +65,7 @@ func main() {
 
 		// Тест 3: Fallback механизм
 		fmt.Println("3. Тест fallback механизма:")
-		content3, err := internal.ExtractContentWithFallback(url)
+		content3, err := internal.ExtractContentWithFallback(ctx, url)
 		if err != nil {
 			fmt.Printf("   Ошибка: %v\n", err)
 		} else {
@@ -79,7 +82,7 @@ func main() {
 	// Тест с невалидным URL
 	fmt.Println("Тест с невалидным URL:")
 	invalidURL := "https://invalid-domain-that-does-not-exist-12345.com/"
-	content, err := internal.ExtractContentWithFallback(invalidURL)
+	content, err := internal.ExtractContentWithFallback(ctx, invalidURL)
 	if err != nil {
 		fmt.Printf("Ожидаемая ошибка: %v\n", err)
 	} else {
@@ -99,7 +102,7 @@ func batchExtractContent(urls []string) {
 	// Запускаем горутины для параллельного извлечения
 	for _, url := range urls {
 		go func(u string) {
-			content, err := internal.ExtractContentWithFallback(u)
+			content, err := internal.ExtractContentWithFallback(context.Background(), u)
 			if err != nil {
 				errors <- fmt.Errorf("ошибка для %s: %w", u, err)
 				return
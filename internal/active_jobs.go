@@ -0,0 +1,90 @@
+package internal
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+)
+
+// sendStartedKey - ключ контекста для отметки о начале отправки файла задачи
+type sendStartedKey struct{}
+
+// markSendStarted отмечает, что задача начала отправлять файл в Telegram.
+// Отмененный запрос отправки завершается в фоне, поэтому такую задачу
+// нельзя выполнять повторно: файл может прийти дважды
+func markSendStarted(ctx context.Context) {
+	if started, ok := ctx.Value(sendStartedKey{}).(*atomic.Bool); ok {
+		started.Store(true)
+	}
+}
+
+// activeJobs хранит функции отмены задач пользователей, ожидающих в очереди
+// или выполняющихся, чтобы команда /cancel могла их прервать
+type activeJobs struct {
+	mu   sync.Mutex
+	jobs map[int64]map[*Job]context.CancelFunc
+}
+
+// newActiveJobs создает пустой реестр задач
+func newActiveJobs() *activeJobs {
+	return &activeJobs{jobs: make(map[int64]map[*Job]context.CancelFunc)}
+}
+
+// add создает отменяемый контекст задачи пользователя
+func (a *activeJobs) add(parent context.Context, userID int64, job *Job) {
+	ctx, cancel := context.WithCancel(parent)
+	job.ctx = context.WithValue(ctx, sendStartedKey{}, &job.sendStarted)
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.jobs[userID] == nil {
+		a.jobs[userID] = make(map[*Job]context.CancelFunc)
+	}
+	a.jobs[userID][job] = cancel
+}
+
+// remove убирает завершенную задачу из реестра и освобождает ее контекст
+func (a *activeJobs) remove(userID int64, job *Job) {
+	a.mu.Lock()
+	cancel, ok := a.jobs[userID][job]
+	delete(a.jobs[userID], job)
+	if len(a.jobs[userID]) == 0 {
+		delete(a.jobs, userID)
+	}
+	a.mu.Unlock()
+
+	if ok {
+		cancel()
+	}
+}
+
+// cancel отменяет все задачи пользователя и возвращает их число
+func (a *activeJobs) cancel(userID int64) int {
+	a.mu.Lock()
+	jobs := a.jobs[userID]
+	delete(a.jobs, userID)
+	a.mu.Unlock()
+
+	for _, cancel := range jobs {
+		cancel()
+	}
+	return len(jobs)
+}
+
+// drain отменяет задачи всех пользователей и возвращает их
+func (a *activeJobs) drain() []*Job {
+	a.mu.Lock()
+	all := a.jobs
+	a.jobs = make(map[int64]map[*Job]context.CancelFunc)
+	a.mu.Unlock()
+
+	var jobs []*Job
+	for _, userJobs := range all {
+		for job, cancel := range userJobs {
+			cancel()
+			jobs = append(jobs, job)
+		}
+	}
+	return jobs
+}
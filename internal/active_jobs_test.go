@@ -0,0 +1,47 @@
+package internal
+
+import (
+	"context"
+	"testing"
+)
+
+func TestActiveJobsCancel(t *testing.T) {
+	jobs := newActiveJobs()
+
+	first, second, other := &Job{UpdateID: 1}, &Job{UpdateID: 2}, &Job{UpdateID: 3}
+	jobs.add(context.Background(), 100, first)
+	jobs.add(context.Background(), 100, second)
+	jobs.add(context.Background(), 200, other)
+
+	// Завершенная задача не отменяется повторно
+	jobs.remove(100, first)
+
+	if count := jobs.cancel(100); count != 1 {
+		t.Errorf("Ожидалась отмена одной задачи, отменено %d", count)
+	}
+	if second.Context().Err() == nil {
+		t.Error("Задача пользователя должна быть отменена")
+	}
+	if other.Context().Err() != nil {
+		t.Error("Задачи других пользователей не должны отменяться")
+	}
+
+	if count := jobs.cancel(100); count != 0 {
+		t.Errorf("Повторная отмена не должна находить задачи, найдено %d", count)
+	}
+
+	// Задача, отмененная командой, завершается обработчиком как обычно
+	jobs.remove(100, second)
+
+	jobs.remove(200, other)
+	if other.Context().Err() == nil {
+		t.Error("Контекст завершенной задачи должен освобождаться")
+	}
+}
+
+func TestJobContextWithoutRegistry(t *testing.T) {
+	job := &Job{UpdateID: 1}
+	if job.Context() == nil || job.Context().Err() != nil {
+		t.Error("Задача без реестра должна получать неотменяемый контекст")
+	}
+}
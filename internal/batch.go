@@ -0,0 +1,213 @@
+package internal
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"fmt"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// batchResult содержит результат обработки одной ссылки из сообщения
+type batchResult struct {
+	URL  string
+	File tgbotapi.FileBytes
+	Err  error
+}
+
+// processURLs обрабатывает ссылки параллельно, не более concurrency одновременно,
+// и возвращает результаты в порядке ссылок. После отмены ctx ожидающие ссылки не загружаются
+func processURLs(ctx context.Context, urls []string, locale Locale, prefs UserSettings, concurrency int) []batchResult {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	results := make([]batchResult, len(urls))
+	sem := make(chan struct{}, concurrency)
+
+	var wg sync.WaitGroup
+	for i, pageURL := range urls {
+		wg.Add(1)
+		go func(i int, pageURL string) {
+			defer wg.Done()
+
+			select {
+			case sem <- struct{}{}:
+				defer func() { <-sem }()
+			case <-ctx.Done():
+				results[i] = batchResult{URL: pageURL, Err: ctx.Err()}
+				return
+			}
+
+			// Ошибка одной страницы не должна прерывать обработку остальных
+			defer func() {
+				if r := recover(); r != nil {
+					results[i] = batchResult{URL: pageURL, Err: fmt.Errorf("паника при обработке: %v", r)}
+				}
+			}()
+
+			file, err := processURL(ctx, pageURL, locale, prefs)
+			results[i] = batchResult{URL: pageURL, File: file, Err: err}
+		}(i, pageURL)
+	}
+	wg.Wait()
+
+	return results
+}
+
+// handleBatch обрабатывает сообщение с несколькими ссылками
+func handleBatch(ctx context.Context, bot *tgbotapi.BotAPI, message *tgbotapi.Message, urls []string, locale Locale, prefs UserSettings) {
+	if len(urls) > batchMaxURLs {
+		msg := tgbotapi.NewMessage(message.Chat.ID, fmt.Sprintf(locale.BatchTooManyMsg, batchMaxURLs))
+		bot.Send(msg)
+		urls = urls[:batchMaxURLs]
+	}
+
+	processingMsg := tgbotapi.NewMessage(message.Chat.ID, fmt.Sprintf(locale.BatchProcessingMessage, len(urls)))
+	sentMsg, err := bot.Send(processingMsg)
+	if err != nil {
+		logger.Errorf("Ошибка при отправке сообщения о обработке: %v", err)
+	}
+
+	results := processURLs(ctx, urls, locale, prefs, batchConcurrency)
+	if ctx.Err() != nil {
+		logger.Infof("Обработка ссылок прервана, пользователь %d", message.Chat.ID)
+		deleteMessage(bot, sentMsg)
+		return
+	}
+
+	var files []tgbotapi.FileBytes
+	var failures []string
+	for _, result := range results {
+		if result.Err != nil {
+			logger.Errorf("Ошибка при извлечении контента %s: %v", result.URL, result.Err)
+			failures = append(failures, fmt.Sprintf("%s — %s", result.URL, errorMessageFor(result.Err, locale)))
+			continue
+		}
+		files = append(files, result.File)
+	}
+
+	if len(files) > 0 {
+		if prefs.BatchMode == BatchModeZip {
+			sendZip(ctx, bot, message, files, locale)
+		} else {
+			for _, file := range files {
+				if _, err := sendContext(ctx, bot, tgbotapi.NewDocument(message.Chat.ID, file)); err != nil {
+					if ctx.Err() != nil {
+						break
+					}
+					logger.Errorf("Ошибка при отправке файла: %v", err)
+					bot.Send(tgbotapi.NewMessage(message.Chat.ID, sendErrorMessage(err, locale)))
+				}
+			}
+		}
+	}
+
+	if ctx.Err() != nil {
+		logger.Infof("Отправка файлов прервана, пользователь %d", message.Chat.ID)
+		deleteMessage(bot, sentMsg)
+		return
+	}
+
+	if len(failures) > 0 {
+		text := locale.BatchFailedMsg + "\n\n" + strings.Join(failures, "\n")
+		bot.Send(tgbotapi.NewMessage(message.Chat.ID, text))
+	}
+
+	// Удаляем сообщение о обработке
+	deleteMessage(bot, sentMsg)
+
+	logger.Infof("Обработано ссылок: %d, ошибок: %d, пользователь %d", len(files), len(failures), message.Chat.ID)
+}
+
+// sendZip отправляет файлы одним zip архивом
+func sendZip(ctx context.Context, bot *tgbotapi.BotAPI, message *tgbotapi.Message, files []tgbotapi.FileBytes, locale Locale) {
+	data, err := buildZip(files)
+	if err != nil {
+		logger.Errorf("Ошибка при создании архива: %v", err)
+		bot.Send(tgbotapi.NewMessage(message.Chat.ID, locale.ErrorSendingMsg))
+		return
+	}
+
+	archive := tgbotapi.NewDocument(message.Chat.ID, tgbotapi.FileBytes{
+		Name:  fmt.Sprintf("links_%s.zip", time.Now().Format("20060102_150405")),
+		Bytes: data,
+	})
+	if _, err := sendContext(ctx, bot, archive); err != nil && ctx.Err() == nil {
+		logger.Errorf("Ошибка при отправке архива: %v", err)
+		bot.Send(tgbotapi.NewMessage(message.Chat.ID, sendErrorMessage(err, locale)))
+	}
+}
+
+// buildZip упаковывает файлы в zip архив, делая имена уникальными
+func buildZip(files []tgbotapi.FileBytes) ([]byte, error) {
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+
+	used := make(map[string]bool)
+	for _, file := range files {
+		w, err := archive.CreateHeader(&zip.FileHeader{
+			Name:     uniqueFilename(file.Name, used),
+			Method:   zip.Deflate,
+			Modified: time.Now(),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("ошибка добавления файла в архив: %w", err)
+		}
+		if _, err := w.Write(file.Bytes); err != nil {
+			return nil, fmt.Errorf("ошибка записи файла в архив: %w", err)
+		}
+	}
+
+	if err := archive.Close(); err != nil {
+		return nil, fmt.Errorf("ошибка создания архива: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// uniqueFilename добавляет номер к имени файла, если оно уже занято
+func uniqueFilename(name string, used map[string]bool) string {
+	if !used[name] {
+		used[name] = true
+		return name
+	}
+
+	ext := path.Ext(name)
+	base := strings.TrimSuffix(name, ext)
+	for i := 2; ; i++ {
+		candidate := fmt.Sprintf("%s (%d)%s", base, i, ext)
+		if !used[candidate] {
+			used[candidate] = true
+			return candidate
+		}
+	}
+}
+
+// HandleBatchCommand обрабатывает команду /batch, выбирающую режим отправки
+func HandleBatchCommand(bot *tgbotapi.BotAPI, message *tgbotapi.Message) {
+	locale := GetLocale(message)
+	mode := strings.ToLower(strings.TrimSpace(message.CommandArguments()))
+
+	if mode != BatchModeFiles && mode != BatchModeZip {
+		text := fmt.Sprintf(locale.BatchModeMsg, userSettings(message).BatchMode)
+		bot.Send(tgbotapi.NewMessage(message.Chat.ID, text))
+		return
+	}
+
+	err := settings.Update(messageUserID(message), func(s *UserSettings) {
+		s.BatchMode = mode
+	})
+	if err != nil {
+		logger.Errorf("Ошибка при сохранении настроек: %v", err)
+		bot.Send(tgbotapi.NewMessage(message.Chat.ID, locale.ErrorSettingsMsg))
+		return
+	}
+
+	bot.Send(tgbotapi.NewMessage(message.Chat.ID, fmt.Sprintf(locale.BatchModeSetMsg, mode)))
+}
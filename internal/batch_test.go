@@ -0,0 +1,122 @@
+package internal
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// countingFetcher отдает страницу по URL и считает одновременные загрузки
+type countingFetcher struct {
+	mu      sync.Mutex
+	active  int
+	maxSeen int
+}
+
+func (f *countingFetcher) Fetch(ctx context.Context, pageURL string) (*FetchResult, error) {
+	f.mu.Lock()
+	f.active++
+	if f.active > f.maxSeen {
+		f.maxSeen = f.active
+	}
+	f.mu.Unlock()
+
+	time.Sleep(20 * time.Millisecond)
+
+	f.mu.Lock()
+	f.active--
+	f.mu.Unlock()
+
+	if strings.Contains(pageURL, "blocked") {
+		return nil, &BlockedError{Kind: BlockRobotsTxt, Reason: "запрещено"}
+	}
+
+	html := `<html><head><title>Page</title></head><body><article>
+<p>This is a long enough paragraph of text for readability to treat it as the main content of the page.</p>
+<p>Another paragraph with more words so that the article is not considered empty by the extractor.</p>
+</article></body></html>`
+	return &FetchResult{Body: []byte(html), FinalURL: pageURL, StatusCode: 200}, nil
+}
+
+func TestProcessURLsConcurrency(t *testing.T) {
+	stub := &countingFetcher{}
+	previous := fetcher
+	SetFetcher(stub)
+	defer SetFetcher(previous)
+
+	urls := []string{
+		"https://example.com/1",
+		"https://example.com/blocked",
+		"https://example.com/3",
+		"https://example.com/4",
+	}
+
+	results := processURLs(context.Background(), urls, locales["en"], UserSettings{}.withDefaults(), 2)
+
+	if len(results) != len(urls) {
+		t.Fatalf("Ожидалось %d результатов, получено %d", len(urls), len(results))
+	}
+	for i, result := range results {
+		if result.URL != urls[i] {
+			t.Errorf("Результаты должны идти в порядке ссылок: %d - %s", i, result.URL)
+		}
+	}
+
+	var blocked *BlockedError
+	if !errors.As(results[1].Err, &blocked) {
+		t.Errorf("Ожидалась ошибка BlockedError для второй ссылки, получено %v", results[1].Err)
+	}
+	if results[0].Err != nil || !strings.HasSuffix(results[0].File.Name, ".md") {
+		t.Errorf("Первая ссылка должна быть обработана: %v, %s", results[0].Err, results[0].File.Name)
+	}
+
+	if stub.maxSeen > 2 {
+		t.Errorf("Одновременно должно обрабатываться не более 2 ссылок, обрабатывалось %d", stub.maxSeen)
+	}
+}
+
+func TestBuildZipUniqueNames(t *testing.T) {
+	data, err := buildZip([]tgbotapi.FileBytes{
+		{Name: "page.md", Bytes: []byte("first")},
+		{Name: "page.md", Bytes: []byte("second")},
+		{Name: "other.md", Bytes: []byte("third")},
+	})
+	if err != nil {
+		t.Fatalf("Ошибка создания архива: %v", err)
+	}
+
+	reader, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("Ошибка чтения архива: %v", err)
+	}
+
+	expected := map[string]string{
+		"page.md":     "first",
+		"page (2).md": "second",
+		"other.md":    "third",
+	}
+	if len(reader.File) != len(expected) {
+		t.Fatalf("Ожидалось %d файлов в архиве, получено %d", len(expected), len(reader.File))
+	}
+
+	for _, file := range reader.File {
+		rc, err := file.Open()
+		if err != nil {
+			t.Fatalf("Ошибка открытия %s: %v", file.Name, err)
+		}
+		content, _ := io.ReadAll(rc)
+		rc.Close()
+
+		if expected[file.Name] != string(content) {
+			t.Errorf("Неверное содержимое %s: %s", file.Name, content)
+		}
+	}
+}
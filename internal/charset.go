@@ -0,0 +1,147 @@
+package internal
+
+import (
+	"bytes"
+	"mime"
+	"net/http"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/saintfish/chardet"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/net/html/charset"
+	"golang.org/x/text/encoding"
+)
+
+// charsetPrescanSize - сколько байт начала документа просматривать в поисках <meta charset>
+const charsetPrescanSize = 1024
+
+// metaCharsetPattern находит <meta charset="..."> и <meta http-equiv="Content-Type" content="...; charset=...">
+var metaCharsetPattern = regexp.MustCompile(`(?i)<meta[^>]+charset\s*=\s*["']?\s*([\w.:-]+)`)
+
+// byteOrderMarks сопоставляет метки порядка байтов с кодировками
+var byteOrderMarks = []struct {
+	bom  []byte
+	name string
+}{
+	{[]byte{0xEF, 0xBB, 0xBF}, "utf-8"},
+	{[]byte{0xFE, 0xFF}, "utf-16be"},
+	{[]byte{0xFF, 0xFE}, "utf-16le"},
+}
+
+// normalizeCharset перекодирует текстовый ответ в UTF-8. В возвращаемых заголовках
+// указан charset=utf-8, чтобы ответ не декодировался повторно, например из кэша
+func normalizeCharset(body []byte, headers http.Header) ([]byte, http.Header) {
+	contentType := headers.Get("Content-Type")
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mediaType, _, _ = mime.ParseMediaType(http.DetectContentType(body))
+		params = map[string]string{}
+	}
+	if !isTextContentType(mediaType) {
+		return body, headers
+	}
+
+	decoded, name := decodeToUTF8(body, contentType)
+	if name != "utf-8" {
+		logrus.Debugf("Ответ перекодирован из %s в UTF-8", name)
+	}
+
+	return decoded, withUTF8Charset(headers, mediaType, params)
+}
+
+// withUTF8Charset возвращает копию заголовков с charset=utf-8 в Content-Type
+func withUTF8Charset(headers http.Header, mediaType string, params map[string]string) http.Header {
+	params["charset"] = "utf-8"
+	headers = headers.Clone()
+	if headers == nil {
+		headers = http.Header{}
+	}
+	headers.Set("Content-Type", mime.FormatMediaType(mediaType, params))
+	return headers
+}
+
+// collyDecodedHeaders отмечает ответ Colly как UTF-8, если Colly уже перекодировал
+// его по charset из заголовка Content-Type
+func collyDecodedHeaders(headers http.Header) http.Header {
+	mediaType, params, err := mime.ParseMediaType(headers.Get("Content-Type"))
+	if err != nil || params["charset"] == "" || !isTextContentType(mediaType) {
+		return headers
+	}
+	return withUTF8Charset(headers, mediaType, params)
+}
+
+// isTextContentType проверяет, что ответ содержит текст, который нужно перекодировать
+func isTextContentType(mediaType string) bool {
+	return strings.HasPrefix(mediaType, "text/") ||
+		mediaType == "application/xhtml+xml" ||
+		mediaType == "application/xml" ||
+		strings.HasSuffix(mediaType, "+xml")
+}
+
+// decodeToUTF8 определяет кодировку документа и перекодирует его в UTF-8.
+// Возвращает документ и название исходной кодировки
+func decodeToUTF8(body []byte, contentType string) ([]byte, string) {
+	enc, name := detectCharset(body, contentType)
+	if name == "utf-8" || enc == nil {
+		return bytes.TrimPrefix(body, byteOrderMarks[0].bom), "utf-8"
+	}
+
+	decoded, err := enc.NewDecoder().Bytes(body)
+	if err != nil {
+		return body, "utf-8"
+	}
+	// UTF-16 декодер может оставить BOM в начале текста
+	return bytes.TrimPrefix(decoded, byteOrderMarks[0].bom), name
+}
+
+// detectCharset определяет кодировку по BOM, заголовку Content-Type, <meta charset>
+// и, если она нигде не указана, по статистике байтов документа
+func detectCharset(body []byte, contentType string) (encoding.Encoding, string) {
+	for _, mark := range byteOrderMarks {
+		if bytes.HasPrefix(body, mark.bom) {
+			return charset.Lookup(mark.name)
+		}
+	}
+
+	var declared []string
+	if _, params, err := mime.ParseMediaType(contentType); err == nil && params["charset"] != "" {
+		declared = append(declared, params["charset"])
+	}
+	prescan := body
+	if len(prescan) > charsetPrescanSize {
+		prescan = prescan[:charsetPrescanSize]
+	}
+	if match := metaCharsetPattern.FindSubmatch(prescan); match != nil {
+		declared = append(declared, string(match[1]))
+	}
+
+	for _, label := range declared {
+		enc, name := charset.Lookup(label)
+		if enc == nil {
+			continue
+		}
+		// Сайты часто указывают UTF-8, отдавая текст в другой кодировке
+		if name == "utf-8" && !utf8.Valid(body) {
+			continue
+		}
+		return enc, name
+	}
+
+	if utf8.Valid(body) {
+		return encoding.Nop, "utf-8"
+	}
+
+	// Уверенность детектора на коротких страницах низкая, но лучший вариант
+	// все равно точнее кодировки по умолчанию
+	result, err := chardet.NewHtmlDetector().DetectBest(body)
+	if err == nil {
+		if enc, name := charset.Lookup(result.Charset); enc != nil {
+			return enc, name
+		}
+	}
+
+	// Кодировка по умолчанию для HTML без объявления кодировки
+	return charset.Lookup("windows-1252")
+}
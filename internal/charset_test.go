@@ -0,0 +1,159 @@
+package internal
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/encoding/unicode"
+)
+
+const russianText = "Съешь же ещё этих мягких французских булок, да выпей чаю. " +
+	"Широкая электрификация южных губерний даст мощный толчок подъёму сельского хозяйства."
+
+func encodeText(t *testing.T, enc encoding.Encoding, text string) []byte {
+	t.Helper()
+	encoded, err := enc.NewEncoder().Bytes([]byte(text))
+	if err != nil {
+		t.Fatalf("Ошибка кодирования: %v", err)
+	}
+	return encoded
+}
+
+func TestDecodeToUTF8(t *testing.T) {
+	japaneseText := "日本語のページです。文字コードはシフトJISです。"
+
+	tests := []struct {
+		name        string
+		body        []byte
+		contentType string
+		expected    string
+		charset     string
+	}{
+		{
+			"заголовок Content-Type",
+			encodeText(t, charmap.Windows1251, "<p>"+russianText+"</p>"),
+			"text/html; charset=windows-1251",
+			"<p>" + russianText + "</p>",
+			"windows-1251",
+		},
+		{
+			"meta charset",
+			encodeText(t, charmap.KOI8R, `<html><head><meta charset="koi8-r"></head><body>`+russianText+`</body></html>`),
+			"text/html",
+			`<html><head><meta charset="koi8-r"></head><body>` + russianText + `</body></html>`,
+			"koi8-r",
+		},
+		{
+			"meta http-equiv",
+			encodeText(t, japanese.ShiftJIS, `<meta http-equiv="Content-Type" content="text/html; charset=Shift_JIS"><p>`+japaneseText+`</p>`),
+			"",
+			`<meta http-equiv="Content-Type" content="text/html; charset=Shift_JIS"><p>` + japaneseText + `</p>`,
+			"shift_jis",
+		},
+		{
+			"BOM UTF-16",
+			encodeText(t, unicode.UTF16(unicode.LittleEndian, unicode.UseBOM), "<p>"+russianText+"</p>"),
+			"text/html; charset=iso-8859-1",
+			"<p>" + russianText + "</p>",
+			"utf-16le",
+		},
+		{
+			"статистическое определение при неверном заголовке",
+			encodeText(t, charmap.Windows1251, "<html><body><p>"+russianText+"</p></body></html>"),
+			"text/html; charset=utf-8",
+			"<html><body><p>" + russianText + "</p></body></html>",
+			"windows-1251",
+		},
+		{
+			"UTF-8 без объявления",
+			[]byte("\xEF\xBB\xBF<p>" + russianText + "</p>"),
+			"text/html",
+			"<p>" + russianText + "</p>",
+			"utf-8",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decoded, name := decodeToUTF8(tt.body, tt.contentType)
+			if string(decoded) != tt.expected {
+				t.Errorf("Неверный текст после перекодирования: %q", decoded)
+			}
+			if name != tt.charset {
+				t.Errorf("Ожидалась кодировка %s, определена %s", tt.charset, name)
+			}
+		})
+	}
+}
+
+func TestNormalizeCharsetHeaders(t *testing.T) {
+	headers := http.Header{}
+	headers.Set("Content-Type", "text/html; charset=windows-1251")
+
+	body, normalized := normalizeCharset(encodeText(t, charmap.Windows1251, russianText), headers)
+	if string(body) != russianText {
+		t.Errorf("Текст должен быть перекодирован: %q", body)
+	}
+	if normalized.Get("Content-Type") != "text/html; charset=utf-8" {
+		t.Errorf("В заголовке должна быть указана кодировка UTF-8: %s", normalized.Get("Content-Type"))
+	}
+	if headers.Get("Content-Type") != "text/html; charset=windows-1251" {
+		t.Error("Исходные заголовки не должны изменяться")
+	}
+
+	// Повторная нормализация не должна портить текст
+	if again, _ := normalizeCharset(body, normalized); string(again) != russianText {
+		t.Errorf("Повторная нормализация испортила текст: %q", again)
+	}
+
+	image := http.Header{}
+	image.Set("Content-Type", "image/png")
+	png := []byte("\x89PNG\r\n\x1a\n\xff\xfe")
+	if body, _ := normalizeCharset(png, image); string(body) != string(png) {
+		t.Error("Двоичные ответы не должны перекодироваться")
+	}
+}
+
+func TestEthicalScraperTranscodesResponse(t *testing.T) {
+	page := encodeText(t, charmap.Windows1251, `<html><head><meta charset="windows-1251"></head><body><p>`+russianText+`</p></body></html>`)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/robots.txt" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		w.Header().Set("Cache-Control", "max-age=60")
+		w.Write(page)
+	}))
+	defer server.Close()
+
+	scraper := NewEthicalScraper(&Config{
+		HTTPTimeout:          30,
+		CacheTTL:             1,
+		UserAgent:            "Test-Bot/1.0",
+		AllowPrivateNetworks: true,
+	})
+
+	for _, cached := range []bool{false, true} {
+		result, err := scraper.ScrapeURL(context.Background(), server.URL+"/news")
+		if err != nil {
+			t.Fatalf("Ошибка загрузки: %v", err)
+		}
+		if result.IsCached != cached {
+			t.Errorf("Ожидалось IsCached=%t", cached)
+		}
+		if !strings.Contains(string(result.Content), russianText) {
+			t.Errorf("Страница в windows-1251 должна быть перекодирована в UTF-8: %q", result.Content)
+		}
+		if result.Headers.Get("Content-Type") != "text/html; charset=utf-8" {
+			t.Errorf("Неверный Content-Type: %s", result.Headers.Get("Content-Type"))
+		}
+	}
+}
@@ -3,6 +3,7 @@ package internal
 import (
 	"os"
 	"strconv"
+	"strings"
 )
 
 // Config представляет конфигурацию приложения
@@ -11,21 +12,91 @@ type Config struct {
 	HTTPTimeout int // в секундах
 	MaxRetries  int
 
+	// Сетевая политика для ссылок пользователей
+	AllowPrivateNetworks bool  // разрешить внутренние адреса (только для разработки)
+	AllowedPorts         []int // пусто - любой порт
+
+	// Ограничения загружаемых страниц
+	MaxBodySizeMB       int      // 0 - без ограничения
+	AllowedContentTypes []string // пусто - любой тип
+
+	// Режим работы бота
+	BotMode        string // webhook или polling
+	PollingTimeout int    // в секундах
+
 	// Webhook
 	WebhookURL  string
 	WebhookPort string
 	SSLCertFile string
 	SSLKeyFile  string
 
+	// Метрики Prometheus на /metrics
+	MetricsToken string // пусто - без авторизации
+	MetricsPort  string // порт /metrics и /healthz в режиме polling, пусто - не запускать
+
+	// Очередь обработки
+	WorkerCount  int
+	JobQueueSize int
+
+	// Ограничения времени стадий обработки ссылки, 0 - без ограничения
+	FetchTimeout   int // в секундах
+	ProcessTimeout int // в секундах
+	SendTimeout    int // в секундах
+
+	// Остановка
+	ShutdownTimeout int    // в секундах, ожидание обработки принятых сообщений
+	PendingJobsFile string // пустой путь - незавершенные задачи не сохраняются
+
+	// Обработка нескольких ссылок в сообщении
+	BatchConcurrency int
+	BatchMaxURLs     int
+
+	// Оформление ссылок
+	ReferenceLinksMin int // 0 - ссылки остаются в тексте
+
+	// Загрузка изображений статей
+	ImageMaxSizeKB  int // 0 - без ограничения
+	ImageMaxCount   int // 0 - без ограничения
+	ImageMaxTotalMB int // 0 - без ограничения
+
+	// Настройки пользователей
+	SettingsFile string // пустой путь - настройки только в памяти
+
+	// Шаблоны оформления markdown файлов
+	TemplatesDir string // пустой путь - только стандартное оформление
+
+	// Ограничения для пользователей
+	UserRateLimit  int // ссылок в минуту, 0 - без ограничения
+	UserDailyQuota int // ссылок в сутки, 0 - без ограничения
+	AdminUserIDs   []int64
+
+	// Дедупликация обновлений
+	DedupStore      string // memory или file
+	DedupFile       string
+	DedupTTL        int // в минутах
+	DedupMaxEntries int
+
 	// Логирование
 	LogLevel string
 
+	// Загрузка страниц
+	FetcherBackend string // ethical или colly
+
 	// Ethical scraping
 	UserAgent         string
 	ContactEmail      string
 	WhitelistDomains  string
-	RateLimitInterval int // в секундах
-	CacheTTL          int // в часах
+	RateLimitInterval int    // в секундах
+	RateLimitBurst    int    // запросов к домену подряд без ожидания
+	RateLimitGlobal   int    // запросов в секунду ко всем сайтам, 0 - без ограничения
+	RateLimitDomains  string // политики доменов: example.com=2s:3,news.ru=500ms
+	CacheTTL          int    // в часах
+
+	// Кэш ответов
+	CacheBackend    string // memory или disk
+	CacheDir        string
+	CacheMaxEntries int // 0 - без ограничения
+	CacheMaxSizeMB  int // 0 - без ограничения
 }
 
 // LoadConfig загружает конфигурацию из переменных окружения
@@ -34,8 +105,49 @@ func LoadConfig() *Config {
 		// Значения по умолчанию
 		HTTPTimeout: 30,
 		MaxRetries:  3,
-		LogLevel:    "info",
-		WebhookPort: "8443",
+
+		AllowedPorts: DefaultAllowedPorts,
+
+		MaxBodySizeMB:       10,
+		AllowedContentTypes: DefaultContentTypes,
+		LogLevel:            "info",
+		WebhookPort:         "8443",
+
+		BotMode:        "webhook",
+		PollingTimeout: 60,
+		MetricsPort:    "8080",
+
+		WorkerCount:  4,
+		JobQueueSize: 100,
+
+		FetchTimeout:   int(DefaultFetchTimeout.Seconds()),
+		ProcessTimeout: int(DefaultProcessTimeout.Seconds()),
+		SendTimeout:    int(DefaultSendTimeout.Seconds()),
+
+		ShutdownTimeout: 25,
+		PendingJobsFile: "data/pending_jobs.json",
+
+		BatchConcurrency: 3,
+		BatchMaxURLs:     10,
+
+		ImageMaxSizeKB:  5 * 1024,
+		ImageMaxCount:   50,
+		ImageMaxTotalMB: 20,
+
+		SettingsFile: "data/user_settings.json",
+
+		UserRateLimit:  10,
+		UserDailyQuota: 200,
+
+		DedupStore:      "memory",
+		DedupFile:       "data/processed_updates.json",
+		DedupTTL:        60,
+		DedupMaxEntries: 10000,
+
+		CacheBackend:    "memory",
+		CacheDir:        "data/cache",
+		CacheMaxEntries: 1000,
+		CacheMaxSizeMB:  100,
 	}
 
 	// Загружаем из переменных окружения
@@ -51,10 +163,155 @@ func LoadConfig() *Config {
 		}
 	}
 
+	if val := os.Getenv("WORKER_COUNT"); val != "" {
+		if workers, err := strconv.Atoi(val); err == nil && workers > 0 {
+			config.WorkerCount = workers
+		}
+	}
+
+	if val := os.Getenv("JOB_QUEUE_SIZE"); val != "" {
+		if size, err := strconv.Atoi(val); err == nil && size >= 0 {
+			config.JobQueueSize = size
+		}
+	}
+
+	// Ограничения времени стадий обработки ссылки
+	if val := os.Getenv("FETCH_TIMEOUT"); val != "" {
+		if timeout, err := strconv.Atoi(val); err == nil && timeout >= 0 {
+			config.FetchTimeout = timeout
+		}
+	}
+
+	if val := os.Getenv("PROCESS_TIMEOUT"); val != "" {
+		if timeout, err := strconv.Atoi(val); err == nil && timeout >= 0 {
+			config.ProcessTimeout = timeout
+		}
+	}
+
+	if val := os.Getenv("SEND_TIMEOUT"); val != "" {
+		if timeout, err := strconv.Atoi(val); err == nil && timeout >= 0 {
+			config.SendTimeout = timeout
+		}
+	}
+
+	// Метрики
+	config.MetricsToken = os.Getenv("METRICS_TOKEN")
+	if val, ok := os.LookupEnv("METRICS_PORT"); ok {
+		config.MetricsPort = strings.TrimSpace(val)
+	}
+
+	// Остановка
+	if val := os.Getenv("SHUTDOWN_TIMEOUT"); val != "" {
+		if timeout, err := strconv.Atoi(val); err == nil && timeout >= 0 {
+			config.ShutdownTimeout = timeout
+		}
+	}
+
+	if val, ok := os.LookupEnv("PENDING_JOBS_FILE"); ok {
+		config.PendingJobsFile = val
+	}
+
+	// Обработка нескольких ссылок в сообщении
+	if val := os.Getenv("BATCH_CONCURRENCY"); val != "" {
+		if concurrency, err := strconv.Atoi(val); err == nil && concurrency > 0 {
+			config.BatchConcurrency = concurrency
+		}
+	}
+
+	if val := os.Getenv("BATCH_MAX_URLS"); val != "" {
+		if maxURLs, err := strconv.Atoi(val); err == nil && maxURLs > 0 {
+			config.BatchMaxURLs = maxURLs
+		}
+	}
+
+	// Оформление ссылок
+	if val := os.Getenv("REFERENCE_LINKS_MIN"); val != "" {
+		if minLinks, err := strconv.Atoi(val); err == nil && minLinks >= 0 {
+			config.ReferenceLinksMin = minLinks
+		}
+	}
+
+	// Загрузка изображений статей
+	if val := os.Getenv("IMAGE_MAX_SIZE_KB"); val != "" {
+		if size, err := strconv.Atoi(val); err == nil && size >= 0 {
+			config.ImageMaxSizeKB = size
+		}
+	}
+
+	if val := os.Getenv("IMAGE_MAX_COUNT"); val != "" {
+		if count, err := strconv.Atoi(val); err == nil && count >= 0 {
+			config.ImageMaxCount = count
+		}
+	}
+
+	if val := os.Getenv("IMAGE_MAX_TOTAL_MB"); val != "" {
+		if size, err := strconv.Atoi(val); err == nil && size >= 0 {
+			config.ImageMaxTotalMB = size
+		}
+	}
+
+	if val, ok := os.LookupEnv("SETTINGS_FILE"); ok {
+		config.SettingsFile = val
+	}
+
+	// Шаблоны оформления markdown файлов
+	config.TemplatesDir = os.Getenv("TEMPLATES_DIR")
+
+	// Ограничения для пользователей
+	if val := os.Getenv("USER_RATE_LIMIT"); val != "" {
+		if limit, err := strconv.Atoi(val); err == nil && limit >= 0 {
+			config.UserRateLimit = limit
+		}
+	}
+
+	if val := os.Getenv("USER_DAILY_QUOTA"); val != "" {
+		if quota, err := strconv.Atoi(val); err == nil && quota >= 0 {
+			config.UserDailyQuota = quota
+		}
+	}
+
+	if val := os.Getenv("ADMIN_USER_IDS"); val != "" {
+		if ids, err := ParseUserIDs(val); err == nil {
+			config.AdminUserIDs = ids
+		}
+	}
+
+	// Дедупликация обновлений
+	if val := os.Getenv("DEDUP_STORE"); val != "" {
+		config.DedupStore = strings.ToLower(strings.TrimSpace(val))
+	}
+
+	if val := os.Getenv("DEDUP_FILE"); val != "" {
+		config.DedupFile = val
+	}
+
+	if val := os.Getenv("DEDUP_TTL"); val != "" {
+		if ttl, err := strconv.Atoi(val); err == nil && ttl > 0 {
+			config.DedupTTL = ttl
+		}
+	}
+
+	if val := os.Getenv("DEDUP_MAX_ENTRIES"); val != "" {
+		if entries, err := strconv.Atoi(val); err == nil && entries > 0 {
+			config.DedupMaxEntries = entries
+		}
+	}
+
 	if val := os.Getenv("LOG_LEVEL"); val != "" {
 		config.LogLevel = val
 	}
 
+	// Режим работы бота
+	if val := os.Getenv("BOT_MODE"); val != "" {
+		config.BotMode = strings.ToLower(strings.TrimSpace(val))
+	}
+
+	if val := os.Getenv("POLLING_TIMEOUT"); val != "" {
+		if timeout, err := strconv.Atoi(val); err == nil && timeout >= 0 {
+			config.PollingTimeout = timeout
+		}
+	}
+
 	// Webhook настройки
 	if val := os.Getenv("WEBHOOK_URL"); val != "" {
 		config.WebhookURL = val
@@ -72,7 +329,42 @@ func LoadConfig() *Config {
 		config.SSLKeyFile = val
 	}
 
+	// Источник загрузки страниц
+	if val := os.Getenv("FETCHER_BACKEND"); val != "" {
+		config.FetcherBackend = val
+	} else {
+		config.FetcherBackend = "ethical"
+	}
+
 	// Ethical scraping настройки
+
+	if val := os.Getenv("ALLOW_PRIVATE_NETWORKS"); val != "" {
+		if allow, err := strconv.ParseBool(val); err == nil {
+			config.AllowPrivateNetworks = allow
+		}
+	}
+
+	if val := os.Getenv("ALLOWED_PORTS"); val != "" {
+		if ports, ok := parsePorts(val); ok {
+			config.AllowedPorts = ports
+		}
+	}
+
+	if val := os.Getenv("MAX_BODY_SIZE_MB"); val != "" {
+		if size, err := strconv.Atoi(val); err == nil && size >= 0 {
+			config.MaxBodySizeMB = size
+		}
+	}
+
+	if val := os.Getenv("ALLOWED_CONTENT_TYPES"); val != "" {
+		config.AllowedContentTypes = nil
+		for _, contentType := range strings.Split(val, ",") {
+			if contentType = strings.ToLower(strings.TrimSpace(contentType)); contentType != "" && contentType != "*" {
+				config.AllowedContentTypes = append(config.AllowedContentTypes, contentType)
+			}
+		}
+	}
+
 	if val := os.Getenv("USER_AGENT"); val != "" {
 		config.UserAgent = val
 	} else {
@@ -97,6 +389,23 @@ func LoadConfig() *Config {
 		config.RateLimitInterval = 1
 	}
 
+	config.RateLimitBurst = 1
+	if val := os.Getenv("RATE_LIMIT_BURST"); val != "" {
+		if burst, err := strconv.Atoi(val); err == nil && burst > 0 {
+			config.RateLimitBurst = burst
+		}
+	}
+
+	if val := os.Getenv("RATE_LIMIT_GLOBAL"); val != "" {
+		if global, err := strconv.Atoi(val); err == nil && global >= 0 {
+			config.RateLimitGlobal = global
+		}
+	}
+
+	if val := os.Getenv("RATE_LIMIT_DOMAINS"); val != "" {
+		config.RateLimitDomains = val
+	}
+
 	if val := os.Getenv("CACHE_TTL"); val != "" {
 		if ttl, err := strconv.Atoi(val); err == nil && ttl > 0 {
 			config.CacheTTL = ttl
@@ -107,5 +416,43 @@ func LoadConfig() *Config {
 		config.CacheTTL = 24
 	}
 
+	// Кэш ответов
+	if val := os.Getenv("CACHE_BACKEND"); val != "" {
+		config.CacheBackend = strings.ToLower(strings.TrimSpace(val))
+	}
+
+	if val := os.Getenv("CACHE_DIR"); val != "" {
+		config.CacheDir = val
+	}
+
+	if val := os.Getenv("CACHE_MAX_ENTRIES"); val != "" {
+		if entries, err := strconv.Atoi(val); err == nil && entries >= 0 {
+			config.CacheMaxEntries = entries
+		}
+	}
+
+	if val := os.Getenv("CACHE_MAX_SIZE_MB"); val != "" {
+		if size, err := strconv.Atoi(val); err == nil && size >= 0 {
+			config.CacheMaxSizeMB = size
+		}
+	}
+
 	return config
 }
+
+// parsePorts разбирает список портов через запятую, "*" разрешает любые порты
+func parsePorts(val string) ([]int, bool) {
+	if strings.TrimSpace(val) == "*" {
+		return nil, true
+	}
+
+	var ports []int
+	for _, part := range strings.Split(val, ",") {
+		port, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil || port <= 0 || port > 65535 {
+			return nil, false
+		}
+		ports = append(ports, port)
+	}
+	return ports, true
+}
@@ -0,0 +1,169 @@
+package internal
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+)
+
+// acceptEncoding - сжатия, которые скрапер умеет распаковывать
+const acceptEncoding = "gzip, deflate, br, zstd"
+
+// decodingTransport распаковывает ответы в gzip, deflate, brotli и zstd.
+// Стандартный http.Transport распаковывает только gzip и только если
+// заголовок Accept-Encoding не задан вручную
+type decodingTransport struct {
+	next http.RoundTripper
+}
+
+// newDecodingTransport оборачивает транспорт распаковкой ответов
+func newDecodingTransport(next http.RoundTripper) http.RoundTripper {
+	return &decodingTransport{next: next}
+}
+
+// RoundTrip выполняет запрос и подменяет тело ответа распакованным
+func (t *decodingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Header.Get("Accept-Encoding") == "" {
+		req = req.Clone(req.Context())
+		req.Header.Set("Accept-Encoding", acceptEncoding)
+	}
+
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	encodings := contentEncodings(resp.Header)
+	if len(encodings) == 0 || req.Method == http.MethodHead || resp.StatusCode == http.StatusNotModified {
+		return resp, nil
+	}
+
+	body, err := decodingReader(resp.Body, encodings)
+	if err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+
+	resp.Body = &decodedBody{Reader: body, raw: resp.Body}
+	resp.Header.Del("Content-Encoding")
+	resp.Header.Del("Content-Length")
+	resp.ContentLength = -1
+	resp.Uncompressed = true
+
+	return resp, nil
+}
+
+// decodedBody закрывает исходное тело ответа вместе с распаковщиком
+type decodedBody struct {
+	io.Reader
+	raw io.ReadCloser
+}
+
+func (b *decodedBody) Close() error {
+	if closer, ok := b.Reader.(io.Closer); ok {
+		closer.Close()
+	}
+	return b.raw.Close()
+}
+
+// contentEncodings возвращает сжатия из Content-Encoding в порядке их применения
+func contentEncodings(headers http.Header) []string {
+	var encodings []string
+	for _, value := range headers.Values("Content-Encoding") {
+		for _, encoding := range strings.Split(value, ",") {
+			encoding = strings.ToLower(strings.TrimSpace(encoding))
+			if encoding != "" && encoding != "identity" {
+				encodings = append(encodings, encoding)
+			}
+		}
+	}
+	return encodings
+}
+
+// decodingReader распаковывает поток, снимая сжатия в обратном порядке
+func decodingReader(r io.Reader, encodings []string) (io.Reader, error) {
+	for i := len(encodings) - 1; i >= 0; i-- {
+		var err error
+		switch encodings[i] {
+		case "gzip", "x-gzip":
+			r, err = gzip.NewReader(r)
+		case "deflate":
+			r, err = deflateReader(r)
+		case "br":
+			r = brotli.NewReader(r)
+		case "zstd":
+			var decoder *zstd.Decoder
+			decoder, err = zstd.NewReader(r, zstd.WithDecoderConcurrency(1))
+			if err == nil {
+				r = decoder.IOReadCloser()
+			}
+		default:
+			return nil, fmt.Errorf("неподдерживаемое сжатие ответа: %s", encodings[i])
+		}
+		if err != nil {
+			return nil, fmt.Errorf("ошибка распаковки %s: %w", encodings[i], err)
+		}
+	}
+	return r, nil
+}
+
+// deflateReader распаковывает deflate: по стандарту это zlib поток,
+// но часть серверов отдает «сырой» deflate без заголовка zlib
+func deflateReader(r io.Reader) (io.Reader, error) {
+	buffered := bufio.NewReader(r)
+	header, err := buffered.Peek(2)
+	if err != nil && len(header) < 2 {
+		return flate.NewReader(buffered), nil
+	}
+
+	// Заголовок zlib: метод сжатия 8 и контрольная сумма, кратная 31
+	if header[0]&0x0f == 8 && (uint16(header[0])<<8|uint16(header[1]))%31 == 0 {
+		return zlib.NewReader(buffered)
+	}
+	return flate.NewReader(buffered), nil
+}
+
+// decodeCachedResponse распаковывает ответ, сохраненный в кэше сжатым
+// до появления распаковки (записи дискового кэша переживают перезапуск).
+// Запись, которая распаковывается больше maxSize байт, не используется, 0 - без ограничения
+func decodeCachedResponse(cached *CachedResponse, maxSize int64) *CachedResponse {
+	if cached == nil {
+		return nil
+	}
+
+	encodings := contentEncodings(cached.Headers)
+	if len(encodings) == 0 {
+		return cached
+	}
+
+	body, err := decodingReader(bytes.NewReader(cached.Content), encodings)
+	if err != nil {
+		return nil
+	}
+	if maxSize > 0 {
+		body = io.LimitReader(body, maxSize+1)
+	}
+	content, err := io.ReadAll(body)
+	if err != nil {
+		return nil
+	}
+	if maxSize > 0 && int64(len(content)) > maxSize {
+		return nil
+	}
+
+	decoded := *cached
+	decoded.Headers = cached.Headers.Clone()
+	decoded.Headers.Del("Content-Encoding")
+	decoded.Headers.Del("Content-Length")
+	decoded.Content, decoded.Headers = normalizeCharset(content, decoded.Headers)
+	return &decoded
+}
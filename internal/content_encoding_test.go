@@ -0,0 +1,196 @@
+package internal
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+)
+
+const compressedPage = "<html><body><p>Сжатая страница</p></body></html>"
+
+// compressBody сжимает данные указанным способом
+func compressBody(t *testing.T, encoding string, data []byte) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	var w io.WriteCloser
+	switch encoding {
+	case "gzip":
+		w = gzip.NewWriter(&buf)
+	case "deflate":
+		w = zlib.NewWriter(&buf)
+	case "raw-deflate":
+		w, _ = flate.NewWriter(&buf, flate.DefaultCompression)
+	case "br":
+		w = brotli.NewWriter(&buf)
+	case "zstd":
+		w, _ = zstd.NewWriter(&buf)
+	default:
+		t.Fatalf("Неизвестное сжатие %s", encoding)
+	}
+
+	if _, err := w.Write(data); err != nil {
+		t.Fatalf("Ошибка сжатия: %v", err)
+	}
+	w.Close()
+	return buf.Bytes()
+}
+
+func TestEthicalScraperDecodesContentEncoding(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		layers []string // в порядке применения
+	}{
+		{"gzip", "gzip", []string{"gzip"}},
+		{"deflate", "deflate", []string{"deflate"}},
+		{"raw-deflate", "deflate", []string{"raw-deflate"}},
+		{"br", "br", []string{"br"}},
+		{"zstd", "zstd", []string{"zstd"}},
+		{"gzip-br", "gzip, br", []string{"gzip", "br"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := []byte(compressedPage)
+			for _, layer := range tt.layers {
+				body = compressBody(t, layer, body)
+			}
+
+			var acceptEncodingHeader string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path == "/robots.txt" {
+					http.NotFound(w, r)
+					return
+				}
+				acceptEncodingHeader = r.Header.Get("Accept-Encoding")
+				w.Header().Set("Content-Type", "text/html; charset=utf-8")
+				w.Header().Set("Content-Encoding", tt.header)
+				w.Header().Set("Cache-Control", "max-age=60")
+				w.Write(body)
+			}))
+			defer server.Close()
+
+			scraper := NewEthicalScraper(&Config{
+				HTTPTimeout:          30,
+				CacheTTL:             1,
+				UserAgent:            "Test-Bot/1.0",
+				AllowPrivateNetworks: true,
+			})
+
+			for _, cached := range []bool{false, true} {
+				result, err := scraper.ScrapeURL(context.Background(), server.URL+"/page")
+				if err != nil {
+					t.Fatalf("Ошибка загрузки: %v", err)
+				}
+				if result.IsCached != cached {
+					t.Errorf("Ожидалось IsCached=%t", cached)
+				}
+				if string(result.Content) != compressedPage {
+					t.Errorf("Ответ должен быть распакован, получено %q", result.Content)
+				}
+				if result.Headers.Get("Content-Encoding") != "" {
+					t.Errorf("Заголовок Content-Encoding должен быть удален: %s", result.Headers.Get("Content-Encoding"))
+				}
+			}
+
+			if acceptEncodingHeader != acceptEncoding {
+				t.Errorf("Ожидался Accept-Encoding %q, получен %q", acceptEncoding, acceptEncodingHeader)
+			}
+		})
+	}
+}
+
+func TestDecodeCachedResponse(t *testing.T) {
+	headers := http.Header{}
+	headers.Set("Content-Type", "text/html")
+	headers.Set("Content-Encoding", "gzip")
+
+	// Запись, сохраненная в кэш сжатой
+	cached := &CachedResponse{
+		Content:    compressBody(t, "gzip", []byte(compressedPage)),
+		Headers:    headers,
+		StatusCode: http.StatusOK,
+	}
+
+	decoded := decodeCachedResponse(cached, 0)
+	if decoded == nil || string(decoded.Content) != compressedPage {
+		t.Fatalf("Сжатая запись кэша должна распаковываться: %+v", decoded)
+	}
+	if decoded.Headers.Get("Content-Encoding") != "" || cached.Headers.Get("Content-Encoding") != "gzip" {
+		t.Error("Заголовки распакованной копии не должны содержать Content-Encoding, исходные не должны меняться")
+	}
+
+	plain := &CachedResponse{Content: []byte(compressedPage), Headers: http.Header{}}
+	if decodeCachedResponse(plain, 0) != plain {
+		t.Error("Несжатая запись должна возвращаться без изменений")
+	}
+
+	broken := &CachedResponse{Content: []byte("не gzip"), Headers: headers}
+	if decodeCachedResponse(broken, 0) != nil {
+		t.Error("Поврежденная запись не должна использоваться")
+	}
+
+	limit := int64(len(compressedPage))
+	if decodeCachedResponse(cached, limit) == nil {
+		t.Error("Запись размером с ограничение должна использоваться")
+	}
+	if decodeCachedResponse(cached, limit-1) != nil {
+		t.Error("Запись больше ограничения после распаковки не должна использоваться")
+	}
+}
+
+func TestScraperDropsOversizedCachedResponse(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/robots.txt" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		requests++
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(compressedPage))
+	}))
+	defer server.Close()
+
+	scraper := NewEthicalScraper(&Config{
+		HTTPTimeout:          30,
+		AllowPrivateNetworks: true, // httptest сервер слушает 127.0.0.1
+		CacheTTL:             1,
+		MaxBodySizeMB:        1,
+		UserAgent:            "Test-Bot/1.0",
+	})
+
+	// Старая сжатая запись распаковывается больше допустимого размера
+	headers := http.Header{}
+	headers.Set("Content-Type", "text/html")
+	headers.Set("Content-Encoding", "gzip")
+	pageURL := server.URL + "/bomb"
+	scraper.cache.Set(CacheKey(pageURL), &CachedResponse{
+		Content:    compressBody(t, "gzip", bytes.Repeat([]byte("a"), 2*1024*1024)),
+		Headers:    headers,
+		StatusCode: http.StatusOK,
+		ExpiresAt:  time.Now().Add(time.Hour),
+	})
+
+	result, err := scraper.ScrapeURL(context.Background(), pageURL)
+	if err != nil {
+		t.Fatalf("Ошибка запроса: %v", err)
+	}
+	if result.IsCached || requests != 1 || string(result.Content) != compressedPage {
+		t.Errorf("Слишком большая запись кэша должна считаться промахом: cached=%v, запросов %d", result.IsCached, requests)
+	}
+	if cached := scraper.cache.Get(CacheKey(pageURL)); cached == nil || cached.Headers.Get("Content-Encoding") != "" {
+		t.Error("Слишком большая запись должна быть заменена свежим ответом")
+	}
+}
@@ -1,9 +1,9 @@
 package internal
 
 import (
+	"bytes"
+	"context"
 	"fmt"
-	"io"
-	"net/http"
 	"net/url"
 	"regexp"
 	"strings"
@@ -22,7 +22,24 @@ type Content struct {
 	Markdown string
 	URL      string
 	Author   string
-	Date     string
+	Date     string // дата публикации для показа, пустая если не разобрана
+	Language string // код языка страницы, например en или ru-RU
+	Tags     []string
+
+	Description  string
+	SiteName     string
+	CanonicalURL string
+	Image        string    // адрес главного изображения статьи
+	Published    time.Time // нулевое время - дата публикации неизвестна
+	Modified     time.Time
+	AMPURL       string // адрес AMP версии страницы из <link rel="amphtml">
+
+	amp bool // страница является AMP версией
+}
+
+// SourceURL возвращает ссылку на статью для документа: каноническую, если она известна
+func (c *Content) SourceURL() string {
+	return firstNonEmpty(c.CanonicalURL, c.URL)
 }
 
 // CollyConfig содержит конфигурацию для Colly
@@ -32,6 +49,15 @@ type CollyConfig struct {
 	MaxRetries     int
 	FollowRedirect bool
 	RespectRobots  bool
+
+	// MaxBodySize ограничивает размер страницы в байтах, 0 - без ограничения
+	MaxBodySize int64
+
+	// Network ограничивает адреса для загрузки (по умолчанию - политика из SetNetworkPolicy)
+	Network *NetworkPolicy
+
+	// Fetcher переопределяет способ загрузки страницы (по умолчанию Colly)
+	Fetcher Fetcher
 }
 
 // DefaultCollyConfig возвращает конфигурацию по умолчанию
@@ -42,9 +68,18 @@ func DefaultCollyConfig() *CollyConfig {
 		MaxRetries:     3,
 		FollowRedirect: true,
 		RespectRobots:  false,
+		MaxBodySize:    pageLimits.MaxBodySize,
 	}
 }
 
+// network возвращает сетевую политику коллектора
+func (config *CollyConfig) network() *NetworkPolicy {
+	if config.Network != nil {
+		return config.Network
+	}
+	return networkPolicy
+}
+
 // createCollyCollector создает и настраивает коллектор Colly
 func createCollyCollector(config *CollyConfig) *colly.Collector {
 	c := colly.NewCollector(
@@ -56,6 +91,14 @@ func createCollyCollector(config *CollyConfig) *colly.Collector {
 	// Настройка таймаута
 	c.SetRequestTimeout(config.Timeout)
 
+	// Ограничение размера ответа, 0 - без ограничения
+	c.MaxBodySize = int(config.MaxBodySize)
+
+	// Подключаемся только к публичным адресам и проверяем каждый редирект
+	network := config.network()
+	c.WithTransport(network.Transport())
+	c.SetRedirectHandler(network.CheckRedirect)
+
 	// Настройка лимитов
 	c.Limit(&colly.LimitRule{
 		DomainGlob:  "*",
@@ -87,184 +130,226 @@ func createCollyCollector(config *CollyConfig) *colly.Collector {
 }
 
 // ExtractContent извлекает контент из веб-страницы с использованием Colly
-func ExtractContent(pageURL string) (*Content, error) {
-	return ExtractContentWithConfig(pageURL, DefaultCollyConfig())
+func ExtractContent(ctx context.Context, pageURL string) (*Content, error) {
+	return ExtractContentWithConfig(ctx, pageURL, DefaultCollyConfig())
 }
 
-// ExtractContentWithConfig извлекает контент с пользовательской конфигурацией
-func ExtractContentWithConfig(pageURL string, config *CollyConfig) (*Content, error) {
+// ExtractContentWithConfig извлекает контент с пользовательской конфигурацией.
+// Отмена ctx прерывает загрузку страницы и пропускает разбор
+func ExtractContentWithConfig(ctx context.Context, pageURL string, config *CollyConfig) (*Content, error) {
 	fmt.Printf("Извлекаю контент из: %s\n", pageURL)
 
-	// Создаем коллектор Colly
-	c := createCollyCollector(config)
-
-	// Переменные для хранения данных
-	var htmlContent string
-	var finalURL string
-	var loadError error
-
-	// Настраиваем обработчик для получения HTML
-	c.OnResponse(func(r *colly.Response) {
-		finalURL = r.Request.URL.String()
-		htmlContent = string(r.Body)
-		fmt.Printf("Успешно загружена страница: %s (размер: %d байт)\n", finalURL, len(htmlContent))
-	})
-
-	// Настраиваем обработчик ошибок
-	c.OnError(func(r *colly.Response, err error) {
-		loadError = fmt.Errorf("ошибка при загрузке %s: %w", r.Request.URL, err)
-	})
-
-	// Выполняем запрос
-	err := c.Visit(pageURL)
-	if err != nil {
-		return nil, fmt.Errorf("ошибка при посещении страницы: %w", err)
-	}
-
-	// Проверяем ошибки загрузки
-	if loadError != nil {
-		return nil, loadError
+	// Выбираем источник загрузки страницы
+	fetcher := config.Fetcher
+	if fetcher == nil {
+		fetcher = NewCollyFetcher(config)
 	}
 
-	// Проверяем, что контент загружен
-	if htmlContent == "" {
-		return nil, fmt.Errorf("не удалось загрузить контент страницы")
-	}
-
-	// Парсим HTML с помощью goquery для извлечения метаданных
-	doc, err := goquery.NewDocumentFromReader(strings.NewReader(htmlContent))
-	if err != nil {
-		return nil, fmt.Errorf("ошибка при парсинге HTML: %w", err)
-	}
+	// Разворачиваем переадресации и переходим к известной канонической ссылке
+	pageURL = ResolveURL(pageURL)
 
-	// Парсим URL для go-readability
-	parsedURL, err := url.Parse(finalURL)
+	// Загружаем страницу
+	fetchStart := time.Now()
+	result, err := fetchPage(ctx, fetcher, pageURL)
+	observeStage(stageFetch, fetchStart)
 	if err != nil {
-		return nil, fmt.Errorf("ошибка при парсинге URL: %w", err)
+		observeBlocked(err)
+		return nil, err
 	}
-
-	// Извлекаем контент с помощью go-readability
-	article, err := readability.FromReader(strings.NewReader(htmlContent), parsedURL)
-	if err != nil {
-		return nil, fmt.Errorf("ошибка при извлечении контента: %w", err)
+	if err := checkContentType(result, pageLimits.ContentTypes); err != nil {
+		return nil, err
 	}
-
-	// Создаем объект контента
-	content := &Content{
-		URL: finalURL,
+	if err := ctx.Err(); err != nil {
+		return nil, err
 	}
 
-	// Извлекаем заголовок (приоритет go-readability, затем fallback)
-	if article.Title != "" {
-		content.Title = article.Title
-	} else {
-		content.Title = extractTitle(doc)
+	parseStart := time.Now()
+	content, err := parseFetchResult(result)
+	observeStage(stageReadability, parseStart)
+	if err != nil {
+		return nil, err
+	}
+
+	// AMP версия страницы урезана, поэтому загружаем каноническую. Ссылки
+	// сравниваются без CacheKey: он может уже связывать AMP версию с канонической
+	if content.amp && usableCanonical(result.FinalURL, content.CanonicalURL) &&
+		NormalizeURL(content.CanonicalURL) != NormalizeURL(result.FinalURL) {
+		fmt.Printf("AMP страница, загружаю каноническую: %s\n", content.CanonicalURL)
+		if canonical, err := fetchPage(ctx, fetcher, NormalizeURL(content.CanonicalURL)); err == nil &&
+			checkContentType(canonical, pageLimits.ContentTypes) == nil {
+			if canonicalContent, err := parseContent(canonical.Body, canonical.FinalURL); err == nil {
+				content = canonicalContent
+			}
+		}
 	}
 
-	// Извлекаем основной текст и конвертируем в markdown
-	content.Markdown = extractAndConvertToMarkdown(article)
-
-	// Извлекаем автора
-	if article.Byline != "" {
-		content.Author = article.Byline
+	// Запоминаем каноническую ссылку для вариантов ссылки и AMP версии
+	if usableCanonical(content.URL, content.CanonicalURL) {
+		canonicalURLs.remember(content.CanonicalURL, pageURL, result.FinalURL, content.URL, content.AMPURL)
 	} else {
-		content.Author = extractAuthor(doc)
+		content.CanonicalURL = ""
 	}
 
-	// Извлекаем дату
-	content.Date = extractDate(doc)
-
 	fmt.Printf("Извлечено: заголовок='%s', длина markdown=%d символов\n",
 		content.Title, len(content.Markdown))
 
 	return content, nil
 }
 
+// fetchPage загружает страницу и переходит по <meta http-equiv="refresh">,
+// которым сервисы коротких ссылок вроде t.co переадресуют браузеры
+func fetchPage(ctx context.Context, f Fetcher, pageURL string) (*FetchResult, error) {
+	for i := 0; ; i++ {
+		result, err := f.Fetch(ctx, pageURL)
+		if err != nil {
+			return nil, err
+		}
+		if result.FinalURL == "" {
+			result.FinalURL = pageURL
+		}
+
+		if i == maxRedirectorUnwraps || !isShortenerURL(result.FinalURL) {
+			return result, nil
+		}
+
+		doc, err := goquery.NewDocumentFromReader(bytes.NewReader(result.Body))
+		if err != nil {
+			return result, nil
+		}
+		base, _ := url.Parse(result.FinalURL)
+		target := metaRefreshURL(doc, base)
+		if target == "" {
+			return result, nil
+		}
+		pageURL = NormalizeURL(target)
+	}
+}
+
 // ExtractContentWithFallback извлекает контент с fallback на стандартный HTTP клиент
-func ExtractContentWithFallback(pageURL string) (*Content, error) {
+func ExtractContentWithFallback(ctx context.Context, pageURL string) (*Content, error) {
 	// Сначала пробуем с Colly
-	content, err := ExtractContent(pageURL)
+	content, err := ExtractContent(ctx, pageURL)
 	if err == nil {
 		return content, nil
 	}
 
+	// Отмененную загрузку не повторяем
+	if ctx.Err() != nil {
+		return nil, err
+	}
+
 	fmt.Printf("Colly не удалось загрузить страницу, пробуем fallback: %v\n", err)
 
 	// Fallback на стандартный HTTP клиент
-	return extractContentWithHTTPClient(pageURL)
+	fallbackUsed.Inc()
+	return extractContentWithHTTPClient(ctx, pageURL)
 }
 
 // extractContentWithHTTPClient извлекает контент с помощью стандартного HTTP клиента
-func extractContentWithHTTPClient(pageURL string) (*Content, error) {
+func extractContentWithHTTPClient(ctx context.Context, pageURL string) (*Content, error) {
 	fmt.Printf("Использую fallback HTTP клиент для: %s\n", pageURL)
 
-	// Создаем HTTP клиент с таймаутом
-	client := &http.Client{
-		Timeout: 30 * time.Second,
+	result, err := NewHTTPFetcher(30*time.Second).Fetch(ctx, pageURL)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkContentType(result, pageLimits.ContentTypes); err != nil {
+		return nil, err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
 	}
 
-	// Выполняем запрос
-	resp, err := client.Get(pageURL)
+	content, err := parseFetchResult(result)
 	if err != nil {
-		return nil, fmt.Errorf("ошибка при загрузке страницы: %w", err)
+		return nil, err
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != 200 {
-		return nil, fmt.Errorf("статус ответа: %d", resp.StatusCode)
-	}
+	fmt.Printf("Извлечено (fallback): заголовок='%s', длина markdown=%d символов\n",
+		content.Title, len(content.Markdown))
 
-	// Читаем тело ответа
-	bodyBytes, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("ошибка при чтении тела ответа: %w", err)
+	return content, nil
+}
+
+// parseFetchResult извлекает контент из загруженного документа в зависимости от его типа
+func parseFetchResult(result *FetchResult) (*Content, error) {
+	if responseContentType(result) == "application/pdf" {
+		return parsePDFContent(result.Body, result.FinalURL)
 	}
+	return parseContent(result.Body, result.FinalURL)
+}
+
+// parseContent извлекает контент и метаданные из загруженного HTML
+func parseContent(body []byte, finalURL string) (*Content, error) {
+	htmlContent := string(body)
 
 	// Парсим HTML с помощью goquery для извлечения метаданных
-	doc, err := goquery.NewDocumentFromReader(strings.NewReader(string(bodyBytes)))
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(htmlContent))
 	if err != nil {
 		return nil, fmt.Errorf("ошибка при парсинге HTML: %w", err)
 	}
 
 	// Парсим URL для go-readability
-	parsedURL, err := url.Parse(pageURL)
+	parsedURL, err := url.Parse(finalURL)
 	if err != nil {
 		return nil, fmt.Errorf("ошибка при парсинге URL: %w", err)
 	}
 
+	// Относительные ссылки разрешаются относительно <base href>, если он задан
+	baseURL := documentBaseURL(doc, parsedURL)
+
 	// Извлекаем контент с помощью go-readability
-	article, err := readability.FromReader(strings.NewReader(string(bodyBytes)), parsedURL)
+	article, err := readability.FromReader(strings.NewReader(htmlContent), baseURL)
 	if err != nil {
 		return nil, fmt.Errorf("ошибка при извлечении контента: %w", err)
 	}
 
+	// Метаданные из JSON-LD, OpenGraph, Twitter Cards, Dublin Core и микроданных
+	meta := extractMetadata(doc, baseURL)
+
 	// Создаем объект контента
 	content := &Content{
-		URL: pageURL,
+		URL:          finalURL,
+		Description:  firstNonEmpty(meta.Description, article.Excerpt),
+		SiteName:     firstNonEmpty(meta.SiteName, article.SiteName),
+		CanonicalURL: meta.CanonicalURL,
+		AMPURL:       meta.AMPURL,
+		amp:          isAMPDocument(doc),
+		Image:        firstNonEmpty(meta.Image, resolveMetadataURL(baseURL, article.Image)),
+		Tags:         meta.Tags,
+		Modified:     meta.Modified,
 	}
 
 	// Извлекаем заголовок (приоритет go-readability, затем fallback)
 	if article.Title != "" {
 		content.Title = article.Title
+	} else if meta.Title != "" {
+		content.Title = meta.Title
 	} else {
 		content.Title = extractTitle(doc)
 	}
 
+	// Делаем адреса изображений абсолютными и выбираем лучший вариант из srcset
+	article.Content = resolveImageSources(article.Content, baseURL)
+
 	// Извлекаем основной текст и конвертируем в markdown
 	content.Markdown = extractAndConvertToMarkdown(article)
 
-	// Извлекаем автора
-	if article.Byline != "" {
-		content.Author = article.Byline
-	} else {
-		content.Author = extractAuthor(doc)
-	}
+	// Делаем оставшиеся относительные ссылки абсолютными и убираем параметры отслеживания
+	content.Markdown = RewriteLinks(content.Markdown, baseURL)
+
+	// Извлекаем автора: структурированные метаданные надежнее разметки страницы
+	content.Author = firstNonEmpty(meta.Author, article.Byline, extractAuthor(doc))
 
 	// Извлекаем дату
-	content.Date = extractDate(doc)
+	content.Published = meta.Published
+	if content.Published.IsZero() {
+		content.Published = extractDate(doc)
+	}
+	content.Date = formatContentDate(content.Published)
 
-	fmt.Printf("Извлечено (fallback): заголовок='%s', длина markdown=%d символов\n",
-		content.Title, len(content.Markdown))
+	// Извлекаем язык
+	content.Language = firstNonEmpty(normalizeLanguage(article.Language), meta.Language)
 
 	return content, nil
 }
@@ -326,42 +411,56 @@ func extractAuthor(doc *goquery.Document) string {
 	return ""
 }
 
-// extractDate извлекает дату публикации
-func extractDate(doc *goquery.Document) string {
+// extractDate извлекает дату публикации из разметки страницы. Текст, который
+// не удается разобрать как дату (например, "3 дня назад"), пропускается
+func extractDate(doc *goquery.Document) time.Time {
 	selectors := []string{
+		"time[datetime]",
+		"time",
 		".date",
 		".post-date",
 		".article-date",
 		".published",
-		"[property='article:published_time']",
-		"[property='og:published_time']",
-		"time",
 		".timestamp",
 	}
 
 	for _, selector := range selectors {
-		date := doc.Find(selector).First()
-		if selector == "time" {
-			dateStr := date.AttrOr("datetime", "")
-			if dateStr == "" {
-				dateStr = date.Text()
-			}
-			if dateStr != "" {
-				return strings.TrimSpace(dateStr)
-			}
-		} else if strings.Contains(selector, "property=") {
-			dateStr := date.AttrOr("content", "")
-			if dateStr != "" {
-				return strings.TrimSpace(dateStr)
-			}
-		} else {
-			dateStr := date.Text()
-			if dateStr != "" {
-				return strings.TrimSpace(dateStr)
-			}
+		var date time.Time
+		doc.Find(selector).EachWithBreak(func(i int, s *goquery.Selection) bool {
+			date = parseMetadataDate(s.AttrOr("datetime", s.Text()))
+			return date.IsZero()
+		})
+		if !date.IsZero() {
+			return date
 		}
 	}
 
+	return time.Time{}
+}
+
+// formatContentDate возвращает дату для показа: без времени, если оно не указано
+func formatContentDate(date time.Time) string {
+	if date.IsZero() {
+		return ""
+	}
+	if isDateOnly(date) {
+		return date.Format("2006-01-02")
+	}
+	return date.Format("2006-01-02 15:04")
+}
+
+// isDateOnly сообщает, что время разобрано из даты без времени суток
+func isDateOnly(date time.Time) bool {
+	return date.Location() == time.UTC && date.Equal(date.Truncate(24*time.Hour))
+}
+
+// firstNonEmpty возвращает первую непустую строку
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value = strings.TrimSpace(value); value != "" {
+			return value
+		}
+	}
 	return ""
 }
 
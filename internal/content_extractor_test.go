@@ -1,6 +1,7 @@
 package internal
 
 import (
+	"context"
 	"strings"
 	"testing"
 
@@ -390,7 +391,7 @@ func TestConvertToMarkdownLocalization(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result := ConvertToMarkdown(content, "https://example.com/article", tt.locale)
+			result := ConvertToMarkdown(context.Background(), content, "https://example.com/article", tt.locale)
 
 			for _, expected := range tt.expected {
 				if !strings.Contains(result, expected) {
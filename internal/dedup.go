@@ -0,0 +1,303 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// UpdateStore отслеживает обновления Telegram, чтобы не обрабатывать повторы
+type UpdateStore interface {
+	// Begin отмечает начало обработки; возвращает false, если обновление
+	// уже обработано или обрабатывается
+	Begin(updateID int) bool
+	// Done отмечает, что обработка обновления завершена
+	Done(updateID int)
+	// Close сохраняет несохраненные изменения при остановке
+	Close()
+}
+
+// dedupFlushInterval - как часто FileUpdateStore записывает изменения на диск
+const dedupFlushInterval = 2 * time.Second
+
+// updateState описывает состояние обновления в хранилище
+type updateState struct {
+	Processing bool      `json:"processing"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
+
+// MemoryUpdateStore хранит идентификаторы обновлений в памяти с ограничением по TTL и размеру
+type MemoryUpdateStore struct {
+	mu         sync.Mutex
+	ttl        time.Duration
+	maxEntries int
+	entries    map[int]*updateState
+	order      []orderedUpdate
+}
+
+// orderedUpdate фиксирует порядок добавления записей для вытеснения
+type orderedUpdate struct {
+	id        int
+	expiresAt time.Time
+}
+
+// NewMemoryUpdateStore создает хранилище обновлений в памяти
+func NewMemoryUpdateStore(ttl time.Duration, maxEntries int) *MemoryUpdateStore {
+	if maxEntries < 1 {
+		maxEntries = 1
+	}
+
+	return &MemoryUpdateStore{
+		ttl:        ttl,
+		maxEntries: maxEntries,
+		entries:    make(map[int]*updateState),
+	}
+}
+
+// Begin отмечает начало обработки обновления
+func (s *MemoryUpdateStore) Begin(updateID int) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.evict(now)
+
+	if state, exists := s.entries[updateID]; exists && now.Before(state.ExpiresAt) {
+		return false
+	}
+
+	expiresAt := now.Add(s.ttl)
+	s.entries[updateID] = &updateState{
+		Processing: true,
+		ExpiresAt:  expiresAt,
+	}
+	s.order = append(s.order, orderedUpdate{id: updateID, expiresAt: expiresAt})
+
+	return true
+}
+
+// Done отмечает завершение обработки обновления
+func (s *MemoryUpdateStore) Done(updateID int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if state, exists := s.entries[updateID]; exists {
+		state.Processing = false
+	}
+}
+
+// Close ничего не делает: записи в памяти не сохраняются
+func (s *MemoryUpdateStore) Close() {}
+
+// evict удаляет устаревшие записи и самые старые записи сверх лимита
+func (s *MemoryUpdateStore) evict(now time.Time) {
+	for len(s.order) > 0 {
+		oldest := s.order[0]
+		state, exists := s.entries[oldest.id]
+
+		// Запись в очереди устарела, если обновление позже добавили заново
+		current := exists && state.ExpiresAt.Equal(oldest.expiresAt)
+		if current && now.Before(state.ExpiresAt) && len(s.entries) < s.maxEntries {
+			break
+		}
+
+		s.order = s.order[1:]
+		if current {
+			delete(s.entries, oldest.id)
+		}
+	}
+}
+
+// snapshot возвращает копию записей
+func (s *MemoryUpdateStore) snapshot() map[int]updateState {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	result := make(map[int]updateState, len(s.entries))
+	for id, state := range s.entries {
+		result[id] = *state
+	}
+	return result
+}
+
+// restore загружает записи, пропуская устаревшие и незавершенные
+func (s *MemoryUpdateStore) restore(entries map[int]updateState) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for id, state := range entries {
+		// Незавершенная обработка прервалась вместе с процессом
+		if state.Processing || !now.Before(state.ExpiresAt) {
+			continue
+		}
+		restored := state
+		s.entries[id] = &restored
+		s.order = append(s.order, orderedUpdate{id: id, expiresAt: state.ExpiresAt})
+	}
+
+	// Восстанавливаем порядок вытеснения по времени истечения
+	sort.Slice(s.order, func(i, j int) bool {
+		return s.order[i].expiresAt.Before(s.order[j].expiresAt)
+	})
+	s.evict(now)
+}
+
+// FileUpdateStore сохраняет обработанные обновления в JSON файл. Изменения
+// записываются раз в dedupFlushInterval и при остановке, а не на каждое обновление
+type FileUpdateStore struct {
+	mu     sync.Mutex
+	path   string
+	memory *MemoryUpdateStore
+	dirty  bool
+
+	stop      chan struct{}
+	stopped   chan struct{}
+	closeOnce sync.Once
+}
+
+// NewFileUpdateStore создает хранилище обновлений с сохранением на диск
+func NewFileUpdateStore(path string, ttl time.Duration, maxEntries int) (*FileUpdateStore, error) {
+	store := &FileUpdateStore{
+		path:    path,
+		memory:  NewMemoryUpdateStore(ttl, maxEntries),
+		stop:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("ошибка чтения %s: %w", path, err)
+	}
+
+	if len(data) > 0 {
+		var entries map[int]updateState
+		if err := json.Unmarshal(data, &entries); err != nil {
+			return nil, fmt.Errorf("ошибка разбора %s: %w", path, err)
+		}
+		store.memory.restore(entries)
+	}
+
+	go store.flushLoop(dedupFlushInterval)
+
+	return store, nil
+}
+
+// Begin отмечает начало обработки обновления
+func (s *FileUpdateStore) Begin(updateID int) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.memory.Begin(updateID) {
+		return false
+	}
+
+	s.dirty = true
+	return true
+}
+
+// Done отмечает завершение обработки обновления
+func (s *FileUpdateStore) Done(updateID int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.memory.Done(updateID)
+	s.dirty = true
+}
+
+// Close останавливает периодическое сохранение и записывает последние изменения
+func (s *FileUpdateStore) Close() {
+	s.closeOnce.Do(func() {
+		close(s.stop)
+		<-s.stopped
+	})
+}
+
+// flushLoop периодически сохраняет изменения, пока хранилище не закрыто
+func (s *FileUpdateStore) flushLoop(interval time.Duration) {
+	defer close(s.stopped)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.flush()
+		case <-s.stop:
+			s.flush()
+			return
+		}
+	}
+}
+
+// flush атомарно записывает состояние в файл, если оно изменилось
+func (s *FileUpdateStore) flush() {
+	s.mu.Lock()
+	if !s.dirty {
+		s.mu.Unlock()
+		return
+	}
+	data, err := json.Marshal(s.memory.snapshot())
+	s.dirty = false
+	s.mu.Unlock()
+
+	if err != nil {
+		logger.Errorf("Ошибка сериализации обработанных обновлений: %v", err)
+		return
+	}
+
+	if err := writeFileAtomic(s.path, data); err != nil {
+		logger.Errorf("Ошибка сохранения обработанных обновлений: %v", err)
+
+		// Повторим при следующем сохранении
+		s.mu.Lock()
+		s.dirty = true
+		s.mu.Unlock()
+	}
+}
+
+// writeFileAtomic записывает файл через временный файл и переименование
+func writeFileAtomic(path string, data []byte) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(dir, filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
+
+// NewUpdateStore создает хранилище обновлений в соответствии с конфигурацией
+func NewUpdateStore(config *Config) UpdateStore {
+	ttl := time.Duration(config.DedupTTL) * time.Minute
+
+	if config.DedupStore == "file" {
+		store, err := NewFileUpdateStore(config.DedupFile, ttl, config.DedupMaxEntries)
+		if err == nil {
+			return store
+		}
+		logger.Errorf("Не удалось открыть хранилище обновлений, используем память: %v", err)
+	}
+
+	return NewMemoryUpdateStore(ttl, config.DedupMaxEntries)
+}
@@ -0,0 +1,133 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestMemoryUpdateStore(t *testing.T) {
+	store := NewMemoryUpdateStore(time.Hour, 100)
+
+	if !store.Begin(1) {
+		t.Fatal("Первое обновление должно быть принято")
+	}
+
+	// Обновление еще обрабатывается
+	if store.Begin(1) {
+		t.Error("Обрабатываемое обновление не должно приниматься повторно")
+	}
+
+	store.Done(1)
+
+	// Обновление уже обработано
+	if store.Begin(1) {
+		t.Error("Обработанное обновление не должно приниматься повторно")
+	}
+
+	if !store.Begin(2) {
+		t.Error("Новое обновление должно быть принято")
+	}
+}
+
+func TestMemoryUpdateStoreTTL(t *testing.T) {
+	store := NewMemoryUpdateStore(10*time.Millisecond, 100)
+
+	store.Begin(1)
+	store.Done(1)
+
+	time.Sleep(20 * time.Millisecond)
+
+	if !store.Begin(1) {
+		t.Error("Обновление с истекшим TTL должно приниматься снова")
+	}
+}
+
+func TestMemoryUpdateStoreMaxEntries(t *testing.T) {
+	store := NewMemoryUpdateStore(time.Hour, 3)
+
+	for id := 1; id <= 5; id++ {
+		store.Begin(id)
+		store.Done(id)
+	}
+
+	if len(store.entries) > 3 {
+		t.Errorf("Хранилище должно содержать не больше 3 записей, содержит %d", len(store.entries))
+	}
+
+	// Самые старые записи вытеснены
+	if !store.Begin(1) {
+		t.Error("Вытесненное обновление должно приниматься снова")
+	}
+
+	// Свежие записи сохранены
+	if store.Begin(5) {
+		t.Error("Недавнее обновление не должно приниматься повторно")
+	}
+}
+
+func TestFileUpdateStorePersistence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "updates.json")
+
+	store, err := NewFileUpdateStore(path, time.Hour, 100)
+	if err != nil {
+		t.Fatalf("Ошибка создания хранилища: %v", err)
+	}
+
+	store.Begin(10)
+	store.Done(10)
+	store.Begin(11) // Обработка прервана перезапуском
+
+	// Изменения записываются периодически, а не на каждое обновление
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Error("Файл не должен перезаписываться на каждое обновление")
+	}
+
+	// При остановке несохраненные изменения записываются
+	store.Close()
+
+	restored, err := NewFileUpdateStore(path, time.Hour, 100)
+	if err != nil {
+		t.Fatalf("Ошибка загрузки хранилища: %v", err)
+	}
+	defer restored.Close()
+
+	if restored.Begin(10) {
+		t.Error("Обработанное обновление должно сохраниться после перезапуска")
+	}
+
+	if !restored.Begin(11) {
+		t.Error("Прерванное обновление должно обрабатываться после перезапуска")
+	}
+}
+
+func TestFileUpdateStorePeriodicFlush(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "updates.json")
+
+	store, err := NewFileUpdateStore(path, time.Hour, 100)
+	if err != nil {
+		t.Fatalf("Ошибка создания хранилища: %v", err)
+	}
+	defer store.Close()
+
+	// Без изменений файл не создается
+	store.flush()
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Error("Без изменений файл не должен записываться")
+	}
+
+	store.Begin(1)
+	store.Done(1)
+	store.flush()
+
+	restored, err := NewFileUpdateStore(path, time.Hour, 100)
+	if err != nil {
+		t.Fatalf("Ошибка загрузки хранилища: %v", err)
+	}
+	defer restored.Close()
+
+	if restored.Begin(1) {
+		t.Error("Сохраненное обновление не должно приниматься повторно")
+	}
+}
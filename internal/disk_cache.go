@@ -0,0 +1,290 @@
+package internal
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// diskCacheIndexFile хранит метаданные записей дискового кэша
+const diskCacheIndexFile = "index.json"
+
+// diskCacheEntry описывает запись дискового кэша в индексе
+type diskCacheEntry struct {
+	File         string      `json:"file"`
+	Size         int64       `json:"size"`
+	Headers      http.Header `json:"headers"`
+	StatusCode   int         `json:"status_code"`
+	ExpiresAt    time.Time   `json:"expires_at"`
+	ETag         string      `json:"etag,omitempty"`
+	LastModified string      `json:"last_modified,omitempty"`
+	LastAccess   time.Time   `json:"last_access"`
+}
+
+// DiskCache хранит ответы в каталоге в виде gzip файлов и индекса,
+// поэтому кэш переживает перезапуск бота
+type DiskCache struct {
+	mu         sync.Mutex
+	dir        string
+	ttl        time.Duration
+	maxEntries int   // 0 - без ограничения
+	maxBytes   int64 // 0 - без ограничения, учитывается сжатый размер
+	index      map[string]*diskCacheEntry
+	size       int64
+}
+
+// NewDiskCache открывает дисковый кэш, загружая индекс из каталога
+func NewDiskCache(dir string, ttl time.Duration, maxEntries int, maxBytes int64) (*DiskCache, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("ошибка создания каталога кэша: %w", err)
+	}
+
+	dc := &DiskCache{
+		dir:        dir,
+		ttl:        ttl,
+		maxEntries: maxEntries,
+		maxBytes:   maxBytes,
+		index:      make(map[string]*diskCacheEntry),
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, diskCacheIndexFile))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("ошибка чтения индекса кэша: %w", err)
+	}
+
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &dc.index); err != nil {
+			// Поврежденный индекс не должен мешать запуску, начинаем с пустого кэша
+			logrus.Warnf("Индекс дискового кэша поврежден, кэш очищен: %v", err)
+			dc.index = make(map[string]*diskCacheEntry)
+		}
+	}
+
+	// Убираем записи, файлы которых пропали, и устаревшие записи без валидаторов
+	now := time.Now()
+	for key, entry := range dc.index {
+		path := filepath.Join(dir, entry.File)
+		expired := !now.Before(entry.ExpiresAt) && entry.ETag == "" && entry.LastModified == ""
+		if _, err := os.Stat(path); err != nil || expired {
+			delete(dc.index, key)
+			os.Remove(path)
+			continue
+		}
+		dc.size += entry.Size
+	}
+
+	dc.evictLocked()
+	dc.saveIndexLocked()
+
+	return dc, nil
+}
+
+// TTL возвращает время жизни ответа по умолчанию
+func (dc *DiskCache) TTL() time.Duration {
+	return dc.ttl
+}
+
+// Get получает кэшированный ответ с диска
+func (dc *DiskCache) Get(key string) *CachedResponse {
+	dc.mu.Lock()
+	defer dc.mu.Unlock()
+
+	entry, exists := dc.index[key]
+	if !exists {
+		return nil
+	}
+
+	if !time.Now().Before(entry.ExpiresAt) {
+		// Устаревший ответ храним только если его можно подтвердить условным запросом
+		if entry.ETag == "" && entry.LastModified == "" {
+			dc.removeLocked(key)
+			dc.saveIndexLocked()
+		}
+		return nil
+	}
+
+	return dc.loadLocked(key, entry)
+}
+
+// GetStale получает кэшированный ответ с диска независимо от срока его жизни
+func (dc *DiskCache) GetStale(key string) *CachedResponse {
+	dc.mu.Lock()
+	defer dc.mu.Unlock()
+
+	entry, exists := dc.index[key]
+	if !exists {
+		return nil
+	}
+
+	return dc.loadLocked(key, entry)
+}
+
+// loadLocked читает содержимое записи с диска
+func (dc *DiskCache) loadLocked(key string, entry *diskCacheEntry) *CachedResponse {
+	content, err := readGzipFile(filepath.Join(dc.dir, entry.File))
+	if err != nil {
+		logrus.Warnf("Ошибка чтения записи дискового кэша: %v", err)
+		dc.removeLocked(key)
+		dc.saveIndexLocked()
+		return nil
+	}
+
+	entry.LastAccess = time.Now()
+
+	return &CachedResponse{
+		Content:      content,
+		Headers:      entry.Headers,
+		StatusCode:   entry.StatusCode,
+		ExpiresAt:    entry.ExpiresAt,
+		ETag:         entry.ETag,
+		LastModified: entry.LastModified,
+	}
+}
+
+// Set сохраняет ответ на диск
+func (dc *DiskCache) Set(key string, response *CachedResponse) {
+	dc.mu.Lock()
+	defer dc.mu.Unlock()
+
+	dc.removeLocked(key)
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	if _, err := gz.Write(response.Content); err != nil {
+		logrus.Warnf("Ошибка сжатия записи дискового кэша: %v", err)
+		return
+	}
+	if err := gz.Close(); err != nil {
+		logrus.Warnf("Ошибка сжатия записи дискового кэша: %v", err)
+		return
+	}
+
+	// Запись больше всего кэша не сохраняем
+	size := int64(buf.Len())
+	if dc.maxBytes > 0 && size > dc.maxBytes {
+		return
+	}
+
+	file := diskCacheFilename(key)
+	if err := writeFileAtomic(filepath.Join(dc.dir, file), buf.Bytes()); err != nil {
+		logrus.Warnf("Ошибка записи дискового кэша: %v", err)
+		return
+	}
+
+	dc.index[key] = &diskCacheEntry{
+		File:         file,
+		Size:         size,
+		Headers:      response.Headers,
+		StatusCode:   response.StatusCode,
+		ExpiresAt:    response.ExpiresAt,
+		ETag:         response.ETag,
+		LastModified: response.LastModified,
+		LastAccess:   time.Now(),
+	}
+	dc.size += size
+
+	dc.evictLocked()
+	dc.saveIndexLocked()
+}
+
+// Delete удаляет ответ с диска
+func (dc *DiskCache) Delete(key string) {
+	dc.mu.Lock()
+	defer dc.mu.Unlock()
+
+	if _, exists := dc.index[key]; !exists {
+		return
+	}
+
+	dc.removeLocked(key)
+	dc.saveIndexLocked()
+}
+
+// removeLocked удаляет запись и ее файл
+func (dc *DiskCache) removeLocked(key string) {
+	entry, exists := dc.index[key]
+	if !exists {
+		return
+	}
+
+	delete(dc.index, key)
+	dc.size -= entry.Size
+
+	if err := os.Remove(filepath.Join(dc.dir, entry.File)); err != nil && !os.IsNotExist(err) {
+		logrus.Warnf("Ошибка удаления записи дискового кэша: %v", err)
+	}
+}
+
+// evictLocked вытесняет давно неиспользуемые записи сверх лимитов
+func (dc *DiskCache) evictLocked() {
+	overEntries := dc.maxEntries > 0 && len(dc.index) > dc.maxEntries
+	overBytes := dc.maxBytes > 0 && dc.size > dc.maxBytes
+	if !overEntries && !overBytes {
+		return
+	}
+
+	keys := make([]string, 0, len(dc.index))
+	for key := range dc.index {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return dc.index[keys[i]].LastAccess.Before(dc.index[keys[j]].LastAccess)
+	})
+
+	for _, key := range keys {
+		overEntries = dc.maxEntries > 0 && len(dc.index) > dc.maxEntries
+		overBytes = dc.maxBytes > 0 && dc.size > dc.maxBytes
+		if !overEntries && !overBytes {
+			return
+		}
+		dc.removeLocked(key)
+	}
+}
+
+// saveIndexLocked записывает индекс на диск
+func (dc *DiskCache) saveIndexLocked() {
+	data, err := json.Marshal(dc.index)
+	if err != nil {
+		logrus.Warnf("Ошибка сериализации индекса кэша: %v", err)
+		return
+	}
+
+	if err := writeFileAtomic(filepath.Join(dc.dir, diskCacheIndexFile), data); err != nil {
+		logrus.Warnf("Ошибка записи индекса кэша: %v", err)
+	}
+}
+
+// diskCacheFilename возвращает имя файла для ключа кэша
+func diskCacheFilename(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:]) + ".gz"
+}
+
+// readGzipFile читает и распаковывает gzip файл
+func readGzipFile(path string) ([]byte, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		return nil, err
+	}
+	defer gz.Close()
+
+	return io.ReadAll(gz)
+}
@@ -0,0 +1,286 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// jobAbortTimeout - сколько ждать обработчики после отмены задач при остановке
+const jobAbortTimeout = 5 * time.Second
+
+// pendingRestoreTimeout - сколько ждать места в очереди для задач, сохраненных при остановке
+var pendingRestoreTimeout = 30 * time.Second
+
+// Dispatcher распределяет входящие обновления по обработчикам
+type Dispatcher struct {
+	bot     *tgbotapi.BotAPI
+	pool    *WorkerPool
+	updates UpdateStore
+	limiter *UserLimiter
+
+	// ctx - родительский контекст задач, jobs - их функции отмены для /cancel
+	ctx  context.Context
+	jobs *activeJobs
+
+	// pendingFile хранит задачи, не завершенные к остановке, пустой путь - не сохранять
+	pendingFile string
+}
+
+// NewDispatcher создает диспетчер с пулом обработчиков из конфигурации
+func NewDispatcher(bot *tgbotapi.BotAPI, config *Config) *Dispatcher {
+	d := &Dispatcher{
+		bot:     bot,
+		updates: NewUpdateStore(config),
+		limiter: NewUserLimiter(config.UserRateLimit, config.UserDailyQuota, config.AdminUserIDs),
+		ctx:     context.Background(),
+		jobs:    newActiveJobs(),
+
+		pendingFile: config.PendingJobsFile,
+	}
+
+	d.pool = NewWorkerPool(config.WorkerCount, config.JobQueueSize, func(job *Job) {
+		defer d.updates.Done(job.UpdateID)
+		defer d.jobs.remove(messageUserID(job.Message), job)
+
+		// Задачу отменили, пока она ждала в очереди
+		if job.Context().Err() != nil {
+			logger.Infof("Обновление %d отменено до начала обработки", job.UpdateID)
+			return
+		}
+
+		HandleMessage(job.Context(), d.bot, job.Message)
+	})
+
+	return d
+}
+
+// Start запускает обработчики сообщений и ставит в очередь задачи,
+// не завершенные до прошлой остановки
+func (d *Dispatcher) Start() {
+	d.pool.Start()
+	d.restorePending()
+}
+
+// Stop прекращает прием обновлений и ждет обработки принятых
+func (d *Dispatcher) Stop() {
+	d.pool.Stop()
+	d.updates.Close()
+}
+
+// Shutdown прекращает прием обновлений и ждет обработки принятых до истечения ctx.
+// Незавершенные к этому времени задачи отменяются и сохраняются в файл, чтобы
+// обработать их после перезапуска
+func (d *Dispatcher) Shutdown(ctx context.Context) error {
+	// Отметки об обработанных обновлениях сохраняются после завершения обработчиков
+	defer d.updates.Close()
+
+	stopped := make(chan struct{})
+	go func() {
+		d.pool.Stop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+		logger.Info("Все принятые сообщения обработаны")
+		return nil
+	case <-ctx.Done():
+	}
+
+	jobs := d.jobs.drain()
+	logger.Warnf("Время ожидания обработки истекло, прерываем задачи: %d", len(jobs))
+
+	// Прерванная отправка завершается в фоне, повтор такой задачи отправил бы файл дважды
+	unsent := jobs[:0]
+	for _, job := range jobs {
+		if job.sendStarted.Load() {
+			logger.Warnf("Обновление %d уже отправляло файл, не сохраняем его", job.UpdateID)
+			continue
+		}
+		unsent = append(unsent, job)
+	}
+	jobs = unsent
+
+	var saveErr error
+	if err := savePendingJobs(d.pendingFile, jobs); err != nil {
+		saveErr = fmt.Errorf("не удалось сохранить незавершенные задачи: %w", err)
+	} else if d.pendingFile != "" && len(jobs) > 0 {
+		logger.Infof("Незавершенные задачи сохранены в %s", d.pendingFile)
+	}
+
+	// Отмененные задачи завершаются быстро, но зависший обработчик не должен задерживать выход
+	select {
+	case <-stopped:
+	case <-time.After(jobAbortTimeout):
+		logger.Warn("Обработчики не завершились после отмены задач")
+	}
+
+	if saveErr != nil {
+		return saveErr
+	}
+	return ctx.Err()
+}
+
+// restorePending ставит в очередь задачи, сохраненные при прошлой остановке.
+// Сохраненных задач может быть больше, чем мест в очереди, поэтому задачи
+// ждут свободного места; не принятые за pendingRestoreTimeout остаются в файле
+func (d *Dispatcher) restorePending() {
+	// Без бота задачи некому выполнять, файл остается до запуска с ботом
+	if d.bot == nil {
+		return
+	}
+
+	jobs, err := loadPendingJobs(d.pendingFile)
+	if err != nil {
+		logger.Errorf("Не удалось загрузить незавершенные задачи: %v", err)
+		return
+	}
+	if len(jobs) == 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(d.ctx, pendingRestoreTimeout)
+	defer cancel()
+
+	// Обновления могли остаться отмеченными как обработанные, поэтому хранилище не проверяем
+	accepted := 0
+	for _, job := range jobs {
+		userID := messageUserID(job.Message)
+		d.jobs.add(d.ctx, userID, job)
+		if err := d.pool.SubmitWait(ctx, job); err != nil {
+			d.jobs.remove(userID, job)
+			logger.Warnf("Не удалось поставить восстановленное обновление %d в очередь: %v", job.UpdateID, err)
+			break
+		}
+		accepted++
+	}
+
+	logger.Infof("Восстановлено незавершенных задач: %d из %d", accepted, len(jobs))
+
+	// Файл перезаписывается оставшимися задачами или удаляется, когда приняты все
+	rest := jobs[accepted:]
+	if len(rest) > 0 {
+		err = savePendingJobs(d.pendingFile, rest)
+	} else {
+		err = removePendingJobs(d.pendingFile)
+	}
+	if err != nil {
+		logger.Errorf("Не удалось обновить файл незавершенных задач: %v", err)
+	}
+}
+
+// Dispatch ставит обновление в очередь на обработку
+func (d *Dispatcher) Dispatch(update tgbotapi.Update) {
+	// Обрабатываем только сообщения
+	if update.Message == nil {
+		return
+	}
+
+	if d.bot == nil {
+		logger.Debug("Bot не доступен, пропускаем обработку сообщения")
+		return
+	}
+
+	// Telegram повторяет доставку, если не дождался ответа
+	if !d.updates.Begin(update.UpdateID) {
+		logger.Infof("Обновление %d уже обработано или обрабатывается, пропускаем", update.UpdateID)
+		return
+	}
+
+	// Отмена выполняется сразу: в очереди она ждала бы задачу, которую отменяет
+	if update.Message.IsCommand() && update.Message.Command() == "cancel" {
+		d.cancel(update.Message)
+		d.updates.Done(update.UpdateID)
+		return
+	}
+
+	if d.limited(update.Message) {
+		d.updates.Done(update.UpdateID)
+		return
+	}
+
+	d.enqueue(&Job{UpdateID: update.UpdateID, Message: update.Message})
+}
+
+// limited учитывает ссылки сообщения в ограничениях пользователя и отвечает,
+// если они превышены
+func (d *Dispatcher) limited(message *tgbotapi.Message) bool {
+	// Команды и сообщения без ссылок не загружают страницы и не расходуют квоту
+	if message.IsCommand() {
+		return false
+	}
+
+	// Обрабатываются не больше batchMaxURLs ссылок сообщения
+	count := min(len(ExtractURLs(message)), batchMaxURLs)
+	if count == 0 {
+		return false
+	}
+
+	err := d.limiter.Allow(messageUserID(message), count)
+	if err == nil {
+		return false
+	}
+
+	var limitErr *UserLimitError
+	if !errors.As(err, &limitErr) {
+		return false
+	}
+
+	logger.Infof("Ограничение для пользователя %d: %v", messageUserID(message), err)
+
+	locale := GetLocale(message)
+	msg := tgbotapi.NewMessage(message.Chat.ID, userLimitMessage(limitErr, locale))
+	go d.bot.Send(msg)
+	return true
+}
+
+// cancel отменяет задачи пользователя в очереди и в обработке
+func (d *Dispatcher) cancel(message *tgbotapi.Message) {
+	userID := messageUserID(message)
+	locale := GetLocale(message)
+
+	text := locale.NothingToCancelMsg
+	if count := d.jobs.cancel(userID); count > 0 {
+		logger.Infof("Пользователь %d отменил задачи: %d", userID, count)
+		text = locale.CancelledMsg
+	}
+
+	go d.bot.Send(tgbotapi.NewMessage(message.Chat.ID, text))
+}
+
+// messageUserID возвращает идентификатор отправителя или чата
+func messageUserID(message *tgbotapi.Message) int64 {
+	if message.From != nil {
+		return message.From.ID
+	}
+	return message.Chat.ID
+}
+
+// enqueue ставит задачу в очередь и сообщает пользователю о перегрузке
+func (d *Dispatcher) enqueue(job *Job) {
+	userID := messageUserID(job.Message)
+	d.jobs.add(d.ctx, userID, job)
+
+	err := d.pool.Submit(job)
+	if err == nil {
+		return
+	}
+	d.jobs.remove(userID, job)
+
+	// Пользователь получит ответ о перегрузке, повтор обрабатывать не нужно
+	d.updates.Done(job.UpdateID)
+
+	if errors.Is(err, ErrQueueFull) {
+		logger.Warnf("Очередь заполнена, обновление %d отклонено", job.UpdateID)
+	} else {
+		logger.Errorf("Не удалось поставить обновление %d в очередь: %v", job.UpdateID, err)
+	}
+
+	locale := GetLocale(job.Message)
+	msg := tgbotapi.NewMessage(job.Message.Chat.ID, locale.ServerOverloadMessage)
+	go d.bot.Send(msg)
+}
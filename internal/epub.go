@@ -0,0 +1,371 @@
+package internal
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"encoding/xml"
+	"fmt"
+	"mime"
+	"net/url"
+	"path"
+	"strings"
+	"text/template"
+	"time"
+
+	"golang.org/x/net/html"
+)
+
+// epubFormat упаковывает контент в электронную книгу EPUB 3
+// с обложкой из заголовка, автора и даты
+type epubFormat struct{}
+
+func (epubFormat) Name() string      { return "epub" }
+func (epubFormat) Extension() string { return ".epub" }
+
+// epubBook содержит данные для шаблонов EPUB
+type epubBook struct {
+	ID         string
+	Title      string
+	Author     string
+	Date       string // дата для обложки в исходном виде
+	ISODate    string // дата в формате W3CDTF для dc:date
+	Language   string
+	Source     string
+	Modified   string
+	Meta       []metadataItem
+	CoverLines []string
+	Body       string
+	Footer     string
+
+	RemoteImages []epubRemoteImage
+}
+
+// epubRemoteImage - внешнее изображение статьи, объявленное в манифесте
+type epubRemoteImage struct {
+	ID        string
+	Href      string
+	MediaType string
+}
+
+func (epubFormat) Render(ctx context.Context, content *Content, originalURL string, locale Locale) ([]byte, error) {
+	body, err := renderContentHTML(ctx, content)
+	if err != nil {
+		return nil, err
+	}
+
+	xhtmlBody, err := toXHTML(body)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка подготовки XHTML: %w", err)
+	}
+
+	book := epubBook{
+		ID:         epubIdentifier(originalURL),
+		Title:      content.Title,
+		Author:     content.Author,
+		Date:       content.Date,
+		ISODate:    epubDate(content.Date),
+		Language:   epubLanguage(content.Language),
+		Source:     originalURL,
+		Modified:   time.Now().UTC().Format("2006-01-02T15:04:05Z"),
+		Meta:       contentMetadata(content, originalURL, locale),
+		CoverLines: wrapText(content.Title, 22),
+		Body:       xhtmlBody,
+		Footer:     strings.Trim(locale.FooterText, "*"),
+
+		RemoteImages: epubRemoteImages(xhtmlBody),
+	}
+
+	files := []struct {
+		name     string
+		template *template.Template
+	}{
+		{"META-INF/container.xml", epubContainerTemplate},
+		{"OEBPS/content.opf", epubPackageTemplate},
+		{"OEBPS/nav.xhtml", epubNavTemplate},
+		{"OEBPS/cover.svg", epubCoverImageTemplate},
+		{"OEBPS/cover.xhtml", epubCoverTemplate},
+		{"OEBPS/content.xhtml", epubContentTemplate},
+		{"OEBPS/style.css", epubStyleTemplate},
+	}
+
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+
+	// mimetype должен быть первым файлом архива и храниться без сжатия
+	w, err := archive.CreateHeader(&zip.FileHeader{Name: "mimetype", Method: zip.Store})
+	if err != nil {
+		return nil, fmt.Errorf("ошибка создания EPUB: %w", err)
+	}
+	if _, err := w.Write([]byte("application/epub+zip")); err != nil {
+		return nil, fmt.Errorf("ошибка создания EPUB: %w", err)
+	}
+
+	for _, file := range files {
+		w, err := archive.CreateHeader(&zip.FileHeader{Name: file.name, Method: zip.Deflate})
+		if err != nil {
+			return nil, fmt.Errorf("ошибка создания EPUB: %w", err)
+		}
+		if err := file.template.Execute(w, book); err != nil {
+			return nil, fmt.Errorf("ошибка создания %s: %w", file.name, err)
+		}
+	}
+
+	if err := archive.Close(); err != nil {
+		return nil, fmt.Errorf("ошибка создания EPUB: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// epubIdentifier создает постоянный идентификатор книги из URL (UUID версии 5)
+func epubIdentifier(originalURL string) string {
+	sum := sha1.Sum([]byte(originalURL))
+	sum[6] = (sum[6] & 0x0f) | 0x50
+	sum[8] = (sum[8] & 0x3f) | 0x80
+	return fmt.Sprintf("urn:uuid:%x-%x-%x-%x-%x", sum[0:4], sum[4:6], sum[6:8], sum[8:10], sum[10:16])
+}
+
+// epubDate приводит дату публикации к формату W3CDTF, если ее удалось разобрать
+func epubDate(date string) string {
+	if t, _, ok := normalizeDate(date); ok {
+		return t.Format("2006-01-02")
+	}
+	return ""
+}
+
+// epubLanguage возвращает язык книги для dc:language, "und" - язык неизвестен
+func epubLanguage(lang string) string {
+	if lang == "" {
+		return "und"
+	}
+	return lang
+}
+
+// wrapText разбивает текст на строки не длиннее width символов для обложки
+func wrapText(text string, width int) []string {
+	var lines []string
+	var line []rune
+
+	for _, word := range strings.Fields(text) {
+		runes := []rune(word)
+		if len(line) > 0 && len(line)+1+len(runes) > width {
+			lines = append(lines, string(line))
+			line = nil
+		}
+		if len(line) > 0 {
+			line = append(line, ' ')
+		}
+		line = append(line, runes...)
+	}
+	if len(line) > 0 {
+		lines = append(lines, string(line))
+	}
+
+	// Слишком длинный заголовок сокращаем, чтобы он поместился на обложке
+	if len(lines) > 6 {
+		lines = append(lines[:5], lines[5]+"…")
+	}
+	return lines
+}
+
+// epubRemoteImages находит изображения с внешними адресами. EPUB 3 требует
+// объявить их в манифесте, а content.xhtml отметить свойством remote-resources
+func epubRemoteImages(body string) []epubRemoteImage {
+	nodes, err := parseHTMLFragment(body)
+	if err != nil {
+		return nil
+	}
+
+	var images []epubRemoteImage
+	seen := make(map[string]bool)
+
+	var walk func(node *html.Node)
+	walk = func(node *html.Node) {
+		if node.Type == html.ElementNode && node.Data == "img" {
+			for _, attr := range node.Attr {
+				if attr.Key != "src" || !isHTTPURL(attr.Val) || seen[attr.Val] {
+					continue
+				}
+				seen[attr.Val] = true
+				images = append(images, epubRemoteImage{
+					ID:        fmt.Sprintf("remote-image-%d", len(images)+1),
+					Href:      attr.Val,
+					MediaType: remoteImageMediaType(attr.Val),
+				})
+			}
+		}
+		for child := node.FirstChild; child != nil; child = child.NextSibling {
+			walk(child)
+		}
+	}
+	for _, node := range nodes {
+		walk(node)
+	}
+
+	return images
+}
+
+// remoteImageMediaType определяет тип внешнего изображения по расширению,
+// без расширения считаем изображение JPEG
+func remoteImageMediaType(src string) string {
+	if u, err := url.Parse(src); err == nil {
+		if mediaType := mime.TypeByExtension(strings.ToLower(path.Ext(u.Path))); strings.HasPrefix(mediaType, "image/") {
+			mediaType, _, _ = strings.Cut(mediaType, ";")
+			return mediaType
+		}
+	}
+	return "image/jpeg"
+}
+
+// toXHTML преобразует фрагмент HTML в корректный XML для EPUB
+func toXHTML(fragment string) (string, error) {
+	nodes, err := parseHTMLFragment(fragment)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	for _, node := range nodes {
+		// Комментарии вида <!-- raw HTML omitted --> в книге не нужны
+		if node.Type == html.CommentNode {
+			continue
+		}
+		if err := html.Render(&buf, node); err != nil {
+			return "", err
+		}
+	}
+	return buf.String(), nil
+}
+
+// xmlEscape экранирует текст для вставки в XML
+func xmlEscape(text string) string {
+	var buf bytes.Buffer
+	xml.EscapeText(&buf, []byte(text))
+	return buf.String()
+}
+
+// newEPUBTemplate создает шаблон файла EPUB
+func newEPUBTemplate(name, text string) *template.Template {
+	return template.Must(template.New(name).Funcs(template.FuncMap{"xml": xmlEscape}).Parse(text))
+}
+
+var epubContainerTemplate = newEPUBTemplate("container", `<?xml version="1.0" encoding="UTF-8"?>
+<container version="1.0" xmlns="urn:oasis:names:tc:opendocument:xmlns:container">
+  <rootfiles>
+    <rootfile full-path="OEBPS/content.opf" media-type="application/oebps-package+xml"/>
+  </rootfiles>
+</container>
+`)
+
+var epubPackageTemplate = newEPUBTemplate("package", `<?xml version="1.0" encoding="UTF-8"?>
+<package xmlns="http://www.idpf.org/2007/opf" version="3.0" unique-identifier="book-id">
+  <metadata xmlns:dc="http://purl.org/dc/elements/1.1/">
+    <dc:identifier id="book-id">{{.ID}}</dc:identifier>
+    <dc:title>{{xml .Title}}</dc:title>
+{{- if .Author}}
+    <dc:creator>{{xml .Author}}</dc:creator>
+{{- end}}
+{{- if .ISODate}}
+    <dc:date>{{.ISODate}}</dc:date>
+{{- end}}
+    <dc:language>{{.Language}}</dc:language>
+    <dc:source>{{xml .Source}}</dc:source>
+    <meta property="dcterms:modified">{{.Modified}}</meta>
+    <meta name="cover" content="cover-image"/>
+  </metadata>
+  <manifest>
+    <item id="nav" href="nav.xhtml" media-type="application/xhtml+xml" properties="nav"/>
+    <item id="cover-image" href="cover.svg" media-type="image/svg+xml" properties="cover-image"/>
+    <item id="cover" href="cover.xhtml" media-type="application/xhtml+xml"/>
+    <item id="content" href="content.xhtml" media-type="application/xhtml+xml"{{if .RemoteImages}} properties="remote-resources"{{end}}/>
+{{- range .RemoteImages}}
+    <item id="{{.ID}}" href="{{xml .Href}}" media-type="{{.MediaType}}"/>
+{{- end}}
+    <item id="style" href="style.css" media-type="text/css"/>
+  </manifest>
+  <spine>
+    <itemref idref="cover" linear="no"/>
+    <itemref idref="content"/>
+  </spine>
+</package>
+`)
+
+var epubNavTemplate = newEPUBTemplate("nav", `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE html>
+<html xmlns="http://www.w3.org/1999/xhtml" xmlns:epub="http://www.idpf.org/2007/ops">
+<head><title>{{xml .Title}}</title></head>
+<body>
+  <nav epub:type="toc">
+    <ol>
+      <li><a href="content.xhtml">{{xml .Title}}</a></li>
+    </ol>
+  </nav>
+</body>
+</html>
+`)
+
+var epubCoverImageTemplate = newEPUBTemplate("cover-image", `<?xml version="1.0" encoding="UTF-8"?>
+<svg xmlns="http://www.w3.org/2000/svg" width="600" height="800" viewBox="0 0 600 800">
+  <rect width="600" height="800" fill="#f4efe6"/>
+  <rect x="30" y="30" width="540" height="740" fill="none" stroke="#3b3b3b" stroke-width="2"/>
+  <text x="300" y="240" font-family="Georgia, serif" font-size="40" fill="#222" text-anchor="middle">
+{{- range $i, $line := .CoverLines}}
+    <tspan x="300" dy="{{if $i}}52{{else}}0{{end}}">{{xml $line}}</tspan>
+{{- end}}
+  </text>
+{{- if .Author}}
+  <text x="300" y="640" font-family="Georgia, serif" font-size="28" fill="#444" text-anchor="middle">{{xml .Author}}</text>
+{{- end}}
+{{- if .Date}}
+  <text x="300" y="690" font-family="Georgia, serif" font-size="22" fill="#666" text-anchor="middle">{{xml .Date}}</text>
+{{- end}}
+</svg>
+`)
+
+var epubCoverTemplate = newEPUBTemplate("cover", `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE html>
+<html xmlns="http://www.w3.org/1999/xhtml">
+<head>
+  <title>{{xml .Title}}</title>
+  <style>body { margin: 0; text-align: center; } img { max-width: 100%; max-height: 100%; }</style>
+</head>
+<body>
+  <img src="cover.svg" alt="{{xml .Title}}"/>
+</body>
+</html>
+`)
+
+var epubContentTemplate = newEPUBTemplate("content", `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE html>
+<html xmlns="http://www.w3.org/1999/xhtml">
+<head>
+  <title>{{xml .Title}}</title>
+  <link rel="stylesheet" type="text/css" href="style.css"/>
+</head>
+<body>
+  <h1>{{xml .Title}}</h1>
+  <ul class="meta">
+{{- range .Meta}}
+    <li><strong>{{xml .Label}}</strong> {{if .Link}}<a href="{{xml .Link}}">{{xml .Value}}</a>{{else}}{{xml .Value}}{{end}}</li>
+{{- end}}
+  </ul>
+  <hr/>
+{{.Body}}
+  <hr/>
+  <p class="footer">{{xml .Footer}}</p>
+</body>
+</html>
+`)
+
+var epubStyleTemplate = newEPUBTemplate("style", `body { font-family: serif; line-height: 1.5; }
+h1, h2, h3, h4 { line-height: 1.25; }
+img { max-width: 100%; }
+pre { white-space: pre-wrap; font-size: 0.85em; }
+blockquote { margin-left: 1em; padding-left: 0.5em; border-left: 3px solid #999; }
+table { border-collapse: collapse; }
+th, td { padding: 0.2em 0.4em; border: 1px solid #999; }
+.meta { padding: 0; list-style: none; font-size: 0.9em; }
+.footer { font-size: 0.8em; font-style: italic; }
+`)
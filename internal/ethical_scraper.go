@@ -1,11 +1,10 @@
 package internal
 
 import (
-	"bufio"
 	"context"
 	"crypto/tls"
+	"errors"
 	"fmt"
-	"io"
 	"net/http"
 	"net/url"
 	"strings"
@@ -18,33 +17,17 @@ import (
 // EthicalScraper представляет этичный веб-скрапер
 type EthicalScraper struct {
 	client    *http.Client
+	network   *NetworkPolicy
 	userAgent string
-	contact   string
-	rateLimit *RateLimiter
-	cache     *ResponseCache
-	whitelist *DomainWhitelist
-}
-
-// RateLimiter управляет частотой запросов
-type RateLimiter struct {
-	requests map[string]time.Time
-	interval time.Duration
-}
-
-// ResponseCache кэширует ответы
-type ResponseCache struct {
-	cache map[string]*CachedResponse
-	ttl   time.Duration
-}
-
-// CachedResponse представляет кэшированный ответ
-type CachedResponse struct {
-	Content      []byte
-	Headers      http.Header
-	StatusCode   int
-	ExpiresAt    time.Time
-	ETag         string
-	LastModified string
+	// maxBodySize ограничивает размер ответа в байтах, 0 - без ограничения
+	maxBodySize int64
+	// contentTypes - разрешенные типы содержимого, пусто - любые
+	contentTypes []string
+	contact      string
+	rateLimit    *RateLimiter
+	cache        Cache
+	whitelist    *DomainWhitelist
+	robots       *RobotsCache
 }
 
 // DomainWhitelist управляет белым списком доменов
@@ -58,15 +41,21 @@ type ScrapingResult struct {
 	Content     []byte
 	StatusCode  int
 	Headers     http.Header
+	FinalURL    string
 	IsBlocked   bool
+	BlockKind   BlockKind
 	BlockReason string
 	IsCached    bool
 }
 
 // NewEthicalScraper создает новый этичный скрапер
 func NewEthicalScraper(config *Config) *EthicalScraper {
+	// Подключаемся только к публичным адресам и разрешенным портам
+	network := NewNetworkPolicy(config.AllowPrivateNetworks, config.AllowedPorts)
+
 	// Создаем HTTP клиент с правильными настройками
 	transport := &http.Transport{
+		DialContext: network.DialContext,
 		TLSClientConfig: &tls.Config{
 			MinVersion: tls.VersionTLS12,
 		},
@@ -81,8 +70,10 @@ func NewEthicalScraper(config *Config) *EthicalScraper {
 	}
 
 	client := &http.Client{
-		Transport: transport,
-		Timeout:   time.Duration(config.HTTPTimeout) * time.Second,
+		// Accept-Encoding задается вручную, поэтому ответы распаковываем сами
+		Transport:     newDecodingTransport(transport),
+		Timeout:       time.Duration(config.HTTPTimeout) * time.Second,
+		CheckRedirect: network.CheckRedirect,
 	}
 
 	// Настройка User-Agent и контактной информации
@@ -107,18 +98,60 @@ func NewEthicalScraper(config *Config) *EthicalScraper {
 	}
 
 	return &EthicalScraper{
-		client:    client,
-		userAgent: userAgent,
-		contact:   contact,
-		rateLimit: &RateLimiter{
-			requests: make(map[string]time.Time),
-			interval: time.Duration(config.RateLimitInterval) * time.Second,
-		},
-		cache: &ResponseCache{
-			cache: make(map[string]*CachedResponse),
-			ttl:   time.Duration(config.CacheTTL) * time.Hour,
-		},
-		whitelist: whitelist,
+		client:       client,
+		network:      network,
+		userAgent:    userAgent,
+		maxBodySize:  int64(config.MaxBodySizeMB) * 1024 * 1024,
+		contentTypes: config.AllowedContentTypes,
+		contact:      contact,
+		rateLimit:    newScraperRateLimiter(config),
+		cache:        NewCache(config),
+		whitelist:    whitelist,
+		robots:       NewRobotsCache(client, userAgent),
+	}
+}
+
+// newScraperRateLimiter создает лимитер запросов из конфигурации
+func newScraperRateLimiter(config *Config) *RateLimiter {
+	policy := RatePolicy{
+		Interval: time.Duration(config.RateLimitInterval) * time.Second,
+		Burst:    config.RateLimitBurst,
+	}
+
+	var global RatePolicy
+	if config.RateLimitGlobal > 0 {
+		global = RatePolicy{
+			Interval: time.Second / time.Duration(config.RateLimitGlobal),
+			Burst:    config.RateLimitGlobal,
+		}
+	}
+
+	limiter := NewRateLimiter(policy, global)
+
+	if config.RateLimitDomains != "" {
+		policies, err := ParseDomainRatePolicies(config.RateLimitDomains)
+		if err != nil {
+			logrus.Warnf("Ошибка разбора RATE_LIMIT_DOMAINS: %v", err)
+		}
+		for domain, domainPolicy := range policies {
+			limiter.SetDomainPolicy(domain, domainPolicy)
+		}
+	}
+
+	return limiter
+}
+
+// blockedResult превращает отказ сетевой политики в результат скрапинга,
+// для остальных ошибок возвращает nil
+func blockedResult(err error) *ScrapingResult {
+	var blocked *BlockedError
+	if !errors.As(err, &blocked) {
+		return nil
+	}
+	return &ScrapingResult{
+		IsBlocked:   true,
+		BlockKind:   blocked.Kind,
+		BlockReason: blocked.Reason,
 	}
 }
 
@@ -129,26 +162,36 @@ func (s *EthicalScraper) ScrapeURL(ctx context.Context, pageURL string) (*Scrapi
 		return nil, fmt.Errorf("неверный URL: %w", err)
 	}
 
+	// Ссылки на внутренние адреса и неразрешенные порты не загружаем
+	if err := s.network.CheckURL(parsedURL); err != nil {
+		return blockedResult(err), nil
+	}
+
 	domain := parsedURL.Hostname()
 
 	// Проверяем белый список
 	if !s.whitelist.IsAllowed(domain) {
 		return &ScrapingResult{
 			IsBlocked:   true,
+			BlockKind:   BlockNotWhitelisted,
 			BlockReason: "Домен не в белом списке",
 		}, nil
 	}
 
 	// Проверяем robots.txt
-	if err := s.checkRobotsTxt(parsedURL); err != nil {
-		return &ScrapingResult{
-			IsBlocked:   true,
-			BlockReason: fmt.Sprintf("robots.txt запрещает доступ: %v", err),
-		}, nil
+	if err := s.checkRobotsTxt(ctx, parsedURL); err != nil {
+		if blocked := blockedResult(err); blocked != nil {
+			return blocked, nil
+		}
+		return nil, err
 	}
 
+	// Варианты одной страницы хранятся в кэше под одним ключом
+	cacheKey := CacheKey(pageURL)
+
 	// Проверяем кэш
-	if cached := s.cache.Get(pageURL); cached != nil {
+	if cached := s.cachedResponse(cacheKey, s.cache.Get(cacheKey)); cached != nil {
+		cacheRequests.WithLabelValues("hit").Inc()
 		return &ScrapingResult{
 			Content:    cached.Content,
 			Headers:    cached.Headers,
@@ -157,8 +200,11 @@ func (s *EthicalScraper) ScrapeURL(ctx context.Context, pageURL string) (*Scrapi
 		}, nil
 	}
 
+	// Устаревший ответ можно подтвердить условным запросом
+	stale := s.cachedResponse(cacheKey, s.cache.GetStale(cacheKey))
+
 	// Rate limiting
-	if err := s.rateLimit.Wait(domain); err != nil {
+	if err := s.rateLimit.Wait(ctx, domain); err != nil {
 		return nil, fmt.Errorf("rate limit: %w", err)
 	}
 
@@ -169,15 +215,27 @@ func (s *EthicalScraper) ScrapeURL(ctx context.Context, pageURL string) (*Scrapi
 	}
 
 	// Устанавливаем этичные заголовки
-	s.setEthicalHeaders(req, parsedURL)
+	s.setEthicalHeaders(req, parsedURL, stale)
 
 	// Выполняем запрос с retry логикой
 	var resp *http.Response
 	for attempt := 0; attempt < 3; attempt++ {
 		resp, err = s.client.Do(req)
 		if err != nil {
+			// Запрет сетевой политики не исчезнет при повторе
+			if blocked := blockedResult(err); blocked != nil {
+				return blocked, nil
+			}
+			// Отмененный запрос не повторяем
+			if ctx.Err() != nil {
+				return nil, fmt.Errorf("ошибка запроса: %w", err)
+			}
 			if attempt < 2 {
-				time.Sleep(time.Duration(attempt+1) * time.Second)
+				select {
+				case <-time.After(time.Duration(attempt+1) * time.Second):
+				case <-ctx.Done():
+					return nil, fmt.Errorf("ошибка запроса: %w", ctx.Err())
+				}
 				continue
 			}
 			return nil, fmt.Errorf("ошибка запроса: %w", err)
@@ -186,10 +244,18 @@ func (s *EthicalScraper) ScrapeURL(ctx context.Context, pageURL string) (*Scrapi
 	}
 	defer resp.Body.Close()
 
+	// Ответ 304 подтверждает устаревшую копию - это тоже попадание в кэш
+	if resp.StatusCode == http.StatusNotModified && stale != nil {
+		cacheRequests.WithLabelValues("revalidated").Inc()
+	} else {
+		cacheRequests.WithLabelValues("miss").Inc()
+	}
+
 	// Проверяем статус код
 	if resp.StatusCode == 403 || resp.StatusCode == 451 {
 		return &ScrapingResult{
 			IsBlocked:   true,
+			BlockKind:   BlockForbidden,
 			BlockReason: fmt.Sprintf("Доступ запрещен (статус %d)", resp.StatusCode),
 		}, nil
 	}
@@ -197,143 +263,161 @@ func (s *EthicalScraper) ScrapeURL(ctx context.Context, pageURL string) (*Scrapi
 	if resp.StatusCode == 429 {
 		return &ScrapingResult{
 			IsBlocked:   true,
+			BlockKind:   BlockTooManyRequests,
 			BlockReason: "Слишком много запросов (429)",
 		}, nil
 	}
 
+	// Сайт подтвердил, что кэшированная копия актуальна
+	if resp.StatusCode == http.StatusNotModified && stale != nil {
+		return s.revalidated(cacheKey, stale, resp), nil
+	}
+
 	if resp.StatusCode != 200 {
 		return nil, fmt.Errorf("неожиданный статус код: %d", resp.StatusCode)
 	}
 
-	// Читаем контент
-	content, err := io.ReadAll(resp.Body)
+	// Изображения статьи загружаются со своими ограничениями
+	limits := pageLimitsFromContext(ctx, PageLimits{MaxBodySize: s.maxBodySize, ContentTypes: s.contentTypes})
+
+	// Файлы неподдерживаемых типов не скачиваем
+	if err := checkHeaderContentType(resp.Header, limits.ContentTypes); err != nil {
+		return nil, err
+	}
+
+	// Читаем контент, но не больше допустимого размера
+	content, err := readLimitedBody(resp, limits.MaxBodySize)
 	if err != nil {
 		return nil, fmt.Errorf("ошибка чтения ответа: %w", err)
 	}
 
-	// Кэшируем результат
-	s.cache.Set(pageURL, &CachedResponse{
-		Content:      content,
-		Headers:      resp.Header,
-		StatusCode:   resp.StatusCode,
-		ExpiresAt:    time.Now().Add(s.cache.ttl),
-		ETag:         resp.Header.Get("ETag"),
-		LastModified: resp.Header.Get("Last-Modified"),
-	})
+	// Страницы в windows-1251, KOI8-R, Shift_JIS и т.д. перекодируем в UTF-8
+	content, headers := normalizeCharset(content, resp.Header)
+
+	// Тип ответа без заголовка известен только по телу, отклоненный ответ не кэшируем
+	if err := checkContentType(&FetchResult{Body: content, Headers: headers}, limits.ContentTypes); err != nil {
+		return nil, err
+	}
+
+	// Кэшируем результат с учетом Cache-Control и Expires
+	policy := responseCachePolicy(resp.Header, time.Now(), s.cache.TTL())
+	if policy.Store {
+		s.cache.Set(cacheKey, &CachedResponse{
+			Content:      content,
+			Headers:      headers,
+			StatusCode:   resp.StatusCode,
+			ExpiresAt:    policy.ExpiresAt,
+			ETag:         resp.Header.Get("ETag"),
+			LastModified: resp.Header.Get("Last-Modified"),
+		})
+	} else {
+		s.cache.Delete(cacheKey)
+	}
 
 	return &ScrapingResult{
 		Content:    content,
-		Headers:    resp.Header,
+		Headers:    headers,
 		StatusCode: resp.StatusCode,
+		FinalURL:   resp.Request.URL.String(),
 		IsCached:   false,
 	}, nil
 }
 
+// cachedResponse распаковывает ответ из кэша. Запись, которую не удалось
+// распаковать в пределах допустимого размера, считается промахом и удаляется
+func (s *EthicalScraper) cachedResponse(cacheKey string, cached *CachedResponse) *CachedResponse {
+	if cached == nil {
+		return nil
+	}
+
+	decoded := decodeCachedResponse(cached, s.maxBodySize)
+	if decoded == nil {
+		s.cache.Delete(cacheKey)
+	}
+	return decoded
+}
+
+// revalidated продлевает кэшированный ответ после 304 Not Modified
+func (s *EthicalScraper) revalidated(cacheKey string, stale *CachedResponse, resp *http.Response) *ScrapingResult {
+	refreshed := *stale
+
+	// 304 может обновлять валидаторы и заголовки кэширования
+	if etag := resp.Header.Get("ETag"); etag != "" {
+		refreshed.ETag = etag
+	}
+	if lastModified := resp.Header.Get("Last-Modified"); lastModified != "" {
+		refreshed.LastModified = lastModified
+	}
+
+	policy := responseCachePolicy(resp.Header, time.Now(), s.cache.TTL())
+	if policy.Store {
+		refreshed.ExpiresAt = policy.ExpiresAt
+		s.cache.Set(cacheKey, &refreshed)
+	} else {
+		s.cache.Delete(cacheKey)
+	}
+
+	return &ScrapingResult{
+		Content:    refreshed.Content,
+		Headers:    refreshed.Headers,
+		StatusCode: refreshed.StatusCode,
+		FinalURL:   resp.Request.URL.String(),
+		IsCached:   true,
+	}
+}
+
 // setEthicalHeaders устанавливает этичные заголовки
-func (s *EthicalScraper) setEthicalHeaders(req *http.Request, parsedURL *url.URL) {
+func (s *EthicalScraper) setEthicalHeaders(req *http.Request, parsedURL *url.URL, stale *CachedResponse) {
 	req.Header.Set("User-Agent", s.userAgent)
 	req.Header.Set("Accept", "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8")
 	req.Header.Set("Accept-Language", "en-US,en;q=0.5")
-	req.Header.Set("Accept-Encoding", "gzip, deflate")
+	req.Header.Set("Accept-Encoding", acceptEncoding)
 	req.Header.Set("DNT", "1")
 	req.Header.Set("Connection", "keep-alive")
 	req.Header.Set("Upgrade-Insecure-Requests", "1")
 
+	if referer := refererFromContext(req.Context()); referer != "" {
+		req.Header.Set("Referer", referer)
+	}
+
 	// Добавляем контактную информацию
 	req.Header.Set("From", s.contact)
 	req.Header.Set("X-Requested-With", "TGNIP-Bot")
 
 	// Условные запросы для экономии трафика
-	if cached := s.cache.Get(req.URL.String()); cached != nil {
-		if cached.ETag != "" {
-			req.Header.Set("If-None-Match", cached.ETag)
+	if stale != nil {
+		if stale.ETag != "" {
+			req.Header.Set("If-None-Match", stale.ETag)
 		}
-		if cached.LastModified != "" {
-			req.Header.Set("If-Modified-Since", cached.LastModified)
+		if stale.LastModified != "" {
+			req.Header.Set("If-Modified-Since", stale.LastModified)
 		}
 	}
 }
 
-// checkRobotsTxt проверяет robots.txt
-func (s *EthicalScraper) checkRobotsTxt(parsedURL *url.URL) error {
-	robotsURL := fmt.Sprintf("%s://%s/robots.txt", parsedURL.Scheme, parsedURL.Host)
-
-	req, err := http.NewRequest("GET", robotsURL, nil)
-	if err != nil {
-		return fmt.Errorf("ошибка создания запроса robots.txt: %w", err)
-	}
-
-	req.Header.Set("User-Agent", s.userAgent)
-
-	resp, err := s.client.Do(req)
+// checkRobotsTxt проверяет robots.txt и применяет Crawl-delay сайта.
+// Запрет возвращается как *BlockedError
+func (s *EthicalScraper) checkRobotsTxt(ctx context.Context, parsedURL *url.URL) error {
+	rules, err := s.robots.Rules(ctx, parsedURL)
 	if err != nil {
-		// Если robots.txt недоступен, считаем что доступ разрешен
-		logrus.Warnf("robots.txt недоступен для %s: %v", parsedURL.Host, err)
-		return nil
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode == 404 {
-		// robots.txt не найден, доступ разрешен
-		return nil
+		return err
 	}
 
-	if resp.StatusCode != 200 {
-		// Если robots.txt недоступен, считаем что доступ разрешен
-		logrus.Warnf("robots.txt недоступен для %s (статус %d)", parsedURL.Host, resp.StatusCode)
-		return nil
-	}
-
-	// Парсим robots.txt
-	scanner := bufio.NewScanner(resp.Body)
-	currentUserAgent := ""
-
-	for scanner.Scan() {
-		line := strings.TrimSpace(scanner.Text())
-
-		if strings.HasPrefix(line, "User-agent:") {
-			currentUserAgent = strings.TrimSpace(strings.TrimPrefix(line, "User-agent:"))
-		} else if strings.HasPrefix(line, "Disallow:") && (currentUserAgent == "*" || currentUserAgent == "TGNIP-Bot") {
-			disallowPath := strings.TrimSpace(strings.TrimPrefix(line, "Disallow:"))
-			if strings.HasPrefix(parsedURL.Path, disallowPath) {
-				return fmt.Errorf("путь %s запрещен в robots.txt", parsedURL.Path)
-			}
-		}
+	if delay := rules.CrawlDelay(); delay > 0 {
+		s.rateLimit.SetDomainInterval(parsedURL.Hostname(), delay)
 	}
 
-	return nil
-}
-
-// Wait ожидает соблюдения rate limit
-func (rl *RateLimiter) Wait(domain string) error {
-	if lastRequest, exists := rl.requests[domain]; exists {
-		timeSinceLast := time.Since(lastRequest)
-		if timeSinceLast < rl.interval {
-			sleepTime := rl.interval - timeSinceLast
-			time.Sleep(sleepTime)
+	path := robotsPath(parsedURL)
+	if !rules.IsAllowed(path) {
+		return &BlockedError{
+			Kind:   BlockRobotsTxt,
+			Reason: fmt.Sprintf("robots.txt запрещает доступ: путь %s запрещен в robots.txt", path),
 		}
 	}
 
-	rl.requests[domain] = time.Now()
-	return nil
-}
-
-// Get получает кэшированный ответ
-func (rc *ResponseCache) Get(key string) *CachedResponse {
-	if cached, exists := rc.cache[key]; exists && time.Now().Before(cached.ExpiresAt) {
-		return cached
-	}
-
-	// Удаляем устаревший кэш
-	delete(rc.cache, key)
 	return nil
 }
 
-// Set сохраняет ответ в кэш
-func (rc *ResponseCache) Set(key string, response *CachedResponse) {
-	rc.cache[key] = response
-}
-
 // IsAllowed проверяет, разрешен ли домен
 func (dw *DomainWhitelist) IsAllowed(domain string) bool {
 	// Проверяем точное совпадение
@@ -10,12 +10,13 @@ import (
 
 func TestNewEthicalScraper(t *testing.T) {
 	config := &Config{
-		HTTPTimeout:       30,
-		RateLimitInterval: 2,
-		CacheTTL:          1,
-		UserAgent:         "Test-Bot/1.0",
-		ContactEmail:      "test@example.com",
-		WhitelistDomains:  "example.com,test.com",
+		HTTPTimeout:          30,
+		AllowPrivateNetworks: true, // httptest сервер слушает 127.0.0.1
+		RateLimitInterval:    2,
+		CacheTTL:             1,
+		UserAgent:            "Test-Bot/1.0",
+		ContactEmail:         "test@example.com",
+		WhitelistDomains:     "example.com,test.com",
 	}
 
 	scraper := NewEthicalScraper(config)
@@ -32,12 +33,12 @@ func TestNewEthicalScraper(t *testing.T) {
 		t.Errorf("Ожидался контакт 'test@example.com', получен '%s'", scraper.contact)
 	}
 
-	if scraper.rateLimit.interval != 2*time.Second {
-		t.Errorf("Ожидался интервал rate limit 2 секунды, получен %v", scraper.rateLimit.interval)
+	if scraper.rateLimit.policy.Interval != 2*time.Second {
+		t.Errorf("Ожидался интервал rate limit 2 секунды, получен %v", scraper.rateLimit.policy.Interval)
 	}
 
-	if scraper.cache.ttl != 1*time.Hour {
-		t.Errorf("Ожидался TTL кэша 1 час, получен %v", scraper.cache.ttl)
+	if scraper.cache.TTL() != 1*time.Hour {
+		t.Errorf("Ожидался TTL кэша 1 час, получен %v", scraper.cache.TTL())
 	}
 }
 
@@ -73,22 +74,19 @@ func TestDomainWhitelist(t *testing.T) {
 }
 
 func TestRateLimiter(t *testing.T) {
-	rateLimiter := &RateLimiter{
-		requests: make(map[string]time.Time),
-		interval: 100 * time.Millisecond,
-	}
+	rateLimiter := NewRateLimiter(RatePolicy{Interval: 100 * time.Millisecond, Burst: 1}, RatePolicy{})
 
 	domain := "test.com"
 
 	// Первый запрос должен пройти сразу
 	start := time.Now()
-	err := rateLimiter.Wait(domain)
+	err := rateLimiter.Wait(context.Background(), domain)
 	if err != nil {
 		t.Errorf("Первый запрос не должен возвращать ошибку: %v", err)
 	}
 
 	// Второй запрос должен ждать
-	err = rateLimiter.Wait(domain)
+	err = rateLimiter.Wait(context.Background(), domain)
 	if err != nil {
 		t.Errorf("Второй запрос не должен возвращать ошибку: %v", err)
 	}
@@ -170,11 +168,12 @@ func TestEthicalScraperWithMockServer(t *testing.T) {
 	defer server.Close()
 
 	config := &Config{
-		HTTPTimeout:       30,
-		RateLimitInterval: 1,
-		CacheTTL:          1,
-		UserAgent:         "Test-Bot/1.0",
-		ContactEmail:      "test@example.com",
+		HTTPTimeout:          30,
+		AllowPrivateNetworks: true, // httptest сервер слушает 127.0.0.1
+		RateLimitInterval:    1,
+		CacheTTL:             1,
+		UserAgent:            "Test-Bot/1.0",
+		ContactEmail:         "test@example.com",
 	}
 
 	scraper := NewEthicalScraper(config)
@@ -227,11 +226,12 @@ func TestRobotsTxtBlocking(t *testing.T) {
 	defer server.Close()
 
 	config := &Config{
-		HTTPTimeout:       30,
-		RateLimitInterval: 1,
-		CacheTTL:          1,
-		UserAgent:         "TGNIP-Bot",
-		ContactEmail:      "test@example.com",
+		HTTPTimeout:          30,
+		AllowPrivateNetworks: true, // httptest сервер слушает 127.0.0.1
+		RateLimitInterval:    1,
+		CacheTTL:             1,
+		UserAgent:            "TGNIP-Bot",
+		ContactEmail:         "test@example.com",
 	}
 
 	scraper := NewEthicalScraper(config)
@@ -261,11 +261,12 @@ func TestRateLimitBlocking(t *testing.T) {
 	defer server.Close()
 
 	config := &Config{
-		HTTPTimeout:       30,
-		RateLimitInterval: 1,
-		CacheTTL:          1,
-		UserAgent:         "Test-Bot/1.0",
-		ContactEmail:      "test@example.com",
+		HTTPTimeout:          30,
+		AllowPrivateNetworks: true, // httptest сервер слушает 127.0.0.1
+		RateLimitInterval:    1,
+		CacheTTL:             1,
+		UserAgent:            "Test-Bot/1.0",
+		ContactEmail:         "test@example.com",
 	}
 
 	scraper := NewEthicalScraper(config)
@@ -295,11 +296,12 @@ func TestGeoBlocking(t *testing.T) {
 	defer server.Close()
 
 	config := &Config{
-		HTTPTimeout:       30,
-		RateLimitInterval: 1,
-		CacheTTL:          1,
-		UserAgent:         "Test-Bot/1.0",
-		ContactEmail:      "test@example.com",
+		HTTPTimeout:          30,
+		AllowPrivateNetworks: true, // httptest сервер слушает 127.0.0.1
+		RateLimitInterval:    1,
+		CacheTTL:             1,
+		UserAgent:            "Test-Bot/1.0",
+		ContactEmail:         "test@example.com",
 	}
 
 	scraper := NewEthicalScraper(config)
@@ -319,3 +321,102 @@ func TestGeoBlocking(t *testing.T) {
 		t.Error("Должна быть указана причина блокировки")
 	}
 }
+
+func TestConditionalRevalidation(t *testing.T) {
+	var conditionalRequests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/robots.txt" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			conditionalRequests++
+			w.Header().Set("Cache-Control", "max-age=3600")
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+
+		// Ответ сразу устаревает и требует подтверждения
+		w.Header().Set("ETag", `"v1"`)
+		w.Header().Set("Cache-Control", "max-age=0")
+		w.Write([]byte("<html><body>original</body></html>"))
+	}))
+	defer server.Close()
+
+	config := &Config{
+		HTTPTimeout:          30,
+		AllowPrivateNetworks: true, // httptest сервер слушает 127.0.0.1
+		CacheTTL:             1,
+		UserAgent:            "Test-Bot/1.0",
+	}
+
+	scraper := NewEthicalScraper(config)
+	ctx := context.Background()
+
+	first, err := scraper.ScrapeURL(ctx, server.URL+"/page")
+	if err != nil {
+		t.Fatalf("Ошибка первого запроса: %v", err)
+	}
+	if first.IsCached {
+		t.Error("Первый запрос не должен быть кэширован")
+	}
+
+	second, err := scraper.ScrapeURL(ctx, server.URL+"/page")
+	if err != nil {
+		t.Fatalf("Ошибка повторного запроса: %v", err)
+	}
+
+	if conditionalRequests != 1 {
+		t.Errorf("Ожидался 1 условный запрос, выполнено %d", conditionalRequests)
+	}
+	if !second.IsCached || second.StatusCode != 200 {
+		t.Errorf("После 304 ожидался кэшированный ответ 200, получено cached=%v status=%d", second.IsCached, second.StatusCode)
+	}
+	if string(second.Content) != "<html><body>original</body></html>" {
+		t.Errorf("После 304 ожидалось тело из кэша, получено %q", second.Content)
+	}
+
+	// 304 продлил срок жизни, запрос к сайту не нужен
+	third, err := scraper.ScrapeURL(ctx, server.URL+"/page")
+	if err != nil {
+		t.Fatalf("Ошибка третьего запроса: %v", err)
+	}
+	if !third.IsCached || conditionalRequests != 1 {
+		t.Error("После 304 ответ должен обслуживаться из кэша без запроса к сайту")
+	}
+}
+
+func TestCacheControlNoStore(t *testing.T) {
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/robots.txt" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		requests++
+		w.Header().Set("Cache-Control", "no-store")
+		w.Write([]byte("<html><body>private</body></html>"))
+	}))
+	defer server.Close()
+
+	config := &Config{
+		HTTPTimeout:          30,
+		AllowPrivateNetworks: true, // httptest сервер слушает 127.0.0.1
+		CacheTTL:             1,
+		UserAgent:            "Test-Bot/1.0",
+	}
+
+	scraper := NewEthicalScraper(config)
+	ctx := context.Background()
+
+	scraper.ScrapeURL(ctx, server.URL)
+	result, err := scraper.ScrapeURL(ctx, server.URL)
+	if err != nil {
+		t.Fatalf("Ошибка запроса: %v", err)
+	}
+
+	if result.IsCached || requests != 2 {
+		t.Errorf("Ответ с no-store не должен кэшироваться (запросов: %d)", requests)
+	}
+}
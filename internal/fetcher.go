@@ -0,0 +1,271 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gocolly/colly/v2"
+)
+
+// FetchResult представляет загруженную страницу
+type FetchResult struct {
+	Body       []byte
+	FinalURL   string
+	StatusCode int
+	Headers    http.Header
+	IsCached   bool
+}
+
+// Fetcher загружает страницу по URL
+type Fetcher interface {
+	Fetch(ctx context.Context, pageURL string) (*FetchResult, error)
+}
+
+// refererKey - ключ контекста для заголовка Referer
+type refererKey struct{}
+
+// WithReferer добавляет в контекст адрес страницы, с которой запрашивается ресурс
+func WithReferer(ctx context.Context, referer string) context.Context {
+	return context.WithValue(ctx, refererKey{}, referer)
+}
+
+// refererFromContext возвращает адрес страницы для заголовка Referer
+func refererFromContext(ctx context.Context) string {
+	referer, _ := ctx.Value(refererKey{}).(string)
+	return referer
+}
+
+// BlockKind описывает причину отказа в загрузке страницы
+type BlockKind int
+
+const (
+	BlockUnknown BlockKind = iota
+	BlockNotWhitelisted
+	BlockRobotsTxt
+	BlockForbidden
+	BlockTooManyRequests
+	BlockUnsafeURL // внутренний адрес, неподдерживаемая схема или порт
+)
+
+// BlockedError возвращается, когда страницу нельзя загружать по правилам этичного скрапинга
+type BlockedError struct {
+	Kind   BlockKind
+	Reason string
+}
+
+func (e *BlockedError) Error() string {
+	return fmt.Sprintf("загрузка заблокирована: %s", e.Reason)
+}
+
+// CollyFetcher загружает страницы с помощью Colly
+type CollyFetcher struct {
+	config *CollyConfig
+}
+
+// NewCollyFetcher создает fetcher на базе Colly
+func NewCollyFetcher(config *CollyConfig) *CollyFetcher {
+	return &CollyFetcher{config: config}
+}
+
+// Fetch загружает страницу с помощью Colly
+func (f *CollyFetcher) Fetch(ctx context.Context, pageURL string) (*FetchResult, error) {
+	c := createCollyCollector(f.config)
+	c.Context = ctx
+
+	// Изображения статьи ограничиваются своим размером
+	maxBodySize := pageLimitsFromContext(ctx, PageLimits{MaxBodySize: f.config.MaxBodySize}).MaxBodySize
+	c.MaxBodySize = int(maxBodySize)
+
+	var result *FetchResult
+	var loadError error
+
+	if referer := refererFromContext(ctx); referer != "" {
+		c.OnRequest(func(r *colly.Request) {
+			r.Headers.Set("Referer", referer)
+		})
+	}
+
+	c.OnResponse(func(r *colly.Response) {
+		result = &FetchResult{
+			Body:       r.Body,
+			FinalURL:   r.Request.URL.String(),
+			StatusCode: r.StatusCode,
+		}
+		if r.Headers != nil {
+			result.Headers = *r.Headers
+		}
+		// Colly перекодирует ответ только по charset из заголовка, кодировку
+		// из <meta charset> и без объявления определяем сами
+		result.Body, result.Headers = normalizeCharset(result.Body, collyDecodedHeaders(result.Headers))
+		fmt.Printf("Успешно загружена страница: %s (размер: %d байт)\n", result.FinalURL, len(r.Body))
+	})
+
+	// Страницу, размер которой известен из заголовков, не скачиваем
+	c.OnResponseHeaders(func(r *colly.Response) {
+		if maxSize := c.MaxBodySize; maxSize > 0 && r.Headers != nil {
+			if length, err := strconv.ParseInt(r.Headers.Get("Content-Length"), 10, 64); err == nil && length > int64(maxSize) {
+				r.Request.Abort()
+				loadError = &BodyTooLargeError{Limit: int64(maxSize)}
+			}
+		}
+	})
+
+	c.OnError(func(r *colly.Response, err error) {
+		// Причина прерывания уже записана в OnResponseHeaders
+		if errors.Is(err, colly.ErrAbortedAfterHeaders) {
+			return
+		}
+		loadError = fmt.Errorf("ошибка при загрузке %s: %w", r.Request.URL, err)
+	})
+
+	parsedURL, err := url.Parse(pageURL)
+	if err != nil {
+		return nil, fmt.Errorf("неверный URL: %w", err)
+	}
+	if err := f.config.network().CheckURL(parsedURL); err != nil {
+		return nil, err
+	}
+
+	if err := c.Visit(pageURL); err != nil {
+		if ctx.Err() != nil {
+			return nil, fmt.Errorf("загрузка %s прервана: %w", pageURL, ctx.Err())
+		}
+		if loadError != nil {
+			return nil, loadError
+		}
+		return nil, fmt.Errorf("ошибка при посещении страницы: %w", err)
+	}
+
+	if loadError != nil {
+		return nil, loadError
+	}
+
+	if result == nil || len(result.Body) == 0 {
+		return nil, fmt.Errorf("не удалось загрузить контент страницы")
+	}
+
+	// Colly обрезает ответ до MaxBodySize без ошибки, поэтому ответ
+	// такого размера считаем превышающим ограничение
+	if maxBodySize > 0 && int64(len(result.Body)) >= maxBodySize {
+		return nil, &BodyTooLargeError{Limit: maxBodySize}
+	}
+
+	return result, nil
+}
+
+// HTTPFetcher загружает страницы стандартным HTTP клиентом
+type HTTPFetcher struct {
+	client  *http.Client
+	network *NetworkPolicy
+}
+
+// NewHTTPFetcher создает fetcher на базе net/http с сетевой политикой из SetNetworkPolicy
+func NewHTTPFetcher(timeout time.Duration) *HTTPFetcher {
+	return &HTTPFetcher{
+		client: &http.Client{
+			Transport:     networkPolicy.Transport(),
+			Timeout:       timeout,
+			CheckRedirect: networkPolicy.CheckRedirect,
+		},
+		network: networkPolicy,
+	}
+}
+
+// Fetch загружает страницу стандартным HTTP клиентом
+func (f *HTTPFetcher) Fetch(ctx context.Context, pageURL string) (*FetchResult, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", pageURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка создания запроса: %w", err)
+	}
+	if err := f.network.CheckURL(req.URL); err != nil {
+		return nil, err
+	}
+
+	if referer := refererFromContext(ctx); referer != "" {
+		req.Header.Set("Referer", referer)
+	}
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при загрузке страницы: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("статус ответа: %d", resp.StatusCode)
+	}
+
+	bodyBytes, err := readLimitedBody(resp, pageLimitsFromContext(ctx, pageLimits).MaxBodySize)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при чтении тела ответа: %w", err)
+	}
+
+	// Страницы в windows-1251, KOI8-R, Shift_JIS и т.д. перекодируем в UTF-8
+	bodyBytes, headers := normalizeCharset(bodyBytes, resp.Header)
+
+	return &FetchResult{
+		Body:       bodyBytes,
+		FinalURL:   resp.Request.URL.String(),
+		StatusCode: resp.StatusCode,
+		Headers:    headers,
+	}, nil
+}
+
+// EthicalFetcher загружает страницы через EthicalScraper
+type EthicalFetcher struct {
+	scraper *EthicalScraper
+}
+
+// NewEthicalFetcher создает fetcher на базе EthicalScraper
+func NewEthicalFetcher(scraper *EthicalScraper) *EthicalFetcher {
+	return &EthicalFetcher{scraper: scraper}
+}
+
+// Fetch загружает страницу с соблюдением белого списка, robots.txt и rate limit
+func (f *EthicalFetcher) Fetch(ctx context.Context, pageURL string) (*FetchResult, error) {
+	result, err := f.scraper.ScrapeURL(ctx, pageURL)
+	if err != nil {
+		return nil, err
+	}
+
+	if result.IsBlocked {
+		return nil, &BlockedError{
+			Kind:   result.BlockKind,
+			Reason: result.BlockReason,
+		}
+	}
+
+	finalURL := result.FinalURL
+	if finalURL == "" {
+		finalURL = pageURL
+	}
+
+	return &FetchResult{
+		Body:       result.Content,
+		FinalURL:   finalURL,
+		StatusCode: result.StatusCode,
+		Headers:    result.Headers,
+		IsCached:   result.IsCached,
+	}, nil
+}
+
+// NewFetcher создает fetcher в соответствии с конфигурацией
+func NewFetcher(config *Config) Fetcher {
+	switch strings.ToLower(config.FetcherBackend) {
+	case "colly":
+		collyConfig := DefaultCollyConfig()
+		collyConfig.Timeout = time.Duration(config.HTTPTimeout) * time.Second
+		collyConfig.MaxRetries = config.MaxRetries
+		collyConfig.Network = NewNetworkPolicy(config.AllowPrivateNetworks, config.AllowedPorts)
+		collyConfig.MaxBodySize = int64(config.MaxBodySizeMB) * 1024 * 1024
+		return NewCollyFetcher(collyConfig)
+	default:
+		return NewEthicalFetcher(NewEthicalScraper(config))
+	}
+}
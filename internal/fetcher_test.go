@@ -0,0 +1,113 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// stubFetcher возвращает заранее заданный результат
+type stubFetcher struct {
+	result *FetchResult
+	err    error
+}
+
+func (f *stubFetcher) Fetch(ctx context.Context, pageURL string) (*FetchResult, error) {
+	return f.result, f.err
+}
+
+func TestExtractContentWithCustomFetcher(t *testing.T) {
+	html := `<html><head><title>Stub Page</title></head><body><article>
+<h1>Stub Page</h1>
+<p>This is a long enough paragraph of text for readability to treat it as the main content of the page.</p>
+<p>Another paragraph with more words so that the article is not considered empty by the extractor.</p>
+</article></body></html>`
+
+	config := DefaultCollyConfig()
+	config.Fetcher = &stubFetcher{result: &FetchResult{
+		Body:       []byte(html),
+		FinalURL:   "https://example.com/final",
+		StatusCode: 200,
+	}}
+
+	content, err := ExtractContentWithConfig(context.Background(), "https://example.com/start", config)
+	if err != nil {
+		t.Fatalf("Ошибка извлечения: %v", err)
+	}
+
+	if content.URL != "https://example.com/final" {
+		t.Errorf("Ожидался финальный URL, получен '%s'", content.URL)
+	}
+
+	if !strings.Contains(content.Markdown, "long enough paragraph") {
+		t.Errorf("Markdown не содержит текст статьи: %s", content.Markdown)
+	}
+}
+
+func TestEthicalFetcherBlocked(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/robots.txt" {
+			w.Write([]byte("User-agent: *\nDisallow: /private\n"))
+			return
+		}
+		w.Write([]byte("<html><body>ok</body></html>"))
+	}))
+	defer server.Close()
+
+	config := &Config{
+		HTTPTimeout:          30,
+		AllowPrivateNetworks: true, // httptest сервер слушает 127.0.0.1
+		RateLimitInterval:    1,
+		CacheTTL:             1,
+		UserAgent:            "Test-Bot/1.0",
+	}
+
+	fetcher := NewEthicalFetcher(NewEthicalScraper(config))
+
+	_, err := fetcher.Fetch(context.Background(), server.URL+"/private/page")
+	var blocked *BlockedError
+	if !errors.As(err, &blocked) {
+		t.Fatalf("Ожидалась BlockedError, получено %v", err)
+	}
+
+	if blocked.Kind != BlockRobotsTxt {
+		t.Errorf("Ожидалась блокировка robots.txt, получено %v", blocked.Kind)
+	}
+
+	result, err := fetcher.Fetch(context.Background(), server.URL+"/public")
+	if err != nil {
+		t.Fatalf("Ошибка загрузки: %v", err)
+	}
+
+	if result.FinalURL != server.URL+"/public" {
+		t.Errorf("Неверный финальный URL: %s", result.FinalURL)
+	}
+}
+
+func TestErrorMessageFor(t *testing.T) {
+	locale := locales["en"]
+
+	tests := []struct {
+		name     string
+		err      error
+		expected string
+	}{
+		{"generic error", errors.New("boom"), locale.ErrorProcessingMsg},
+		{"whitelist", &BlockedError{Kind: BlockNotWhitelisted}, locale.BlockedDomainMsg},
+		{"robots", &BlockedError{Kind: BlockRobotsTxt}, locale.BlockedRobotsMsg},
+		{"forbidden", &BlockedError{Kind: BlockForbidden}, locale.BlockedAccessMsg},
+		{"too many requests", &BlockedError{Kind: BlockTooManyRequests}, locale.BlockedRateLimitMsg},
+		{"unsafe url", &BlockedError{Kind: BlockUnsafeURL}, locale.BlockedUnsafeURLMsg},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := errorMessageFor(tt.err, locale); got != tt.expected {
+				t.Errorf("errorMessageFor() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
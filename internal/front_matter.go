@@ -0,0 +1,426 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// FrontMatterTarget описывает оформление front matter для программы заметок
+// или генератора сайтов
+type FrontMatterTarget struct {
+	Name    string
+	Syntax  string // "yaml" или "toml"
+	Heading bool   // добавлять заголовок # в начало текста
+	fields  func(m frontMatterData) []frontMatterField
+}
+
+// frontMatterTargets содержит поддерживаемые варианты в порядке показа пользователю
+var frontMatterTargets = []FrontMatterTarget{
+	{Name: "yaml", Syntax: "yaml", Heading: true, fields: genericFrontMatter},
+	{Name: "toml", Syntax: "toml", Heading: true, fields: genericFrontMatter},
+	{Name: "obsidian", Syntax: "yaml", Heading: true, fields: obsidianFrontMatter},
+	{Name: "hugo", Syntax: "toml", fields: hugoFrontMatter},
+	{Name: "jekyll", Syntax: "yaml", fields: jekyllFrontMatter},
+}
+
+// GetFrontMatterTarget возвращает вариант front matter по имени
+func GetFrontMatterTarget(name string) (FrontMatterTarget, bool) {
+	for _, target := range frontMatterTargets {
+		if target.Name == name {
+			return target, true
+		}
+	}
+	return FrontMatterTarget{}, false
+}
+
+// wordsPerMinute - средняя скорость чтения для оценки времени чтения
+const wordsPerMinute = 200
+
+// frontMatterData содержит метаданные статьи для front matter
+type frontMatterData struct {
+	Title       string
+	Description string
+	Source      string
+	Author      string
+	Date        time.Time
+	DateOnly    bool // дата публикации известна без времени
+	HasDate     bool
+	Modified    time.Time // нулевое время - дата изменения неизвестна
+	Processed   time.Time
+	Language    string
+	Tags        []string
+	WordCount   int
+	ReadingTime int // минут
+}
+
+// frontMatterField - поле front matter. Значение может быть строкой, числом,
+// логическим значением, списком строк, датой или вложенной таблицей полей
+type frontMatterField struct {
+	Key   string
+	Value interface{}
+}
+
+// frontMatterDate записывается без кавычек, как дата YAML или TOML
+type frontMatterDate string
+
+// newFrontMatterData собирает метаданные статьи
+func newFrontMatterData(content *Content, originalURL string, now time.Time) frontMatterData {
+	data := frontMatterData{
+		Title:       content.Title,
+		Description: content.Description,
+		Source:      originalURL,
+		Author:      content.Author,
+		Modified:    content.Modified,
+		Processed:   now,
+		Language:    content.Language,
+		Tags:        content.Tags,
+		WordCount:   markdownWordCount(content.Markdown),
+	}
+
+	if !content.Published.IsZero() {
+		data.Date, data.DateOnly, data.HasDate = content.Published, isDateOnly(content.Published), true
+	} else if date, dateOnly, ok := normalizeDate(content.Date); ok {
+		data.Date, data.DateOnly, data.HasDate = date, dateOnly, true
+	}
+
+	if data.WordCount > 0 {
+		data.ReadingTime = int(math.Ceil(float64(data.WordCount) / wordsPerMinute))
+	}
+
+	return data
+}
+
+// isoDate возвращает дату публикации в формате ISO 8601
+func (m frontMatterData) isoDate() frontMatterDate {
+	if m.DateOnly {
+		return frontMatterDate(m.Date.Format("2006-01-02"))
+	}
+	return frontMatterDate(m.Date.Format(time.RFC3339))
+}
+
+// modifiedField возвращает поле с датой изменения, если она известна
+func (m frontMatterData) modifiedField(key string) []frontMatterField {
+	if m.Modified.IsZero() {
+		return nil
+	}
+	return []frontMatterField{{key, frontMatterDate(m.Modified.Format(time.RFC3339))}}
+}
+
+// genericFrontMatter - поля front matter без привязки к программе
+func genericFrontMatter(m frontMatterData) []frontMatterField {
+	fields := []frontMatterField{
+		{"title", m.Title},
+		{"description", m.Description},
+		{"source", m.Source},
+		{"author", m.Author},
+	}
+	if m.HasDate {
+		fields = append(fields, frontMatterField{"date", m.isoDate()})
+	}
+	fields = append(fields, m.modifiedField("modified")...)
+	return append(fields,
+		frontMatterField{"processed", frontMatterDate(m.Processed.Format(time.RFC3339))},
+		frontMatterField{"language", m.Language},
+		frontMatterField{"tags", m.Tags},
+		frontMatterField{"word_count", m.WordCount},
+		frontMatterField{"reading_time", m.ReadingTime},
+	)
+}
+
+// obsidianFrontMatter следует свойствам заметок Obsidian: списки авторов и тегов,
+// даты published и created, теги без пробелов
+func obsidianFrontMatter(m frontMatterData) []frontMatterField {
+	var authors []string
+	if m.Author != "" {
+		authors = []string{m.Author}
+	}
+
+	tags := make([]string, 0, len(m.Tags))
+	for _, tag := range m.Tags {
+		tag = strings.Join(strings.Fields(strings.TrimPrefix(tag, "#")), "-")
+		if tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+
+	fields := []frontMatterField{
+		{"title", m.Title},
+		{"description", m.Description},
+		{"source", m.Source},
+		{"author", authors},
+	}
+	if m.HasDate {
+		fields = append(fields, frontMatterField{"published", frontMatterDate(m.Date.Format("2006-01-02"))})
+	}
+	return append(fields,
+		frontMatterField{"created", frontMatterDate(m.Processed.Format("2006-01-02"))},
+		frontMatterField{"lang", m.Language},
+		frontMatterField{"tags", tags},
+		frontMatterField{"word_count", m.WordCount},
+		frontMatterField{"reading_time", m.ReadingTime},
+	)
+}
+
+// hugoFrontMatter использует встроенные поля Hugo, остальное кладет в params
+func hugoFrontMatter(m frontMatterData) []frontMatterField {
+	var authors []string
+	if m.Author != "" {
+		authors = []string{m.Author}
+	}
+
+	fields := []frontMatterField{
+		{"title", m.Title},
+		{"description", m.Description},
+	}
+	if m.HasDate {
+		fields = append(fields, frontMatterField{"date", m.isoDate()})
+	}
+	fields = append(fields, m.modifiedField("lastmod")...)
+	return append(fields,
+		frontMatterField{"draft", false},
+		frontMatterField{"authors", authors},
+		frontMatterField{"tags", m.Tags},
+		frontMatterField{"params", []frontMatterField{
+			{"source", m.Source},
+			{"language", m.Language},
+			{"processed", frontMatterDate(m.Processed.Format(time.RFC3339))},
+			{"word_count", m.WordCount},
+			{"reading_time", m.ReadingTime},
+		}},
+	)
+}
+
+// jekyllFrontMatter следует соглашениям Jekyll для записей блога
+func jekyllFrontMatter(m frontMatterData) []frontMatterField {
+	fields := []frontMatterField{
+		{"layout", "post"},
+		{"title", m.Title},
+	}
+	if m.HasDate {
+		fields = append(fields, frontMatterField{"date", frontMatterDate(m.Date.Format("2006-01-02 15:04:05 -0700"))})
+	}
+	fields = append(fields, m.modifiedField("last_modified_at")...)
+	return append(fields,
+		frontMatterField{"description", m.Description},
+		frontMatterField{"author", m.Author},
+		frontMatterField{"tags", m.Tags},
+		frontMatterField{"lang", m.Language},
+		frontMatterField{"source", m.Source},
+		frontMatterField{"processed", frontMatterDate(m.Processed.Format(time.RFC3339))},
+		frontMatterField{"word_count", m.WordCount},
+		frontMatterField{"reading_time", m.ReadingTime},
+	)
+}
+
+// RenderFrontMatter создает блок front matter с метаданными статьи
+func RenderFrontMatter(content *Content, originalURL string, target FrontMatterTarget, now time.Time) string {
+	fields := target.fields(newFrontMatterData(content, originalURL, now))
+
+	var b strings.Builder
+	if target.Syntax == "toml" {
+		b.WriteString("+++\n")
+		writeTOMLFields(&b, fields, "")
+		b.WriteString("+++\n")
+	} else {
+		b.WriteString("---\n")
+		writeYAMLFields(&b, fields, "")
+		b.WriteString("---\n")
+	}
+	return b.String()
+}
+
+// ConvertToMarkdownWithFrontMatter создает markdown документ, в котором метаданные
+// записаны в front matter вместо раздела с метаинформацией и подписи
+func ConvertToMarkdownWithFrontMatter(ctx context.Context, content *Content, originalURL string, target FrontMatterTarget, now time.Time) string {
+	var markdown strings.Builder
+
+	markdown.WriteString(RenderFrontMatter(content, originalURL, target, now))
+	markdown.WriteString("\n")
+
+	if target.Heading {
+		markdown.WriteString(fmt.Sprintf("# %s\n\n", escapeMarkdown(content.Title)))
+	}
+
+	markdown.WriteString(processMarkdownWithLanguageDetection(ctx, content.Markdown))
+	markdown.WriteString("\n")
+
+	return markdown.String()
+}
+
+// writeYAMLFields записывает поля в формате YAML, пропуская пустые значения
+func writeYAMLFields(b *strings.Builder, fields []frontMatterField, indent string) {
+	for _, field := range fields {
+		switch value := field.Value.(type) {
+		case string:
+			if value != "" {
+				fmt.Fprintf(b, "%s%s: %s\n", indent, field.Key, quoteFrontMatter(value))
+			}
+		case frontMatterDate:
+			fmt.Fprintf(b, "%s%s: %s\n", indent, field.Key, value)
+		case int:
+			fmt.Fprintf(b, "%s%s: %d\n", indent, field.Key, value)
+		case bool:
+			fmt.Fprintf(b, "%s%s: %t\n", indent, field.Key, value)
+		case []string:
+			if len(value) == 0 {
+				fmt.Fprintf(b, "%s%s: []\n", indent, field.Key)
+				continue
+			}
+			fmt.Fprintf(b, "%s%s:\n", indent, field.Key)
+			for _, item := range value {
+				fmt.Fprintf(b, "%s  - %s\n", indent, quoteFrontMatter(item))
+			}
+		case []frontMatterField:
+			fmt.Fprintf(b, "%s%s:\n", indent, field.Key)
+			writeYAMLFields(b, value, indent+"  ")
+		}
+	}
+}
+
+// writeTOMLFields записывает поля в формате TOML; вложенные таблицы
+// идут после простых значений, как требует синтаксис
+func writeTOMLFields(b *strings.Builder, fields []frontMatterField, table string) {
+	var tables []frontMatterField
+
+	for _, field := range fields {
+		switch value := field.Value.(type) {
+		case string:
+			if value != "" {
+				fmt.Fprintf(b, "%s = %s\n", field.Key, quoteFrontMatter(value))
+			}
+		case frontMatterDate:
+			fmt.Fprintf(b, "%s = %s\n", field.Key, value)
+		case int:
+			fmt.Fprintf(b, "%s = %d\n", field.Key, value)
+		case bool:
+			fmt.Fprintf(b, "%s = %t\n", field.Key, value)
+		case []string:
+			items := make([]string, len(value))
+			for i, item := range value {
+				items[i] = quoteFrontMatter(item)
+			}
+			fmt.Fprintf(b, "%s = [%s]\n", field.Key, strings.Join(items, ", "))
+		case []frontMatterField:
+			tables = append(tables, field)
+		}
+	}
+
+	for _, field := range tables {
+		name := field.Key
+		if table != "" {
+			name = table + "." + name
+		}
+		fmt.Fprintf(b, "\n[%s]\n", name)
+		writeTOMLFields(b, field.Value.([]frontMatterField), name)
+	}
+}
+
+// quoteFrontMatter записывает строку в двойных кавычках с экранированием,
+// допустимым и в YAML, и в TOML
+func quoteFrontMatter(value string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range value {
+		switch r {
+		case '"':
+			b.WriteString(`\"`)
+		case '\\':
+			b.WriteString(`\\`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '\t':
+			b.WriteString(`\t`)
+		default:
+			if unicode.IsControl(r) {
+				fmt.Fprintf(&b, `\u%04X`, r)
+			} else {
+				b.WriteRune(r)
+			}
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+// dateLayouts - форматы дат, которые встречаются на страницах
+var dateLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05.000Z0700",
+	"2006-01-02T15:04:05Z0700",
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04:05 -0700",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	time.RFC1123Z,
+	time.RFC1123,
+	time.RFC850,
+	"Mon, 2 Jan 2006 15:04:05 -0700",
+	"Mon, 2 Jan 2006 15:04:05 MST",
+}
+
+// dateOnlyLayouts - форматы дат без времени
+var dateOnlyLayouts = []string{
+	"2006-01-02",
+	"2006/01/02",
+	"02.01.2006",
+	"2.1.2006",
+	"January 2, 2006",
+	"Jan 2, 2006",
+	"2 January 2006",
+	"2 Jan 2006",
+	"Monday, January 2, 2006",
+}
+
+// normalizeDate разбирает дату со страницы. dateOnly означает, что время не указано
+func normalizeDate(raw string) (date time.Time, dateOnly bool, ok bool) {
+	raw = strings.Join(strings.Fields(raw), " ")
+	if raw == "" {
+		return time.Time{}, false, false
+	}
+
+	for _, layout := range dateLayouts {
+		if parsed, err := time.Parse(layout, raw); err == nil {
+			return parsed, false, true
+		}
+	}
+
+	for _, layout := range dateOnlyLayouts {
+		if parsed, err := time.Parse(layout, raw); err == nil {
+			return parsed, true, true
+		}
+	}
+
+	// Unix время в секундах
+	if seconds, err := strconv.ParseInt(raw, 10, 64); err == nil && len(raw) == 10 {
+		return time.Unix(seconds, 0).UTC(), false, true
+	}
+
+	return time.Time{}, false, false
+}
+
+// markdownWordCount считает слова текста без адресов ссылок и изображений
+func markdownWordCount(markdown string) int {
+	text := markdownLinkPattern.ReplaceAllStringFunc(markdown, func(match string) string {
+		parts := markdownLinkPattern.FindStringSubmatch(match)
+		if parts[1] == "!" {
+			return " "
+		}
+		return parts[2]
+	})
+
+	count := 0
+	for _, word := range strings.Fields(text) {
+		if strings.IndexFunc(word, func(r rune) bool { return unicode.IsLetter(r) || unicode.IsDigit(r) }) >= 0 {
+			count++
+		}
+	}
+	return count
+}
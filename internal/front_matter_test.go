@@ -0,0 +1,153 @@
+package internal
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func frontMatterContent() *Content {
+	return &Content{
+		Title:    `Заголовок "в кавычках"`,
+		Author:   "Иван Петров",
+		Date:     "2024-03-15T10:30:00+03:00",
+		Language: "ru",
+		Tags:     []string{"Go", "веб разработка"},
+		Markdown: "Раз два [три](https://example.com/3) четыре ![картинка](https://example.com/i.png) - пять.",
+	}
+}
+
+var frontMatterNow = time.Date(2024, 4, 1, 12, 0, 0, 0, time.UTC)
+
+func TestRenderFrontMatterYAML(t *testing.T) {
+	target, _ := GetFrontMatterTarget("yaml")
+	result := RenderFrontMatter(frontMatterContent(), "https://example.com/a", target, frontMatterNow)
+
+	expected := `---
+title: "Заголовок \"в кавычках\""
+source: "https://example.com/a"
+author: "Иван Петров"
+date: 2024-03-15T10:30:00+03:00
+processed: 2024-04-01T12:00:00Z
+language: "ru"
+tags:
+  - "Go"
+  - "веб разработка"
+word_count: 5
+reading_time: 1
+---
+`
+	if result != expected {
+		t.Errorf("Неверный YAML front matter:\n%s", result)
+	}
+}
+
+func TestRenderFrontMatterHugo(t *testing.T) {
+	content := frontMatterContent()
+	content.Date = "15.03.2024"
+
+	target, _ := GetFrontMatterTarget("hugo")
+	result := RenderFrontMatter(content, "https://example.com/a", target, frontMatterNow)
+
+	expected := `+++
+title = "Заголовок \"в кавычках\""
+date = 2024-03-15
+draft = false
+authors = ["Иван Петров"]
+tags = ["Go", "веб разработка"]
+
+[params]
+source = "https://example.com/a"
+language = "ru"
+processed = 2024-04-01T12:00:00Z
+word_count = 5
+reading_time = 1
++++
+`
+	if result != expected {
+		t.Errorf("Неверный TOML front matter для Hugo:\n%s", result)
+	}
+}
+
+func TestRenderFrontMatterTargets(t *testing.T) {
+	content := frontMatterContent()
+
+	obsidian, _ := GetFrontMatterTarget("obsidian")
+	result := RenderFrontMatter(content, "https://example.com/a", obsidian, frontMatterNow)
+	for _, expected := range []string{"published: 2024-03-15", "created: 2024-04-01", "  - \"веб-разработка\"", "author:\n  - \"Иван Петров\""} {
+		if !strings.Contains(result, expected) {
+			t.Errorf("Front matter Obsidian должен содержать %q:\n%s", expected, result)
+		}
+	}
+
+	jekyll, _ := GetFrontMatterTarget("jekyll")
+	result = RenderFrontMatter(content, "https://example.com/a", jekyll, frontMatterNow)
+	for _, expected := range []string{"layout: \"post\"", "date: 2024-03-15 10:30:00 +0300", "lang: \"ru\""} {
+		if !strings.Contains(result, expected) {
+			t.Errorf("Front matter Jekyll должен содержать %q:\n%s", expected, result)
+		}
+	}
+
+	// Дата, которую не удалось разобрать, не попадает в front matter
+	content.Date = "вчера"
+	result = RenderFrontMatter(content, "https://example.com/a", jekyll, frontMatterNow)
+	if strings.Contains(result, "date:") {
+		t.Errorf("Неразобранная дата не должна записываться:\n%s", result)
+	}
+}
+
+func TestConvertToMarkdownWithFrontMatter(t *testing.T) {
+	hugo, _ := GetFrontMatterTarget("hugo")
+	result := ConvertToMarkdownWithFrontMatter(context.Background(), frontMatterContent(), "https://example.com/a", hugo, frontMatterNow)
+
+	if !strings.HasPrefix(result, "+++\n") {
+		t.Error("Документ должен начинаться с front matter")
+	}
+	if strings.Contains(result, "# ") || strings.Contains(result, locales["ru"].FooterText) {
+		t.Error("Для Hugo заголовок и подпись не добавляются в текст")
+	}
+
+	yaml, _ := GetFrontMatterTarget("yaml")
+	result = ConvertToMarkdownWithFrontMatter(context.Background(), frontMatterContent(), "https://example.com/a", yaml, frontMatterNow)
+	if !strings.Contains(result, "---\n\n# Заголовок") {
+		t.Errorf("После YAML front matter должен идти заголовок:\n%s", result)
+	}
+}
+
+func TestUserSettingsFrontMatter(t *testing.T) {
+	format := UserSettings{FrontMatter: "obsidian"}.withDefaults().OutputFormat()
+	if f, ok := format.(markdownFormat); !ok || f.frontMatter != "obsidian" {
+		t.Errorf("Для markdown должен использоваться front matter, получено %#v", format)
+	}
+
+	format = UserSettings{Format: "html", FrontMatter: "obsidian"}.OutputFormat()
+	if format.Name() != "html" {
+		t.Errorf("Front matter не должен менять другие форматы, получено %s", format.Name())
+	}
+}
+
+func TestNormalizeDate(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+		dateOnly bool
+	}{
+		{"2024-03-15", "2024-03-15T00:00:00Z", true},
+		{"March 15, 2024", "2024-03-15T00:00:00Z", true},
+		{"Fri, 15 Mar 2024 10:00:00 GMT", "2024-03-15T10:00:00Z", false},
+		{"2024-03-15T10:00:00.000Z", "2024-03-15T10:00:00Z", false},
+	}
+
+	for _, tt := range tests {
+		date, dateOnly, ok := normalizeDate(tt.input)
+		if !ok || date.UTC().Format(time.RFC3339) != tt.expected || dateOnly != tt.dateOnly {
+			t.Errorf("Для %q ожидалось %s (без времени: %t), получено %s (%t, %t)",
+				tt.input, tt.expected, tt.dateOnly, date.Format(time.RFC3339), dateOnly, ok)
+		}
+	}
+
+	if _, _, ok := normalizeDate("недавно"); ok {
+		t.Error("Произвольный текст не должен разбираться как дата")
+	}
+}
@@ -1,28 +1,123 @@
 package internal
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 	"github.com/sirupsen/logrus"
 )
 
 var logger *logrus.Logger
 
+// fetcher используется ботом для загрузки страниц
+var fetcher Fetcher
+
+// settings хранит настройки пользователей
+var settings = &SettingsStore{users: make(map[int64]UserSettings)}
+
+// imageOptions ограничивает загрузку изображений статей
+var imageOptions = ImageOptions{
+	MaxImageSize: 5 * 1024 * 1024,
+	MaxImages:    50,
+	MaxTotalSize: 20 * 1024 * 1024,
+}
+
+// referenceLinksMin - с какого числа ссылок в абзаце они выносятся
+// в определения [n]: url, 0 - не выносить
+var referenceLinksMin int
+
+// Ограничения обработки сообщения с несколькими ссылками
+var (
+	batchConcurrency = 3
+	batchMaxURLs     = 10
+)
+
 // SetLogger устанавливает логгер для пакета
 func SetLogger(l *logrus.Logger) {
 	logger = l
 }
 
-// HandleMessage обрабатывает входящие сообщения
-func HandleMessage(bot *tgbotapi.BotAPI, message *tgbotapi.Message) {
+// SetFetcher устанавливает источник загрузки страниц для обработчиков
+func SetFetcher(f Fetcher) {
+	fetcher = f
+}
+
+// SetSettingsStore устанавливает хранилище настроек пользователей
+func SetSettingsStore(s *SettingsStore) {
+	settings = s
+}
+
+// SetBatchLimits задает число одновременно обрабатываемых ссылок
+// и максимальное число ссылок в одном сообщении
+func SetBatchLimits(concurrency, maxURLs int) {
+	batchConcurrency = concurrency
+	batchMaxURLs = maxURLs
+}
+
+// SetImageOptions задает ограничения загрузки изображений статей
+func SetImageOptions(opts ImageOptions) {
+	imageOptions = opts
+}
+
+// markdownTemplates - шаблоны оформления markdown файлов, заданные оператором
+var markdownTemplates *MarkdownTemplates
+
+// SetMarkdownTemplates задает шаблоны, которые пользователи выбирают командой /template
+func SetMarkdownTemplates(templates *MarkdownTemplates) {
+	markdownTemplates = templates
+}
+
+// SetReferenceLinksMin задает число ссылок в абзаце, начиная с которого
+// они оформляются в стиле [текст][n]
+func SetReferenceLinksMin(minLinks int) {
+	referenceLinksMin = minLinks
+}
+
+// HandleMessage обрабатывает входящие сообщения; ctx отменяется командой /cancel
+func HandleMessage(ctx context.Context, bot *tgbotapi.BotAPI, message *tgbotapi.Message) {
 	// Обработка команды /start
 	if message.IsCommand() && message.Command() == "start" {
 		HandleStartCommand(bot, message)
 		return
 	}
 
+	// Обработка команды /batch
+	if message.IsCommand() && message.Command() == "batch" {
+		HandleBatchCommand(bot, message)
+		return
+	}
+
+	// Обработка команды /format
+	if message.IsCommand() && message.Command() == "format" {
+		HandleFormatCommand(bot, message)
+		return
+	}
+
+	// Обработка команды /images
+	if message.IsCommand() && message.Command() == "images" {
+		HandleImagesCommand(bot, message)
+		return
+	}
+
+	// Обработка команды /frontmatter
+	if message.IsCommand() && message.Command() == "frontmatter" {
+		HandleFrontMatterCommand(bot, message)
+		return
+	}
+
+	// Обработка команды /template
+	if message.IsCommand() && message.Command() == "template" {
+		HandleTemplateCommand(bot, message)
+		return
+	}
+
 	// Обработка ссылок
 	if message.Text != "" {
-		HandleURLMessage(bot, message)
+		HandleURLMessage(ctx, bot, message)
 	}
 }
 
@@ -34,17 +129,25 @@ func HandleStartCommand(bot *tgbotapi.BotAPI, message *tgbotapi.Message) {
 }
 
 // HandleURLMessage обрабатывает сообщения с URL
-func HandleURLMessage(bot *tgbotapi.BotAPI, message *tgbotapi.Message) {
+func HandleURLMessage(ctx context.Context, bot *tgbotapi.BotAPI, message *tgbotapi.Message) {
 	locale := GetLocale(message)
-	url := message.Text
 
-	// Проверка, что это действительно ссылка
-	if !IsValidURL(url) {
+	// Ищем ссылки в тексте и сущностях сообщения
+	urls := ExtractURLs(message)
+	if len(urls) == 0 {
 		msg := tgbotapi.NewMessage(message.Chat.ID, locale.InvalidURLMessage)
 		bot.Send(msg)
 		return
 	}
 
+	prefs := userSettings(message)
+
+	if len(urls) > 1 {
+		handleBatch(ctx, bot, message, urls, locale, prefs)
+		return
+	}
+	url := urls[0]
+
 	// Отправляем сообщение о начале обработки
 	processingMsg := tgbotapi.NewMessage(message.Chat.ID, locale.ProcessingMessage)
 	sentMsg, err := bot.Send(processingMsg)
@@ -52,38 +155,286 @@ func HandleURLMessage(bot *tgbotapi.BotAPI, message *tgbotapi.Message) {
 		logger.Errorf("Ошибка при отправке сообщения о обработке: %v", err)
 	}
 
-	// Извлекаем контент
-	content, err := ExtractContent(url)
+	// Извлекаем контент и создаем файл
+	fileBytes, err := processURL(ctx, url, locale, prefs)
+	if ctx.Err() != nil {
+		logger.Infof("Обработка ссылки прервана, пользователь %d", message.Chat.ID)
+		deleteMessage(bot, sentMsg)
+		return
+	}
 	if err != nil {
 		logger.Errorf("Ошибка при извлечении контента: %v", err)
-		errorMsg := tgbotapi.NewMessage(message.Chat.ID, locale.ErrorProcessingMsg)
+		errorMsg := tgbotapi.NewMessage(message.Chat.ID, errorMessageFor(err, locale))
 		bot.Send(errorMsg)
 		return
 	}
 
-	// Конвертируем в markdown
-	markdown := ConvertToMarkdown(content, url, locale)
-
-	// Создаем файл
-	filename := GenerateFilename(url, content.Title)
-	file := tgbotapi.NewDocument(message.Chat.ID, tgbotapi.FileBytes{
-		Name:  filename,
-		Bytes: []byte(markdown),
-	})
+	file := tgbotapi.NewDocument(message.Chat.ID, fileBytes)
 
 	// Отправляем файл
-	if _, err := bot.Send(file); err != nil {
+	if _, err := sendContext(ctx, bot, file); err != nil {
+		if ctx.Err() != nil {
+			logger.Infof("Отправка файла прервана, пользователь %d", message.Chat.ID)
+			deleteMessage(bot, sentMsg)
+			return
+		}
 		logger.Errorf("Ошибка при отправке файла: %v", err)
-		errorMsg := tgbotapi.NewMessage(message.Chat.ID, locale.ErrorSendingMsg)
+		errorMsg := tgbotapi.NewMessage(message.Chat.ID, sendErrorMessage(err, locale))
 		bot.Send(errorMsg)
 		return
 	}
 
 	// Удаляем сообщение о обработке
-	if sentMsg.MessageID != 0 {
-		deleteMsg := tgbotapi.NewDeleteMessage(message.Chat.ID, sentMsg.MessageID)
-		bot.Send(deleteMsg)
-	}
+	deleteMessage(bot, sentMsg)
 
 	logger.Infof("Файл успешно отправлен пользователю %d", message.Chat.ID)
 }
+
+// deleteMessage удаляет отправленное ботом сообщение, если оно было отправлено
+func deleteMessage(bot *tgbotapi.BotAPI, sent tgbotapi.Message) {
+	if sent.MessageID != 0 && sent.Chat != nil {
+		bot.Send(tgbotapi.NewDeleteMessage(sent.Chat.ID, sent.MessageID))
+	}
+}
+
+// processURL извлекает контент страницы и создает файл в выбранном пользователем формате.
+// Загрузка и оформление файла ограничены временем своих стадий
+func processURL(ctx context.Context, url string, locale Locale, prefs UserSettings) (tgbotapi.FileBytes, error) {
+	config := DefaultCollyConfig()
+	config.Fetcher = fetcher
+
+	fetchCtx, cancelFetch := withStageTimeout(ctx, stageTimeouts.Fetch)
+	defer cancelFetch()
+
+	content, err := ExtractContentWithConfig(fetchCtx, url, config)
+	if err != nil {
+		return tgbotapi.FileBytes{}, stageError(fetchCtx, err)
+	}
+
+	format := prefs.OutputFormat()
+
+	// Абзацы, перегруженные ссылками, читаются лучше со ссылками в конце
+	content.Markdown = ReferenceStyleLinks(content.Markdown, referenceLinksMin)
+
+	// Изображения сохраняются рядом с markdown файлом в zip архиве
+	var assets []ImageAsset
+	if prefs.Images && format.Name() == "markdown" && config.Fetcher != nil {
+		assets = EmbedImages(fetchCtx, config.Fetcher, content, imageOptions)
+	}
+	if err := ctx.Err(); err != nil {
+		return tgbotapi.FileBytes{}, err
+	}
+
+	// Источник и имя файла - по канонической ссылке, а не по варианту из сообщения
+	sourceURL := content.SourceURL()
+
+	processCtx, cancelProcess := withStageTimeout(ctx, stageTimeouts.Process)
+	defer cancelProcess()
+
+	renderStart := time.Now()
+	data, err := format.Render(processCtx, content, sourceURL, locale)
+	observeStage(stageConversion, renderStart)
+	if err != nil {
+		return tgbotapi.FileBytes{}, err
+	}
+	if err := ctx.Err(); err != nil {
+		return tgbotapi.FileBytes{}, err
+	}
+
+	file := tgbotapi.FileBytes{
+		Name:  OutputFileName(sourceURL, content.Title, format),
+		Bytes: data,
+	}
+
+	if len(assets) == 0 {
+		return file, nil
+	}
+
+	files := []tgbotapi.FileBytes{file}
+	for _, asset := range assets {
+		files = append(files, tgbotapi.FileBytes{Name: asset.Path, Bytes: asset.Data})
+	}
+
+	archive, err := buildZip(files)
+	if err != nil {
+		return tgbotapi.FileBytes{}, err
+	}
+
+	return tgbotapi.FileBytes{
+		Name:  strings.TrimSuffix(file.Name, format.Extension()) + ".zip",
+		Bytes: archive,
+	}, nil
+}
+
+// userSettings возвращает настройки отправителя сообщения
+func userSettings(message *tgbotapi.Message) UserSettings {
+	return settings.Get(messageUserID(message))
+}
+
+// HandleFormatCommand обрабатывает команду /format, выбирающую формат файлов
+func HandleFormatCommand(bot *tgbotapi.BotAPI, message *tgbotapi.Message) {
+	locale := GetLocale(message)
+	name := strings.ToLower(strings.TrimSpace(message.CommandArguments()))
+
+	if _, ok := GetOutputFormat(name); !ok {
+		text := fmt.Sprintf(locale.FormatMsg, userSettings(message).Format)
+		bot.Send(tgbotapi.NewMessage(message.Chat.ID, text))
+		return
+	}
+
+	err := settings.Update(messageUserID(message), func(s *UserSettings) {
+		s.Format = name
+	})
+	if err != nil {
+		logger.Errorf("Ошибка при сохранении настроек: %v", err)
+		bot.Send(tgbotapi.NewMessage(message.Chat.ID, locale.ErrorSettingsMsg))
+		return
+	}
+
+	bot.Send(tgbotapi.NewMessage(message.Chat.ID, fmt.Sprintf(locale.FormatSetMsg, name)))
+}
+
+// HandleFrontMatterCommand обрабатывает команду /frontmatter, выбирающую
+// front matter для markdown файлов
+func HandleFrontMatterCommand(bot *tgbotapi.BotAPI, message *tgbotapi.Message) {
+	locale := GetLocale(message)
+	name := strings.ToLower(strings.TrimSpace(message.CommandArguments()))
+
+	if _, ok := GetFrontMatterTarget(name); !ok && name != "off" {
+		text := fmt.Sprintf(locale.FrontMatterMsg, frontMatterName(userSettings(message).FrontMatter))
+		bot.Send(tgbotapi.NewMessage(message.Chat.ID, text))
+		return
+	}
+
+	// Отключенный front matter хранится пустой строкой
+	target := name
+	if name == "off" {
+		target = ""
+	}
+
+	err := settings.Update(messageUserID(message), func(s *UserSettings) {
+		s.FrontMatter = target
+	})
+	if err != nil {
+		logger.Errorf("Ошибка при сохранении настроек: %v", err)
+		bot.Send(tgbotapi.NewMessage(message.Chat.ID, locale.ErrorSettingsMsg))
+		return
+	}
+
+	bot.Send(tgbotapi.NewMessage(message.Chat.ID, fmt.Sprintf(locale.FrontMatterSetMsg, name)))
+}
+
+// frontMatterName возвращает имя варианта front matter для ответа пользователю
+func frontMatterName(name string) string {
+	if name == "" {
+		return "off"
+	}
+	return name
+}
+
+// HandleTemplateCommand обрабатывает команду /template, выбирающую шаблон
+// оформления markdown файлов
+func HandleTemplateCommand(bot *tgbotapi.BotAPI, message *tgbotapi.Message) {
+	locale := GetLocale(message)
+	name := strings.ToLower(strings.TrimSpace(message.CommandArguments()))
+
+	if !markdownTemplates.Has(name) && name != "default" {
+		current := userSettings(message).Template
+		if !markdownTemplates.Has(current) {
+			current = "default"
+		}
+		available := strings.Join(append([]string{"default"}, markdownTemplates.Names()...), ", ")
+		bot.Send(tgbotapi.NewMessage(message.Chat.ID, fmt.Sprintf(locale.TemplateMsg, current, available)))
+		return
+	}
+
+	// Встроенный шаблон хранится пустой строкой
+	template := name
+	if name == "default" {
+		template = ""
+	}
+
+	err := settings.Update(messageUserID(message), func(s *UserSettings) {
+		s.Template = template
+	})
+	if err != nil {
+		logger.Errorf("Ошибка при сохранении настроек: %v", err)
+		bot.Send(tgbotapi.NewMessage(message.Chat.ID, locale.ErrorSettingsMsg))
+		return
+	}
+
+	bot.Send(tgbotapi.NewMessage(message.Chat.ID, fmt.Sprintf(locale.TemplateSetMsg, name)))
+}
+
+// HandleImagesCommand обрабатывает команду /images, включающую сохранение изображений
+func HandleImagesCommand(bot *tgbotapi.BotAPI, message *tgbotapi.Message) {
+	locale := GetLocale(message)
+
+	var enabled bool
+	switch strings.ToLower(strings.TrimSpace(message.CommandArguments())) {
+	case "on":
+		enabled = true
+	case "off":
+		enabled = false
+	default:
+		text := fmt.Sprintf(locale.ImagesMsg, onOff(userSettings(message).Images))
+		bot.Send(tgbotapi.NewMessage(message.Chat.ID, text))
+		return
+	}
+
+	err := settings.Update(messageUserID(message), func(s *UserSettings) {
+		s.Images = enabled
+	})
+	if err != nil {
+		logger.Errorf("Ошибка при сохранении настроек: %v", err)
+		bot.Send(tgbotapi.NewMessage(message.Chat.ID, locale.ErrorSettingsMsg))
+		return
+	}
+
+	bot.Send(tgbotapi.NewMessage(message.Chat.ID, fmt.Sprintf(locale.ImagesSetMsg, onOff(enabled))))
+}
+
+// onOff возвращает значение настройки в виде аргумента команды
+func onOff(enabled bool) string {
+	if enabled {
+		return "on"
+	}
+	return "off"
+}
+
+// errorMessageFor возвращает локализованное сообщение об ошибке извлечения
+func errorMessageFor(err error, locale Locale) string {
+	var unsupported *UnsupportedContentError
+	if errors.As(err, &unsupported) {
+		return unsupportedContentMessage(unsupported.ContentType, locale)
+	}
+
+	var tooLarge *BodyTooLargeError
+	if errors.As(err, &tooLarge) {
+		return fmt.Sprintf(locale.BodyTooLargeMsg, formatMegabytes(tooLarge.Limit))
+	}
+
+	if errors.Is(err, context.DeadlineExceeded) {
+		return locale.TimeoutMsg
+	}
+
+	var blocked *BlockedError
+	if !errors.As(err, &blocked) {
+		return locale.ErrorProcessingMsg
+	}
+
+	switch blocked.Kind {
+	case BlockNotWhitelisted:
+		return locale.BlockedDomainMsg
+	case BlockRobotsTxt:
+		return locale.BlockedRobotsMsg
+	case BlockForbidden:
+		return locale.BlockedAccessMsg
+	case BlockTooManyRequests:
+		return locale.BlockedRateLimitMsg
+	case BlockUnsafeURL:
+		return locale.BlockedUnsafeURLMsg
+	default:
+		return locale.ErrorProcessingMsg
+	}
+}
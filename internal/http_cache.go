@@ -0,0 +1,74 @@
+package internal
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// cachePolicy описывает, можно ли кэшировать ответ и до какого момента
+type cachePolicy struct {
+	Store     bool
+	ExpiresAt time.Time
+}
+
+// responseCachePolicy вычисляет политику кэширования по заголовкам ответа
+// (Cache-Control: no-store/no-cache/s-maxage/max-age, затем Expires)
+func responseCachePolicy(header http.Header, now time.Time, defaultTTL time.Duration) cachePolicy {
+	directives := parseCacheControl(header.Values("Cache-Control"))
+
+	if _, ok := directives["no-store"]; ok {
+		return cachePolicy{Store: false}
+	}
+
+	// no-cache разрешает хранить ответ, но требует подтверждения перед каждым использованием
+	if _, ok := directives["no-cache"]; ok {
+		return cachePolicy{Store: true, ExpiresAt: now}
+	}
+
+	for _, name := range []string{"s-maxage", "max-age"} {
+		if value, ok := directives[name]; ok {
+			if seconds, err := strconv.ParseInt(value, 10, 64); err == nil && seconds >= 0 {
+				return cachePolicy{Store: true, ExpiresAt: now.Add(time.Duration(seconds) * time.Second)}
+			}
+			// Некорректное значение означает, что ответ уже устарел
+			return cachePolicy{Store: true, ExpiresAt: now}
+		}
+	}
+
+	if expires := header.Get("Expires"); expires != "" {
+		expiresAt, err := http.ParseTime(expires)
+		if err != nil {
+			// Некорректный Expires означает, что ответ уже устарел
+			return cachePolicy{Store: true, ExpiresAt: now}
+		}
+		return cachePolicy{Store: true, ExpiresAt: expiresAt}
+	}
+
+	return cachePolicy{Store: true, ExpiresAt: now.Add(defaultTTL)}
+}
+
+// parseCacheControl разбирает директивы Cache-Control в словарь
+func parseCacheControl(values []string) map[string]string {
+	directives := make(map[string]string)
+
+	for _, value := range values {
+		for _, part := range strings.Split(value, ",") {
+			part = strings.TrimSpace(part)
+			if part == "" {
+				continue
+			}
+
+			name, arg, _ := strings.Cut(part, "=")
+			name = strings.ToLower(strings.TrimSpace(name))
+			arg = strings.Trim(strings.TrimSpace(arg), `"`)
+
+			if _, exists := directives[name]; !exists {
+				directives[name] = arg
+			}
+		}
+	}
+
+	return directives
+}
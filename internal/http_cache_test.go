@@ -0,0 +1,50 @@
+package internal
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestResponseCachePolicy(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	defaultTTL := 24 * time.Hour
+
+	tests := []struct {
+		name      string
+		headers   map[string]string
+		store     bool
+		expiresAt time.Time
+	}{
+		{"no headers", nil, true, now.Add(defaultTTL)},
+		{"no-store", map[string]string{"Cache-Control": "private, no-store"}, false, time.Time{}},
+		{"no-cache", map[string]string{"Cache-Control": "no-cache"}, true, now},
+		{"max-age", map[string]string{"Cache-Control": "public, max-age=600"}, true, now.Add(10 * time.Minute)},
+		{"s-maxage wins", map[string]string{"Cache-Control": "max-age=600, s-maxage=60"}, true, now.Add(time.Minute)},
+		{"invalid max-age", map[string]string{"Cache-Control": "max-age=abc"}, true, now},
+		{
+			"max-age over expires",
+			map[string]string{"Cache-Control": "max-age=60", "Expires": "Thu, 01 Jan 2026 00:00:00 GMT"},
+			true, now.Add(time.Minute),
+		},
+		{"expires", map[string]string{"Expires": "Wed, 01 Jan 2025 13:00:00 GMT"}, true, now.Add(time.Hour)},
+		{"invalid expires", map[string]string{"Expires": "0"}, true, now},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := http.Header{}
+			for name, value := range tt.headers {
+				header.Set(name, value)
+			}
+
+			policy := responseCachePolicy(header, now, defaultTTL)
+			if policy.Store != tt.store {
+				t.Errorf("Store = %v, want %v", policy.Store, tt.store)
+			}
+			if tt.store && !policy.ExpiresAt.Equal(tt.expiresAt) {
+				t.Errorf("ExpiresAt = %v, want %v", policy.ExpiresAt, tt.expiresAt)
+			}
+		})
+	}
+}
@@ -0,0 +1,297 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/sirupsen/logrus"
+)
+
+// ImageOptions ограничивает загрузку изображений статьи
+type ImageOptions struct {
+	MaxImageSize int64 // байт на одно изображение, 0 - без ограничения
+	MaxImages    int   // 0 - без ограничения
+	MaxTotalSize int64 // байт на все изображения статьи, 0 - без ограничения
+}
+
+// ImageAsset представляет загруженное изображение с локальным путем в архиве
+type ImageAsset struct {
+	Path string
+	Data []byte
+}
+
+// imageAssetsDir - каталог изображений рядом с markdown файлом
+const imageAssetsDir = "assets"
+
+// allowedImageTypes сопоставляет допустимые типы изображений с расширениями файлов.
+// SVG не встраивается: он может содержать скрипты и внешние ссылки
+var allowedImageTypes = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/gif":  ".gif",
+	"image/webp": ".webp",
+	"image/avif": ".avif",
+}
+
+// imageContentTypes - типы содержимого, которые загрузчик пропускает для изображений;
+// конкретный формат проверяется по allowedImageTypes
+var imageContentTypes = []string{"image/*"}
+
+// lazyImageAttrs - атрибуты, в которых сайты хранят адрес изображения при ленивой загрузке
+var lazyImageAttrs = []string{"data-src", "data-original", "data-lazy-src", "data-url"}
+
+// markdownImagePattern находит изображения ![alt](src "title") и ![alt](<src> "title")
+// в markdown; адрес без угловых скобок может содержать парные скобки
+var markdownImagePattern = regexp.MustCompile(`!\[([^\]]*)\]\(\s*(?:<([^<>\n]+)>|((?:[^()\s<>]|\([^()\s]*\))+))(\s+"[^"]*")?\s*\)`)
+
+// unsafeAssetChars - символы, недопустимые в имени файла изображения
+var unsafeAssetChars = regexp.MustCompile(`[^a-zA-Z0-9_-]+`)
+
+// resolveImageSources выбирает для каждого изображения лучший адрес
+// (srcset, src или атрибуты ленивой загрузки) и делает его абсолютным
+func resolveImageSources(htmlContent string, base *url.URL) string {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(htmlContent))
+	if err != nil {
+		return htmlContent
+	}
+
+	doc.Find("img").Each(func(i int, img *goquery.Selection) {
+		src := bestImageSource(img)
+		if src == "" {
+			return
+		}
+
+		if resolved := resolveReference(base, src); resolved != "" {
+			img.SetAttr("src", resolved)
+		}
+		img.RemoveAttr("srcset")
+	})
+
+	result, err := doc.Find("body").Html()
+	if err != nil {
+		return htmlContent
+	}
+	return result
+}
+
+// bestImageSource возвращает адрес изображения наибольшего размера
+func bestImageSource(img *goquery.Selection) string {
+	for _, attr := range []string{"srcset", "data-srcset"} {
+		if srcset, ok := img.Attr(attr); ok {
+			if src := largestSrcsetCandidate(srcset); src != "" {
+				return src
+			}
+		}
+	}
+
+	src, _ := img.Attr("src")
+	src = strings.TrimSpace(src)
+	if src != "" && !strings.HasPrefix(src, "data:") {
+		return src
+	}
+
+	for _, attr := range lazyImageAttrs {
+		if lazy, ok := img.Attr(attr); ok && strings.TrimSpace(lazy) != "" {
+			return strings.TrimSpace(lazy)
+		}
+	}
+
+	return src
+}
+
+// largestSrcsetCandidate выбирает из srcset вариант с наибольшей шириной или плотностью
+func largestSrcsetCandidate(srcset string) string {
+	var best string
+	var bestSize float64
+
+	for _, candidate := range strings.Split(srcset, ",") {
+		fields := strings.Fields(candidate)
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "data:") {
+			continue
+		}
+
+		size := 1.0
+		if len(fields) > 1 {
+			descriptor := fields[1]
+			if value, err := strconv.ParseFloat(descriptor[:len(descriptor)-1], 64); err == nil {
+				size = value
+			}
+		}
+
+		if best == "" || size > bestSize {
+			best = fields[0]
+			bestSize = size
+		}
+	}
+
+	return best
+}
+
+// resolveReference делает ссылку абсолютной относительно базового URL
+func resolveReference(base *url.URL, ref string) string {
+	parsed, err := url.Parse(strings.TrimSpace(ref))
+	if err != nil {
+		return ""
+	}
+	if base == nil {
+		return parsed.String()
+	}
+	return base.ResolveReference(parsed).String()
+}
+
+// EmbedImages загружает изображения статьи через fetcher и заменяет ссылки
+// в markdown на локальные пути в каталоге assets/. Изображения, которые не удалось
+// загрузить или которые превышают ограничения, остаются внешними ссылками
+func EmbedImages(ctx context.Context, f Fetcher, content *Content, opts ImageOptions) []ImageAsset {
+	base, _ := url.Parse(content.URL)
+
+	var assets []ImageAsset
+	var total int64
+	local := make(map[string]string)
+
+	content.Markdown = markdownImagePattern.ReplaceAllStringFunc(content.Markdown, func(match string) string {
+		parts := markdownImagePattern.FindStringSubmatch(match)
+		alt, title := parts[1], parts[4]
+
+		ref := parts[2]
+		if ref == "" {
+			ref = parts[3]
+		}
+		src := resolveReference(base, ref)
+		if !isHTTPURL(src) {
+			return match
+		}
+
+		assetPath, seen := local[src]
+		if !seen {
+			local[src] = ""
+
+			if opts.MaxImages > 0 && len(assets) >= opts.MaxImages {
+				logrus.Debugf("Превышено число изображений, оставляем ссылку %s", src)
+				return markdownImage(alt, src, title)
+			}
+
+			data, ext, err := downloadImage(ctx, f, src, content.URL, opts.MaxImageSize)
+			if err != nil {
+				logrus.Warnf("Не удалось загрузить изображение %s: %v", src, err)
+				return markdownImage(alt, src, title)
+			}
+
+			if opts.MaxTotalSize > 0 && total+int64(len(data)) > opts.MaxTotalSize {
+				logrus.Warnf("Превышен общий объем изображений, оставляем ссылку %s", src)
+				return markdownImage(alt, src, title)
+			}
+
+			assetPath = fmt.Sprintf("%s/%02d-%s%s", imageAssetsDir, len(assets)+1, imageBaseName(src), ext)
+			assets = append(assets, ImageAsset{Path: assetPath, Data: data})
+			total += int64(len(data))
+			local[src] = assetPath
+		}
+
+		if assetPath == "" {
+			return markdownImage(alt, src, title)
+		}
+		return markdownImage(alt, assetPath, title)
+	})
+
+	return assets
+}
+
+// markdownImage форматирует изображение markdown; адрес со скобками или
+// пробелами заключается в угловые скобки
+func markdownImage(alt, src, title string) string {
+	if strings.ContainsAny(src, "() ") {
+		src = "<" + src + ">"
+	}
+	return fmt.Sprintf("![%s](%s%s)", alt, src, title)
+}
+
+// downloadImage загружает изображение и проверяет его размер и тип.
+// Ограничение размера передается загрузчику, чтобы большое изображение
+// не скачивалось целиком
+func downloadImage(ctx context.Context, f Fetcher, src, referer string, maxSize int64) ([]byte, string, error) {
+	limit := pageLimits.MaxBodySize
+	if maxSize > 0 && (limit <= 0 || maxSize < limit) {
+		limit = maxSize
+	}
+
+	// Некоторые сайты отдают изображения только со страницы статьи
+	ctx = WithReferer(ctx, referer)
+	ctx = withPageLimits(ctx, PageLimits{MaxBodySize: limit, ContentTypes: imageContentTypes})
+
+	result, err := f.Fetch(ctx, src)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if result.StatusCode != 0 && result.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("статус ответа: %d", result.StatusCode)
+	}
+
+	if len(result.Body) == 0 {
+		return nil, "", fmt.Errorf("пустой ответ")
+	}
+
+	if maxSize > 0 && int64(len(result.Body)) > maxSize {
+		return nil, "", fmt.Errorf("размер %d байт превышает ограничение %d байт", len(result.Body), maxSize)
+	}
+
+	ext, err := imageExtension(result.Headers.Get("Content-Type"), result.Body)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return result.Body, ext, nil
+}
+
+// imageExtension проверяет тип изображения по содержимому. Заголовку
+// Content-Type доверяем, только если формат не распознан по сигнатуре (например, AVIF)
+func imageExtension(contentType string, body []byte) (string, error) {
+	sniffed := http.DetectContentType(body)
+
+	// Страница ошибки вместо изображения
+	if strings.HasPrefix(sniffed, "text/html") {
+		return "", fmt.Errorf("вместо изображения получена HTML страница")
+	}
+
+	if ext, ok := allowedImageTypes[sniffed]; ok {
+		return ext, nil
+	}
+	if sniffed != "application/octet-stream" {
+		return "", fmt.Errorf("недопустимый тип содержимого %q", sniffed)
+	}
+
+	if mediaType, _, err := mime.ParseMediaType(contentType); err == nil {
+		if ext, ok := allowedImageTypes[strings.ToLower(mediaType)]; ok {
+			return ext, nil
+		}
+	}
+
+	return "", fmt.Errorf("недопустимый тип содержимого %q", contentType)
+}
+
+// imageBaseName возвращает безопасное имя файла изображения из URL
+func imageBaseName(src string) string {
+	name := "image"
+	if u, err := url.Parse(src); err == nil {
+		base := path.Base(u.Path)
+		base = strings.TrimSuffix(base, path.Ext(base))
+		base = strings.Trim(unsafeAssetChars.ReplaceAllString(base, "_"), "_")
+		if base != "" {
+			name = base
+		}
+	}
+
+	if len(name) > 40 {
+		name = name[:40]
+	}
+	return name
+}
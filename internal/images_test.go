@@ -0,0 +1,257 @@
+package internal
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// pngHeader - сигнатура PNG, по которой определяется тип содержимого
+var pngHeader = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+
+// imageFetcher отдает заранее заданные ответы по URL и запоминает Referer
+type imageFetcher struct {
+	responses map[string]*FetchResult
+	referers  []string
+}
+
+func (f *imageFetcher) Fetch(ctx context.Context, pageURL string) (*FetchResult, error) {
+	f.referers = append(f.referers, refererFromContext(ctx))
+
+	result, ok := f.responses[pageURL]
+	if !ok {
+		return nil, fmt.Errorf("статус ответа: 404")
+	}
+	return result, nil
+}
+
+func imageResult(contentType string, body []byte) *FetchResult {
+	return &FetchResult{
+		Body:       body,
+		StatusCode: http.StatusOK,
+		Headers:    http.Header{"Content-Type": []string{contentType}},
+	}
+}
+
+func TestResolveImageSources(t *testing.T) {
+	base, _ := url.Parse("https://example.com/blog/post.html")
+	htmlContent := `<p><img src="small.jpg" srcset="small.jpg 400w, /img/large.jpg 1200w, medium.jpg 800w" alt="a"></p>
+<p><img src="data:image/gif;base64,R0lGOD" data-src="../lazy.png" alt="b"></p>
+<p><img src="https://cdn.example.org/abs.webp" alt="c"></p>`
+
+	result := resolveImageSources(htmlContent, base)
+
+	for _, expected := range []string{
+		`src="https://example.com/img/large.jpg"`,
+		`src="https://example.com/lazy.png"`,
+		`src="https://cdn.example.org/abs.webp"`,
+	} {
+		if !strings.Contains(result, expected) {
+			t.Errorf("Результат должен содержать %s:\n%s", expected, result)
+		}
+	}
+
+	if strings.Contains(result, "srcset") {
+		t.Error("srcset должен быть удален после выбора адреса")
+	}
+}
+
+func TestLargestSrcsetCandidate(t *testing.T) {
+	tests := []struct {
+		srcset   string
+		expected string
+	}{
+		{"a.jpg 1x, b.jpg 2x", "b.jpg"},
+		{"a.jpg 800w, b.jpg 400w", "a.jpg"},
+		{"only.jpg", "only.jpg"},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		if got := largestSrcsetCandidate(tt.srcset); got != tt.expected {
+			t.Errorf("Для %q ожидалось %q, получено %q", tt.srcset, tt.expected, got)
+		}
+	}
+}
+
+func TestEmbedImages(t *testing.T) {
+	png := append(append([]byte{}, pngHeader...), make([]byte, 100)...)
+
+	f := &imageFetcher{responses: map[string]*FetchResult{
+		"https://example.com/img/photo.png": imageResult("image/png", png),
+		"https://example.com/page.html":     imageResult("text/html", []byte("<html><body>404</body></html>")),
+		"https://example.com/big.png":       imageResult("image/png", append(png, make([]byte, 1000)...)),
+		"https://example.com/no-type":       imageResult("application/octet-stream", png),
+	}}
+
+	content := &Content{
+		URL: "https://example.com/blog/post",
+		Markdown: `![Фото](/img/photo.png "Подпись")
+
+Повтор: ![Фото](https://example.com/img/photo.png)
+
+![Ошибка](https://example.com/page.html)
+
+![Большое](https://example.com/big.png)
+
+![Без типа](https://example.com/no-type)
+
+![Нет](https://example.com/missing.png)`,
+	}
+
+	assets := EmbedImages(context.Background(), f, content, ImageOptions{MaxImageSize: 500})
+
+	if len(assets) != 2 {
+		t.Fatalf("Ожидалось 2 изображения, получено %d", len(assets))
+	}
+	if assets[0].Path != "assets/01-photo.png" || !bytes.Equal(assets[0].Data, png) {
+		t.Errorf("Неверное первое изображение: %s", assets[0].Path)
+	}
+	if assets[1].Path != "assets/02-no-type.png" {
+		t.Errorf("Тип изображения должен определяться по содержимому, получен путь %s", assets[1].Path)
+	}
+
+	for _, expected := range []string{
+		`![Фото](assets/01-photo.png "Подпись")`,
+		`Повтор: ![Фото](assets/01-photo.png)`,
+		`![Ошибка](https://example.com/page.html)`,
+		`![Большое](https://example.com/big.png)`,
+		`![Нет](https://example.com/missing.png)`,
+	} {
+		if !strings.Contains(content.Markdown, expected) {
+			t.Errorf("Markdown должен содержать %s:\n%s", expected, content.Markdown)
+		}
+	}
+
+	// Изображение загружается один раз, с Referer страницы статьи
+	if len(f.referers) != 5 {
+		t.Errorf("Ожидалось 5 загрузок, выполнено %d", len(f.referers))
+	}
+	for _, referer := range f.referers {
+		if referer != content.URL {
+			t.Errorf("Ожидался Referer %s, получен %s", content.URL, referer)
+		}
+	}
+}
+
+func TestEmbedImagesLimits(t *testing.T) {
+	png := append([]byte{}, pngHeader...)
+	f := &imageFetcher{responses: map[string]*FetchResult{
+		"https://example.com/1.png": imageResult("image/png", png),
+		"https://example.com/2.png": imageResult("image/png", png),
+		"https://example.com/3.png": imageResult("image/png", png),
+	}}
+
+	content := &Content{
+		URL:      "https://example.com/",
+		Markdown: "![](1.png) ![](2.png) ![](3.png)",
+	}
+
+	assets := EmbedImages(context.Background(), f, content, ImageOptions{MaxImages: 2})
+	if len(assets) != 2 {
+		t.Errorf("Ожидалось не более 2 изображений, получено %d", len(assets))
+	}
+	if !strings.Contains(content.Markdown, "![](https://example.com/3.png)") {
+		t.Errorf("Изображение сверх лимита должно остаться внешней ссылкой: %s", content.Markdown)
+	}
+
+	content.Markdown = "![](1.png) ![](2.png)"
+	assets = EmbedImages(context.Background(), f, content, ImageOptions{MaxTotalSize: int64(len(png)) + 1})
+	if len(assets) != 1 {
+		t.Errorf("Общий объем должен ограничивать число изображений, получено %d", len(assets))
+	}
+}
+
+func TestImageExtension(t *testing.T) {
+	if _, err := imageExtension("image/svg+xml; charset=utf-8", []byte("<svg></svg>")); err == nil {
+		t.Error("SVG может содержать скрипты и должен отклоняться")
+	}
+	if _, err := imageExtension("", []byte(`<?xml version="1.0"?><svg xmlns="http://www.w3.org/2000/svg"></svg>`)); err == nil {
+		t.Error("SVG без заголовка должен отклоняться")
+	}
+	if _, err := imageExtension("image/png", []byte("<!DOCTYPE html><html>")); err == nil {
+		t.Error("HTML страница с типом image/png должна отклоняться")
+	}
+	if _, err := imageExtension("application/pdf", []byte("%PDF-1.4")); err == nil {
+		t.Error("Тип application/pdf должен отклоняться")
+	}
+
+	// Расширение определяется по содержимому, а не по заголовку
+	if ext, err := imageExtension("image/jpeg", pngHeader); err != nil || ext != ".png" {
+		t.Errorf("PNG с заголовком image/jpeg должен сохраняться как .png, получено %q, %v", ext, err)
+	}
+	if _, err := imageExtension("image/png", []byte("not an image")); err == nil {
+		t.Error("Текст с заголовком image/png должен отклоняться")
+	}
+	if _, err := imageExtension("image/png", []byte("%PDF-1.4")); err == nil {
+		t.Error("PDF с заголовком image/png должен отклоняться")
+	}
+
+	// AVIF не распознается по сигнатуре, тип берется из заголовка
+	avif := []byte("\x00\x00\x00\x1cftypavif\x00\x00\x00\x00")
+	if ext, err := imageExtension("image/avif", avif); err != nil || ext != ".avif" {
+		t.Errorf("AVIF должен определяться по заголовку, получено %q, %v", ext, err)
+	}
+	if _, err := imageExtension("application/octet-stream", avif); err == nil {
+		t.Error("Нераспознанное содержимое без типа изображения должно отклоняться")
+	}
+}
+
+func TestEmbedImagesParenthesesInURL(t *testing.T) {
+	png := append([]byte{}, pngHeader...)
+	f := &imageFetcher{responses: map[string]*FetchResult{
+		"https://example.com/wiki/File_(1).png": imageResult("image/png", png),
+		"https://example.com/img/a%20b.png":     imageResult("image/png", png),
+	}}
+
+	content := &Content{
+		URL: "https://example.com/",
+		Markdown: `![Вики](https://example.com/wiki/File_(1).png "Подпись")
+
+![Пробел](<img/a b.png>)
+
+![Внешнее](https://example.com/missing_(2).png)`,
+	}
+
+	assets := EmbedImages(context.Background(), f, content, ImageOptions{})
+	if len(assets) != 2 {
+		t.Fatalf("Ожидалось 2 изображения, получено %d", len(assets))
+	}
+
+	for _, expected := range []string{
+		`![Вики](assets/01-File__1.png "Подпись")`,
+		`![Пробел](assets/02-a_b.png)`,
+		`![Внешнее](<https://example.com/missing_(2).png>)`,
+	} {
+		if !strings.Contains(content.Markdown, expected) {
+			t.Errorf("Markdown должен содержать %s:\n%s", expected, content.Markdown)
+		}
+	}
+}
+
+func TestDownloadImageSizeLimitPassedToFetcher(t *testing.T) {
+	large := append(append([]byte{}, pngHeader...), bytes.Repeat([]byte{0}, 2048)...)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		// Без Content-Length размер проверяется при чтении
+		w.(http.Flusher).Flush()
+		w.Write(large)
+	}))
+	defer server.Close()
+
+	SetNetworkPolicy(NewNetworkPolicy(true, nil))
+	defer SetNetworkPolicy(NewNetworkPolicy(false, DefaultAllowedPorts))
+
+	_, _, err := downloadImage(context.Background(), NewHTTPFetcher(5*time.Second), server.URL+"/big.png", server.URL, 1024)
+	var tooLarge *BodyTooLargeError
+	if !errors.As(err, &tooLarge) || tooLarge.Limit != 1024 {
+		t.Errorf("Изображение должно отклоняться при чтении по своему ограничению, получено %v", err)
+	}
+}
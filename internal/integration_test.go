@@ -1,6 +1,7 @@
 package internal
 
 import (
+	"context"
 	"strings"
 	"testing"
 )
@@ -16,7 +17,7 @@ func TestLanguageDetectionIntegration(t *testing.T) {
 	}
 
 	locale := locales["en"]
-	result := ConvertToMarkdown(content, "https://example.com/test", locale)
+	result := ConvertToMarkdown(context.Background(), content, "https://example.com/test", locale)
 
 	// Проверяем, что блоки кода присутствуют (языки могут быть определены или нет)
 	if !strings.Contains(result, "```") {
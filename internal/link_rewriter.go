@@ -0,0 +1,252 @@
+package internal
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// trackingParams - параметры запроса, которые используются только для отслеживания
+var trackingParams = map[string]bool{
+	"fbclid":  true,
+	"gclid":   true,
+	"dclid":   true,
+	"msclkid": true,
+	"yclid":   true,
+	"igshid":  true,
+	"mc_cid":  true,
+	"mc_eid":  true,
+}
+
+// markdownLinkPattern находит ссылки [текст](адрес "заголовок") и изображения ![alt](адрес);
+// текст может содержать вложенное изображение, адрес - одну пару скобок
+var markdownLinkPattern = regexp.MustCompile(`(!?)\[((?:[^\[\]]|\[[^\[\]]*\])*)\]\(\s*<?((?:[^()\s<>]|\([^()\s]*\))+)>?(\s+"[^"]*")?\s*\)`)
+
+// markdownAutolinkPattern находит ссылки вида <https://example.com>
+var markdownAutolinkPattern = regexp.MustCompile(`<(https?://[^>\s]+)>`)
+
+// documentBaseURL возвращает адрес для разрешения относительных ссылок с учетом <base href>
+func documentBaseURL(doc *goquery.Document, pageURL *url.URL) *url.URL {
+	href, ok := doc.Find("base[href]").First().Attr("href")
+	if !ok || strings.TrimSpace(href) == "" {
+		return pageURL
+	}
+
+	ref, err := url.Parse(strings.TrimSpace(href))
+	if err != nil {
+		return pageURL
+	}
+
+	base := pageURL.ResolveReference(ref)
+	if base.Scheme != "http" && base.Scheme != "https" {
+		return pageURL
+	}
+	return base
+}
+
+// RewriteLinks делает ссылки и изображения в markdown абсолютными
+// и убирает из них параметры отслеживания. Блоки кода не изменяются
+func RewriteLinks(markdown string, base *url.URL) string {
+	return mapMarkdownText(markdown, func(text string) string {
+		text = markdownLinkPattern.ReplaceAllStringFunc(text, func(match string) string {
+			parts := markdownLinkPattern.FindStringSubmatch(match)
+			target := rewriteLinkTarget(parts[3], base)
+			return fmt.Sprintf("%s[%s](%s%s)", parts[1], parts[2], target, parts[4])
+		})
+
+		return markdownAutolinkPattern.ReplaceAllStringFunc(text, func(match string) string {
+			target := markdownAutolinkPattern.FindStringSubmatch(match)[1]
+			return "<" + rewriteLinkTarget(target, base) + ">"
+		})
+	})
+}
+
+// rewriteLinkTarget разрешает адрес относительно base и очищает его
+func rewriteLinkTarget(target string, base *url.URL) string {
+	ref, err := url.Parse(target)
+	if err != nil {
+		return target
+	}
+
+	// Ссылки mailto:, tel: и подобные оставляем как есть
+	if ref.Scheme != "" && ref.Scheme != "http" && ref.Scheme != "https" {
+		return target
+	}
+
+	resolved := ref
+	if base != nil {
+		resolved = base.ResolveReference(ref)
+	}
+	StripTrackingParams(resolved)
+
+	return resolved.String()
+}
+
+// StripTrackingParams удаляет из URL параметры utm_*, fbclid, gclid и подобные,
+// сохраняя порядок и кодирование остальных параметров
+func StripTrackingParams(u *url.URL) {
+	if u.RawQuery == "" {
+		return
+	}
+
+	var kept []string
+	for _, pair := range strings.Split(u.RawQuery, "&") {
+		if pair == "" {
+			continue
+		}
+
+		key, _, _ := strings.Cut(pair, "=")
+		if name, err := url.QueryUnescape(key); err == nil {
+			key = name
+		}
+		key = strings.ToLower(key)
+
+		if strings.HasPrefix(key, "utm_") || trackingParams[key] {
+			continue
+		}
+		kept = append(kept, pair)
+	}
+
+	u.RawQuery = strings.Join(kept, "&")
+	u.ForceQuery = false
+}
+
+// ReferenceStyleLinks заменяет ссылки в абзацах, где их не меньше minLinks,
+// на ссылки в стиле [текст][n] с определениями после абзаца
+func ReferenceStyleLinks(markdown string, minLinks int) string {
+	if minLinks <= 0 {
+		return markdown
+	}
+
+	numbers := make(map[string]int)
+	var result []string
+
+	for _, block := range splitMarkdownBlocks(markdown) {
+		if block.code || countInlineLinks(block.text) < minLinks {
+			result = append(result, block.text)
+			continue
+		}
+
+		var definitions []string
+		text := mapInlineText(block.text, func(text string) string {
+			return markdownLinkPattern.ReplaceAllStringFunc(text, func(match string) string {
+				parts := markdownLinkPattern.FindStringSubmatch(match)
+				if parts[1] == "!" {
+					return match
+				}
+
+				key := parts[3] + parts[4]
+				number, exists := numbers[key]
+				if !exists {
+					number = len(numbers) + 1
+					numbers[key] = number
+					definitions = append(definitions, fmt.Sprintf("[%d]: %s%s", number, parts[3], parts[4]))
+				}
+				return fmt.Sprintf("[%s][%d]", parts[2], number)
+			})
+		})
+
+		if len(definitions) > 0 {
+			text += "\n\n" + strings.Join(definitions, "\n")
+		}
+		result = append(result, text)
+	}
+
+	return strings.Join(result, "\n\n")
+}
+
+// countInlineLinks считает ссылки (без изображений) в тексте вне кода
+func countInlineLinks(text string) int {
+	count := 0
+	mapInlineText(text, func(text string) string {
+		for _, parts := range markdownLinkPattern.FindAllStringSubmatch(text, -1) {
+			if parts[1] == "" {
+				count++
+			}
+		}
+		return text
+	})
+	return count
+}
+
+// markdownBlock представляет абзац markdown или блок кода
+type markdownBlock struct {
+	text string
+	code bool
+}
+
+// splitMarkdownBlocks разбивает markdown на блоки по пустым строкам,
+// не разрывая блоки кода
+func splitMarkdownBlocks(markdown string) []markdownBlock {
+	var blocks []markdownBlock
+	var current []string
+	inCode := false
+	codeBlock := false
+
+	flush := func() {
+		if len(current) > 0 {
+			blocks = append(blocks, markdownBlock{text: strings.Join(current, "\n"), code: codeBlock})
+			current = nil
+			codeBlock = false
+		}
+	}
+
+	for _, line := range strings.Split(markdown, "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			if !inCode {
+				flush()
+				codeBlock = true
+			}
+			inCode = !inCode
+			current = append(current, line)
+			if !inCode {
+				flush()
+			}
+			continue
+		}
+
+		if !inCode && strings.TrimSpace(line) == "" {
+			flush()
+			continue
+		}
+		current = append(current, line)
+	}
+	flush()
+
+	return blocks
+}
+
+// mapMarkdownText применяет fn к тексту markdown вне блоков и фрагментов кода
+func mapMarkdownText(markdown string, fn func(string) string) string {
+	lines := strings.Split(markdown, "\n")
+	inCode := false
+
+	for i, line := range lines {
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			inCode = !inCode
+			continue
+		}
+		if !inCode {
+			lines[i] = mapInlineText(line, fn)
+		}
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// mapInlineText применяет fn к частям строки вне `кода`
+func mapInlineText(text string, fn func(string) string) string {
+	parts := strings.Split(text, "`")
+	// Незакрытая обратная кавычка не начинает фрагмент кода
+	if len(parts)%2 == 0 {
+		return fn(text)
+	}
+
+	for i := 0; i < len(parts); i += 2 {
+		parts[i] = fn(parts[i])
+	}
+	return strings.Join(parts, "`")
+}
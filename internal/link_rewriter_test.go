@@ -0,0 +1,94 @@
+package internal
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+func TestRewriteLinks(t *testing.T) {
+	base, _ := url.Parse("https://example.com/blog/post/")
+
+	markdown := "См. [документацию](/docs/foo) и [соседнюю статью](../other \"Заголовок\").\n\n" +
+		"![Схема](../img.png)\n\n" +
+		"[Статья](https://news.example.org/a?id=5&utm_source=tg&fbclid=abc) и <https://example.org/?gclid=1>\n\n" +
+		"[Почта](mailto:me@example.com), [Go](https://en.wikipedia.org/wiki/Go_(language))\n\n" +
+		"```\n[код](/not/a/link)\n```\n\n" +
+		"Фрагмент `[код](/inline)` не меняется"
+
+	result := RewriteLinks(markdown, base)
+
+	for _, expected := range []string{
+		"[документацию](https://example.com/docs/foo)",
+		"[соседнюю статью](https://example.com/blog/other \"Заголовок\")",
+		"![Схема](https://example.com/blog/img.png)",
+		"[Статья](https://news.example.org/a?id=5)",
+		"<https://example.org/>",
+		"[Почта](mailto:me@example.com)",
+		"[Go](https://en.wikipedia.org/wiki/Go_(language))",
+		"[код](/not/a/link)",
+		"`[код](/inline)`",
+	} {
+		if !strings.Contains(result, expected) {
+			t.Errorf("Результат должен содержать %s:\n%s", expected, result)
+		}
+	}
+}
+
+func TestStripTrackingParams(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"https://example.com/?b=2&utm_medium=x&a=1", "https://example.com/?b=2&a=1"},
+		{"https://example.com/?UTM_Source=x&gclid=1", "https://example.com/"},
+		{"https://example.com/?q=a%20b&fbclid=z#part", "https://example.com/?q=a%20b#part"},
+		{"https://example.com/path", "https://example.com/path"},
+	}
+
+	for _, tt := range tests {
+		u, _ := url.Parse(tt.input)
+		StripTrackingParams(u)
+		if u.String() != tt.expected {
+			t.Errorf("Для %s ожидалось %s, получено %s", tt.input, tt.expected, u.String())
+		}
+	}
+}
+
+func TestDocumentBaseURL(t *testing.T) {
+	page, _ := url.Parse("https://example.com/a/b.html")
+
+	doc, _ := goquery.NewDocumentFromReader(strings.NewReader(`<html><head><base href="/static/"></head><body></body></html>`))
+	if base := documentBaseURL(doc, page); base.String() != "https://example.com/static/" {
+		t.Errorf("Ожидался адрес из <base href>, получен %s", base)
+	}
+
+	doc, _ = goquery.NewDocumentFromReader(strings.NewReader(`<html><head><base href="javascript:void(0)"></head></html>`))
+	if base := documentBaseURL(doc, page); base != page {
+		t.Errorf("Недопустимый <base href> должен игнорироваться, получен %s", base)
+	}
+}
+
+func TestReferenceStyleLinks(t *testing.T) {
+	markdown := "Ссылки: [один](https://a.example), [два](https://b.example \"B\") и [снова](https://a.example).\n\n" +
+		"Только [одна](https://c.example) ссылка и ![картинка](https://d.example/i.png).\n\n" +
+		"```\n[x](https://e.example) [y](https://f.example)\n```"
+
+	result := ReferenceStyleLinks(markdown, 2)
+
+	for _, expected := range []string{
+		"Ссылки: [один][1], [два][2] и [снова][1].\n\n[1]: https://a.example\n[2]: https://b.example \"B\"",
+		"Только [одна](https://c.example) ссылка и ![картинка](https://d.example/i.png).",
+		"[x](https://e.example) [y](https://f.example)",
+	} {
+		if !strings.Contains(result, expected) {
+			t.Errorf("Результат должен содержать %q:\n%s", expected, result)
+		}
+	}
+
+	if ReferenceStyleLinks(markdown, 0) != markdown {
+		t.Error("При minLinks = 0 markdown не должен меняться")
+	}
+}
@@ -15,6 +15,58 @@ type Locale struct {
 	ErrorSendingMsg       string
 	SuccessMessage        string
 	ServerOverloadMessage string
+	TimeoutMsg            string
+	SendTimeoutMsg        string
+
+	// Отмена обработки командой /cancel
+	CancelledMsg       string
+	NothingToCancelMsg string
+
+	// Причины отказа в загрузке страницы
+	BlockedDomainMsg    string
+	BlockedRobotsMsg    string
+	BlockedAccessMsg    string
+	BlockedRateLimitMsg string
+	BlockedUnsafeURLMsg string
+
+	// Неподдерживаемые ответы сайтов
+	UnsupportedPDFMsg     string
+	UnsupportedImageMsg   string
+	UnsupportedVideoMsg   string
+	UnsupportedAudioMsg   string
+	UnsupportedContentMsg string
+	BodyTooLargeMsg       string
+
+	// Ограничения для пользователей
+	UserRateLimitMsg string
+	DailyQuotaMsg    string
+	SecondsFormat    string
+	MinutesFormat    string
+	HoursFormat      string
+
+	// Обработка нескольких ссылок
+	BatchProcessingMessage string
+	BatchTooManyMsg        string
+	BatchFailedMsg         string
+	BatchModeMsg           string
+	BatchModeSetMsg        string
+	ErrorSettingsMsg       string
+
+	// Выбор формата файлов
+	FormatMsg    string
+	FormatSetMsg string
+
+	// Сохранение изображений
+	ImagesMsg    string
+	ImagesSetMsg string
+
+	// Front matter для markdown файлов
+	FrontMatterMsg    string
+	FrontMatterSetMsg string
+
+	// Шаблоны markdown файлов
+	TemplateMsg    string
+	TemplateSetMsg string
 
 	// Локализация для markdown файлов
 	MetadataSection string
@@ -39,13 +91,80 @@ var locales = map[string]Locale{
 - Блог-посты
 - Документация
 
-Просто отправьте ссылку!`,
+Просто отправьте ссылку! Можно отправить несколько ссылок в одном сообщении, команда /batch выбирает, прислать их отдельными файлами или zip архивом. Команда /format выбирает формат файлов: Markdown, HTML, EPUB или текст. Команда /cancel прерывает обработку отправленных ссылок.`,
 		ProcessingMessage:     "⏳ Обрабатываю ссылку...",
 		InvalidURLMessage:     "Пожалуйста, отправьте валидную ссылку на веб-страницу.",
 		ErrorProcessingMsg:    "❌ Не удалось обработать ссылку. Проверьте, что ссылка корректна и доступна.",
 		ErrorSendingMsg:       "❌ Не удалось отправить файл.",
 		SuccessMessage:        "✅ Файл успешно создан!",
 		ServerOverloadMessage: "⚠️ Сервер перегружен. Попробуйте позже.",
+		TimeoutMsg:            "⌛ Обработка ссылки заняла слишком много времени. Попробуйте позже.",
+		SendTimeoutMsg:        "⌛ Telegram не подтвердил отправку файла вовремя. Файл может прийти позже.",
+
+		CancelledMsg:       "🛑 Обработка ссылок отменена.",
+		NothingToCancelMsg: "Сейчас нет ссылок в обработке.",
+
+		BlockedDomainMsg:    "🚫 Этот сайт не входит в список разрешенных для обработки.",
+		BlockedRobotsMsg:    "🚫 Владелец сайта запретил автоматическую загрузку этой страницы (robots.txt).",
+		BlockedAccessMsg:    "🚫 Сайт отказал в доступе к странице.",
+		BlockedRateLimitMsg: "⏳ Сайт ограничивает частоту запросов. Попробуйте позже.",
+		BlockedUnsafeURLMsg: "🚫 Эту ссылку нельзя загрузить: поддерживаются только http и https адреса публичных сайтов.",
+
+		UnsupportedPDFMsg:     "📄 По ссылке PDF документ, а обработка PDF отключена на этом сервере.",
+		UnsupportedImageMsg:   "🖼 По ссылке изображение, а не веб-страница. Изображения не поддерживаются.",
+		UnsupportedVideoMsg:   "🎬 По ссылке видео, а не веб-страница. Видео не поддерживается.",
+		UnsupportedAudioMsg:   "🎵 По ссылке аудио, а не веб-страница. Аудио не поддерживается.",
+		UnsupportedContentMsg: "📎 По ссылке файл типа %s, а не веб-страница. Такие файлы не поддерживаются.",
+		BodyTooLargeMsg:       "📦 Страница слишком большая (больше %s МБ).",
+
+		UserRateLimitMsg: "⏳ Слишком много ссылок подряд. Попробуйте через %s.",
+		DailyQuotaMsg:    "🚫 Дневной лимит ссылок исчерпан. Попробуйте через %s.",
+		SecondsFormat:    "%d сек.",
+		MinutesFormat:    "%d мин.",
+		HoursFormat:      "%d ч %d мин.",
+
+		BatchProcessingMessage: "⏳ Обрабатываю ссылки (%d)...",
+		BatchTooManyMsg:        "⚠️ В сообщении слишком много ссылок, обработаю только первые %d.",
+		BatchFailedMsg:         "❌ Не удалось обработать ссылки:",
+		BatchModeMsg: `Режим отправки нескольких ссылок: %s
+
+/batch files — отдельный файл для каждой ссылки
+/batch zip — один zip архив`,
+		BatchModeSetMsg:  "✅ Режим отправки нескольких ссылок: %s",
+		ErrorSettingsMsg: "❌ Не удалось сохранить настройки.",
+
+		FormatMsg: `Формат файлов: %s
+
+/format markdown — Markdown
+/format html — HTML страница
+/format print — HTML для печати и сохранения в PDF
+/format epub — электронная книга EPUB
+/format text — простой текст`,
+		FormatSetMsg: "✅ Формат файлов: %s",
+
+		ImagesMsg: `Сохранение изображений: %s
+
+/images on — присылать markdown файл вместе с изображениями в zip архиве
+/images off — оставлять ссылки на изображения на сайте`,
+		ImagesSetMsg: "✅ Сохранение изображений: %s",
+
+		FrontMatterMsg: `Front matter в markdown файлах: %s
+
+/frontmatter yaml — метаданные в YAML
+/frontmatter toml — метаданные в TOML
+/frontmatter obsidian — свойства заметки Obsidian
+/frontmatter hugo — запись для Hugo
+/frontmatter jekyll — запись для Jekyll
+/frontmatter off — раздел с метаинформацией в тексте`,
+		FrontMatterSetMsg: "✅ Front matter в markdown файлах: %s",
+
+		TemplateMsg: `Шаблон markdown файлов: %s
+
+Доступные шаблоны: %s
+
+/template <имя> — выбрать шаблон
+/template default — стандартное оформление`,
+		TemplateSetMsg: "✅ Шаблон markdown файлов: %s",
 
 		// Локализация для markdown файлов
 		MetadataSection: "## Метаинформация",
@@ -67,13 +186,80 @@ Supported formats:
 - Blog posts
 - Documentation
 
-Just send a link!`,
+Just send a link! You can send several links in one message, the /batch command chooses whether to get separate files or a zip archive. The /format command chooses the file format: Markdown, HTML, EPUB or text. The /cancel command stops processing of the links you sent.`,
 		ProcessingMessage:     "⏳ Processing link...",
 		InvalidURLMessage:     "Please send a valid link to a web page.",
 		ErrorProcessingMsg:    "❌ Failed to process the link. Check that the link is correct and accessible.",
 		ErrorSendingMsg:       "❌ Failed to send the file.",
 		SuccessMessage:        "✅ File successfully created!",
 		ServerOverloadMessage: "⚠️ Server is overloaded. Please try again later.",
+		TimeoutMsg:            "⌛ Processing the link took too long. Please try again later.",
+		SendTimeoutMsg:        "⌛ Telegram did not confirm the file upload in time. The file may still arrive.",
+
+		CancelledMsg:       "🛑 Link processing cancelled.",
+		NothingToCancelMsg: "No links are being processed right now.",
+
+		BlockedDomainMsg:    "🚫 This site is not on the list of allowed sites.",
+		BlockedRobotsMsg:    "🚫 The site owner does not allow automated access to this page (robots.txt).",
+		BlockedAccessMsg:    "🚫 The site denied access to the page.",
+		BlockedRateLimitMsg: "⏳ The site is limiting request rate. Please try again later.",
+		BlockedUnsafeURLMsg: "🚫 This link cannot be loaded: only http and https links to public sites are supported.",
+
+		UnsupportedPDFMsg:     "📄 This link points to a PDF document, and PDF processing is disabled on this server.",
+		UnsupportedImageMsg:   "🖼 This link points to an image, not a web page. Images are not supported.",
+		UnsupportedVideoMsg:   "🎬 This link points to a video, not a web page. Video is not supported.",
+		UnsupportedAudioMsg:   "🎵 This link points to audio, not a web page. Audio is not supported.",
+		UnsupportedContentMsg: "📎 This link points to a %s file, not a web page. Such files are not supported.",
+		BodyTooLargeMsg:       "📦 The page is too large (over %s MB).",
+
+		UserRateLimitMsg: "⏳ Too many links in a row. Please try again in %s.",
+		DailyQuotaMsg:    "🚫 Daily link limit reached. Please try again in %s.",
+		SecondsFormat:    "%d s",
+		MinutesFormat:    "%d min",
+		HoursFormat:      "%d h %d min",
+
+		BatchProcessingMessage: "⏳ Processing links (%d)...",
+		BatchTooManyMsg:        "⚠️ The message has too many links, only the first %d will be processed.",
+		BatchFailedMsg:         "❌ Failed to process links:",
+		BatchModeMsg: `Mode for messages with several links: %s
+
+/batch files — a separate file for each link
+/batch zip — a single zip archive`,
+		BatchModeSetMsg:  "✅ Mode for messages with several links: %s",
+		ErrorSettingsMsg: "❌ Failed to save settings.",
+
+		FormatMsg: `File format: %s
+
+/format markdown — Markdown
+/format html — HTML page
+/format print — HTML for printing and saving as PDF
+/format epub — EPUB e-book
+/format text — plain text`,
+		FormatSetMsg: "✅ File format: %s",
+
+		ImagesMsg: `Saving images: %s
+
+/images on — send the markdown file with its images in a zip archive
+/images off — keep links to images on the site`,
+		ImagesSetMsg: "✅ Saving images: %s",
+
+		FrontMatterMsg: `Front matter in markdown files: %s
+
+/frontmatter yaml — metadata in YAML
+/frontmatter toml — metadata in TOML
+/frontmatter obsidian — Obsidian note properties
+/frontmatter hugo — Hugo post
+/frontmatter jekyll — Jekyll post
+/frontmatter off — metadata section in the text`,
+		FrontMatterSetMsg: "✅ Front matter in markdown files: %s",
+
+		TemplateMsg: `Markdown file template: %s
+
+Available templates: %s
+
+/template <name> — choose a template
+/template default — standard layout`,
+		TemplateSetMsg: "✅ Markdown file template: %s",
 
 		// Локализация для markdown файлов
 		MetadataSection: "## Metadata",
@@ -1,6 +1,7 @@
 package internal
 
 import (
+	"context"
 	"fmt"
 	"net/url"
 	"path"
@@ -9,8 +10,9 @@ import (
 	"time"
 )
 
-// convertToMarkdown конвертирует контент в markdown формат
-func ConvertToMarkdown(content *Content, originalURL string, locale Locale) string {
+// ConvertToMarkdown конвертирует контент в markdown формат. После отмены ctx
+// языки оставшихся блоков кода не определяются
+func ConvertToMarkdown(ctx context.Context, content *Content, originalURL string, locale Locale) string {
 	var markdown strings.Builder
 
 	// Заголовок
@@ -35,7 +37,7 @@ func ConvertToMarkdown(content *Content, originalURL string, locale Locale) stri
 	markdown.WriteString(fmt.Sprintf("%s\n\n", locale.ContentSection))
 
 	// Обрабатываем контент с детекцией языка для блоков кода
-	processedContent := processMarkdownWithLanguageDetection(content.Markdown)
+	processedContent := processMarkdownWithLanguageDetection(ctx, content.Markdown)
 	markdown.WriteString(processedContent)
 	markdown.WriteString("\n\n")
 
@@ -160,8 +162,9 @@ func GenerateFilename(originalURL string, title string) string {
 		return filename
 	}
 
-	// Если заголовка нет, используем URL как fallback
-	u, err := url.Parse(originalURL)
+	// Если заголовка нет, используем URL как fallback; ссылки-переадресации
+	// разворачиваем, чтобы имя файла соответствовало статье
+	u, err := url.Parse(NormalizeURL(originalURL))
 	if err != nil {
 		return fmt.Sprintf("article_%d.md", time.Now().Unix())
 	}
@@ -239,8 +242,10 @@ func cleanTitleForFilename(title string) string {
 	return title
 }
 
-// processMarkdownWithLanguageDetection обрабатывает Markdown и добавляет языки к блокам кода
-func processMarkdownWithLanguageDetection(markdown string) string {
+// processMarkdownWithLanguageDetection обрабатывает Markdown и добавляет языки к блокам кода.
+// Определение языка - самая долгая часть оформления, поэтому после отмены ctx
+// оставшиеся блоки остаются без языка
+func processMarkdownWithLanguageDetection(ctx context.Context, markdown string) string {
 	// Создаем детектор языка
 	detector := NewLanguageDetector()
 
@@ -256,6 +261,9 @@ func processMarkdownWithLanguageDetection(markdown string) string {
 	// Обрабатываем каждый блок кода
 	result := markdown
 	for _, match := range matches {
+		if ctx.Err() != nil {
+			break
+		}
 		if len(match) >= 3 {
 			originalBlock := match[0]
 			language := match[1]
@@ -280,11 +288,15 @@ func processMarkdownWithLanguageDetection(markdown string) string {
 
 			// Если уверенность достаточно высокая, добавляем язык
 			if score.Confidence > 0.3 && score.Language != "" {
+				codeLanguages.WithLabelValues(score.Language).Inc()
+
 				// Создаем новый блок с языком
 				newBlock := "```" + score.Language + "\n" + content + "\n```"
 
 				// Заменяем в результате
 				result = strings.Replace(result, originalBlock, newBlock, 1)
+			} else {
+				codeLanguages.WithLabelValues("unknown").Inc()
 			}
 		}
 	}
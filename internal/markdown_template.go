@@ -0,0 +1,175 @@
+package internal
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// markdownTemplateExt - расширение файлов шаблонов в каталоге шаблонов
+const markdownTemplateExt = ".tmpl"
+
+// templateNamePattern - допустимые имена шаблонов для команды /template
+var templateNamePattern = regexp.MustCompile(`^[a-z0-9_-]+$`)
+
+// MarkdownTemplateData - данные, которые получает шаблон markdown документа
+type MarkdownTemplateData struct {
+	Content *Content
+	Locale  Locale
+
+	URL         string    // ссылка, которую прислал пользователь
+	Domain      string    // домен источника
+	Body        string    // текст статьи с определенными языками блоков кода
+	Date        string    // дата публикации в формате ISO 8601, пустая если не разобрана
+	Processed   time.Time // время обработки
+	WordCount   int
+	ReadingTime int    // минут
+	FrontMatter string // front matter, если пользователь его выбрал
+}
+
+// templateFuncs - функции, доступные в шаблонах
+var templateFuncs = template.FuncMap{
+	"escape": escapeMarkdown,
+	"join":   strings.Join,
+	"upper":  strings.ToUpper,
+	"lower":  strings.ToLower,
+	"formatTime": func(layout string, t time.Time) string {
+		return t.Format(layout)
+	},
+}
+
+// MarkdownTemplates содержит проверенные шаблоны markdown документа
+type MarkdownTemplates struct {
+	templates map[string]*template.Template
+}
+
+// LoadMarkdownTemplates загружает шаблоны *.tmpl из каталога. Шаблон, который
+// не разбирается или завершается ошибкой на тестовых данных, пропускается
+func LoadMarkdownTemplates(dir string) (*MarkdownTemplates, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка чтения каталога шаблонов: %w", err)
+	}
+
+	set := &MarkdownTemplates{templates: make(map[string]*template.Template)}
+
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != markdownTemplateExt {
+			continue
+		}
+
+		name := strings.TrimSuffix(strings.TrimSuffix(entry.Name(), markdownTemplateExt), ".md")
+		name = strings.ToLower(name)
+		if !templateNamePattern.MatchString(name) || name == "default" {
+			logger.Warnf("Недопустимое имя шаблона %s, пропускаем", entry.Name())
+			continue
+		}
+
+		tmpl, err := parseMarkdownTemplate(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			logger.Warnf("Шаблон %s пропущен: %v", entry.Name(), err)
+			continue
+		}
+
+		set.templates[name] = tmpl
+	}
+
+	return set, nil
+}
+
+// parseMarkdownTemplate разбирает шаблон и проверяет его на тестовых данных
+func parseMarkdownTemplate(path string) (*template.Template, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка чтения шаблона: %w", err)
+	}
+
+	tmpl, err := template.New(filepath.Base(path)).Funcs(templateFuncs).Parse(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("ошибка разбора шаблона: %w", err)
+	}
+
+	sample := &Content{
+		Title:    "Title",
+		Markdown: "Text",
+		URL:      "https://example.com/article",
+		Author:   "Author",
+		Date:     "2024-01-02",
+		Language: "en",
+		Tags:     []string{"tag"},
+	}
+	if err := tmpl.Execute(&bytes.Buffer{}, newMarkdownTemplateData(context.Background(), sample, sample.URL, locales["en"], "", time.Now())); err != nil {
+		return nil, fmt.Errorf("ошибка выполнения шаблона: %w", err)
+	}
+
+	return tmpl, nil
+}
+
+// Names возвращает имена шаблонов по алфавиту
+func (t *MarkdownTemplates) Names() []string {
+	if t == nil {
+		return nil
+	}
+
+	names := make([]string, 0, len(t.templates))
+	for name := range t.templates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Has сообщает, загружен ли шаблон с таким именем
+func (t *MarkdownTemplates) Has(name string) bool {
+	if t == nil {
+		return false
+	}
+	_, ok := t.templates[name]
+	return ok
+}
+
+// Render создает markdown документ по шаблону
+func (t *MarkdownTemplates) Render(name string, data MarkdownTemplateData) (string, error) {
+	if !t.Has(name) {
+		return "", fmt.Errorf("шаблон %s не найден", name)
+	}
+
+	var buf bytes.Buffer
+	if err := t.templates[name].Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("ошибка выполнения шаблона %s: %w", name, err)
+	}
+	return buf.String(), nil
+}
+
+// newMarkdownTemplateData вычисляет поля, доступные в шаблоне
+func newMarkdownTemplateData(ctx context.Context, content *Content, originalURL string, locale Locale, frontMatter string, now time.Time) MarkdownTemplateData {
+	meta := newFrontMatterData(content, originalURL, now)
+
+	data := MarkdownTemplateData{
+		Content:     content,
+		Locale:      locale,
+		URL:         originalURL,
+		Domain:      getDomain(originalURL, locale),
+		Body:        processMarkdownWithLanguageDetection(ctx, content.Markdown),
+		Processed:   now,
+		WordCount:   meta.WordCount,
+		ReadingTime: meta.ReadingTime,
+	}
+
+	if meta.HasDate {
+		data.Date = string(meta.isoDate())
+	}
+
+	if target, ok := GetFrontMatterTarget(frontMatter); ok {
+		data.FrontMatter = RenderFrontMatter(content, originalURL, target, now)
+	}
+
+	return data
+}
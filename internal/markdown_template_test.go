@@ -0,0 +1,99 @@
+package internal
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeTemplates(t *testing.T, files map[string]string) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	for name, text := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(text), 0644); err != nil {
+			t.Fatalf("Ошибка записи шаблона: %v", err)
+		}
+	}
+	return dir
+}
+
+func TestLoadMarkdownTemplates(t *testing.T) {
+	dir := writeTemplates(t, map[string]string{
+		"simple.md.tmpl":  "# {{.Content.Title}}\n{{.Domain}} {{.Date}} {{.WordCount}}\n\n{{.Body}}",
+		"broken.tmpl":     "{{if .Content.Title}",
+		"bad-field.tmpl":  "{{.Content.Missing}}",
+		"Bad Name.tmpl":   "{{.URL}}",
+		"notes.txt":       "не шаблон",
+		"default.tmpl":    "{{.URL}}",
+		"with-funcs.tmpl": `{{escape .Content.Title}} {{join .Content.Tags ", "}} {{formatTime "2006" .Processed}}`,
+	})
+
+	templates, err := LoadMarkdownTemplates(dir)
+	if err != nil {
+		t.Fatalf("Ошибка загрузки шаблонов: %v", err)
+	}
+
+	names := strings.Join(templates.Names(), ",")
+	if names != "simple,with-funcs" {
+		t.Errorf("Должны загрузиться только корректные шаблоны, загружены: %s", names)
+	}
+
+	if _, err := LoadMarkdownTemplates(filepath.Join(dir, "missing")); err == nil {
+		t.Error("Отсутствующий каталог должен возвращать ошибку")
+	}
+}
+
+func TestMarkdownFormatTemplate(t *testing.T) {
+	dir := writeTemplates(t, map[string]string{
+		"simple.tmpl": "{{.FrontMatter}}# {{.Content.Title}} ({{.Domain}}, {{.Date}})\n\n{{.Body}}",
+		"fails.tmpl":  `{{index .Content.Tags 0}}`,
+	})
+
+	templates, err := LoadMarkdownTemplates(dir)
+	if err != nil {
+		t.Fatalf("Ошибка загрузки шаблонов: %v", err)
+	}
+
+	previous := markdownTemplates
+	SetMarkdownTemplates(templates)
+	defer SetMarkdownTemplates(previous)
+
+	content := &Content{Title: "Статья", Date: "2024-03-15", Markdown: "Текст статьи"}
+
+	data, err := markdownFormat{template: "simple", frontMatter: "yaml"}.Render(context.Background(), content, "https://example.com/a", locales["ru"])
+	if err != nil {
+		t.Fatalf("Ошибка создания markdown: %v", err)
+	}
+	result := string(data)
+	if !strings.HasPrefix(result, "---\n") || !strings.Contains(result, "# Статья (example.com, 2024-03-15)\n\nТекст статьи") {
+		t.Errorf("Документ должен быть оформлен по шаблону:\n%s", result)
+	}
+
+	// Шаблон падает на статье без тегов - используется стандартное оформление
+	data, err = markdownFormat{template: "fails"}.Render(context.Background(), content, "https://example.com/a", locales["ru"])
+	if err != nil {
+		t.Fatalf("Ошибка создания markdown: %v", err)
+	}
+	if !strings.Contains(string(data), locales["ru"].MetadataSection) {
+		t.Errorf("При ошибке шаблона должно использоваться стандартное оформление:\n%s", data)
+	}
+
+	// Шаблон, которого больше нет, тоже не мешает созданию файла
+	data, _ = markdownFormat{template: "removed"}.Render(context.Background(), content, "https://example.com/a", locales["ru"])
+	if !strings.Contains(string(data), locales["ru"].FooterText) {
+		t.Error("Для неизвестного шаблона должно использоваться стандартное оформление")
+	}
+}
+
+func TestExampleTemplates(t *testing.T) {
+	templates, err := LoadMarkdownTemplates("../templates")
+	if err != nil {
+		t.Fatalf("Ошибка загрузки шаблонов из примера: %v", err)
+	}
+	if !templates.Has("compact") {
+		t.Error("Шаблон из примера должен проходить проверку")
+	}
+}
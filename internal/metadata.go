@@ -0,0 +1,425 @@
+package internal
+
+import (
+	"encoding/json"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// PageMetadata содержит метаданные страницы из JSON-LD, OpenGraph, Twitter Cards,
+// Dublin Core и микроданных
+type PageMetadata struct {
+	Title        string
+	Description  string
+	Author       string
+	SiteName     string
+	CanonicalURL string
+	AMPURL       string
+	Image        string
+	Language     string
+	Tags         []string
+	Published    time.Time
+	Modified     time.Time
+}
+
+// articleTypes - типы schema.org, описывающие статью
+var articleTypes = map[string]bool{
+	"Article":            true,
+	"NewsArticle":        true,
+	"BlogPosting":        true,
+	"TechArticle":        true,
+	"Report":             true,
+	"SocialMediaPosting": true,
+}
+
+// extractMetadata собирает метаданные страницы. Источники перечислены по убыванию
+// надежности: значение берется из первого источника, где оно есть, теги объединяются
+func extractMetadata(doc *goquery.Document, base *url.URL) PageMetadata {
+	meta := metaValues(doc)
+
+	sources := []PageMetadata{
+		jsonLDMetadata(doc),
+		openGraphMetadata(meta),
+		twitterMetadata(meta),
+		dublinCoreMetadata(meta),
+		microdataMetadata(doc),
+		htmlMetadata(doc, meta),
+	}
+
+	var result PageMetadata
+	for _, source := range sources {
+		result.merge(source)
+	}
+
+	result.Author = strings.Join(strings.Fields(result.Author), " ")
+	result.Language = normalizeLanguage(result.Language)
+	result.CanonicalURL = resolveMetadataURL(base, result.CanonicalURL)
+	result.AMPURL = resolveMetadataURL(base, result.AMPURL)
+	result.Image = resolveMetadataURL(base, result.Image)
+
+	return result
+}
+
+// merge заполняет пустые поля значениями из other
+func (m *PageMetadata) merge(other PageMetadata) {
+	fill := func(field *string, value string) {
+		if *field == "" {
+			*field = strings.TrimSpace(value)
+		}
+	}
+
+	fill(&m.Title, other.Title)
+	fill(&m.Description, other.Description)
+	fill(&m.Author, other.Author)
+	fill(&m.SiteName, other.SiteName)
+	fill(&m.CanonicalURL, other.CanonicalURL)
+	fill(&m.AMPURL, other.AMPURL)
+	fill(&m.Image, other.Image)
+	fill(&m.Language, other.Language)
+
+	if m.Published.IsZero() {
+		m.Published = other.Published
+	}
+	if m.Modified.IsZero() {
+		m.Modified = other.Modified
+	}
+
+	m.Tags = mergeTags(m.Tags, other.Tags)
+}
+
+// mergeTags объединяет теги без повторов с учетом регистра, не больше maxTags
+func mergeTags(tags []string, more []string) []string {
+	seen := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		seen[strings.ToLower(tag)] = true
+	}
+
+	for _, tag := range more {
+		tag = strings.Join(strings.Fields(tag), " ")
+		key := strings.ToLower(tag)
+		if tag == "" || seen[key] || len(tags) >= maxTags {
+			continue
+		}
+		seen[key] = true
+		tags = append(tags, tag)
+	}
+
+	return tags
+}
+
+// maxTags ограничивает число тегов статьи
+const maxTags = 20
+
+// metaValues собирает значения метатегов по name и property в нижнем регистре
+func metaValues(doc *goquery.Document) map[string][]string {
+	values := make(map[string][]string)
+
+	doc.Find("meta[content]").Each(func(i int, s *goquery.Selection) {
+		content := strings.TrimSpace(s.AttrOr("content", ""))
+		if content == "" {
+			return
+		}
+		for _, attr := range []string{"name", "property", "http-equiv"} {
+			if key, ok := s.Attr(attr); ok {
+				key = strings.ToLower(strings.TrimSpace(key))
+				values[key] = append(values[key], content)
+			}
+		}
+	})
+
+	return values
+}
+
+// firstMeta возвращает первое значение метатега из перечисленных
+func firstMeta(meta map[string][]string, keys ...string) string {
+	for _, key := range keys {
+		if values := meta[key]; len(values) > 0 {
+			return values[0]
+		}
+	}
+	return ""
+}
+
+// normalizeLanguage приводит код языка к виду BCP 47: ru_RU -> ru-RU
+func normalizeLanguage(lang string) string {
+	lang = strings.TrimSpace(strings.Split(lang, ",")[0])
+	return strings.ReplaceAll(lang, "_", "-")
+}
+
+// parseMetadataDate разбирает дату из метаданных, нулевое время - дата не разобрана
+func parseMetadataDate(raw string) time.Time {
+	date, _, ok := normalizeDate(raw)
+	if !ok {
+		return time.Time{}
+	}
+	return date
+}
+
+// splitKeywords разбивает список ключевых слов через запятую
+func splitKeywords(keywords string) []string {
+	return strings.Split(keywords, ",")
+}
+
+// openGraphMetadata извлекает метаданные OpenGraph и article:*
+func openGraphMetadata(meta map[string][]string) PageMetadata {
+	author := firstMeta(meta, "article:author")
+	// article:author часто содержит ссылку на профиль, а не имя
+	if isHTTPURL(author) {
+		author = ""
+	}
+
+	return PageMetadata{
+		Title:        firstMeta(meta, "og:title"),
+		Description:  firstMeta(meta, "og:description"),
+		Author:       author,
+		SiteName:     firstMeta(meta, "og:site_name"),
+		CanonicalURL: firstMeta(meta, "og:url"),
+		Image:        firstMeta(meta, "og:image:secure_url", "og:image", "og:image:url"),
+		Language:     firstMeta(meta, "og:locale"),
+		Tags:         meta["article:tag"],
+		Published:    parseMetadataDate(firstMeta(meta, "article:published_time", "og:published_time")),
+		Modified:     parseMetadataDate(firstMeta(meta, "article:modified_time", "og:updated_time")),
+	}
+}
+
+// twitterMetadata извлекает метаданные Twitter Cards
+func twitterMetadata(meta map[string][]string) PageMetadata {
+	return PageMetadata{
+		Title:       firstMeta(meta, "twitter:title"),
+		Description: firstMeta(meta, "twitter:description"),
+		Image:       firstMeta(meta, "twitter:image", "twitter:image:src"),
+	}
+}
+
+// dublinCoreMetadata извлекает метаданные Dublin Core (DC.* и DCTERMS.*)
+func dublinCoreMetadata(meta map[string][]string) PageMetadata {
+	var tags []string
+	for _, subject := range append(meta["dc.subject"], meta["dcterms.subject"]...) {
+		tags = append(tags, splitKeywords(subject)...)
+	}
+
+	return PageMetadata{
+		Title:       firstMeta(meta, "dc.title", "dcterms.title"),
+		Description: firstMeta(meta, "dc.description", "dcterms.description", "dcterms.abstract"),
+		Author:      firstMeta(meta, "dc.creator", "dcterms.creator"),
+		SiteName:    firstMeta(meta, "dc.publisher", "dcterms.publisher"),
+		Language:    firstMeta(meta, "dc.language", "dcterms.language"),
+		Tags:        tags,
+		Published:   parseMetadataDate(firstMeta(meta, "dcterms.issued", "dc.date.issued", "dc.date", "dcterms.created", "dcterms.date")),
+		Modified:    parseMetadataDate(firstMeta(meta, "dcterms.modified", "dc.date.modified")),
+	}
+}
+
+// microdataMetadata извлекает микроданные schema.org из атрибутов itemprop
+func microdataMetadata(doc *goquery.Document) PageMetadata {
+	prop := func(name string) *goquery.Selection {
+		return doc.Find("[itemprop='" + name + "']").First()
+	}
+
+	// Автор и издатель могут быть вложенными объектами с собственным itemprop=name
+	nested := func(name string) string {
+		s := prop(name)
+		if s.Length() == 0 {
+			return ""
+		}
+		if _, scoped := s.Attr("itemscope"); scoped {
+			return itempropValue(s.Find("[itemprop='name']").First())
+		}
+		return itempropValue(s)
+	}
+
+	image := prop("image")
+	if _, scoped := image.Attr("itemscope"); scoped {
+		image = image.Find("[itemprop='url']").First()
+	}
+
+	return PageMetadata{
+		Title:       itempropValue(prop("headline")),
+		Description: itempropValue(prop("description")),
+		Author:      nested("author"),
+		SiteName:    nested("publisher"),
+		Image:       itempropValue(image),
+		Language:    itempropValue(prop("inLanguage")),
+		Tags:        splitKeywords(itempropValue(prop("keywords"))),
+		Published:   parseMetadataDate(itempropValue(prop("datePublished"))),
+		Modified:    parseMetadataDate(itempropValue(prop("dateModified"))),
+	}
+}
+
+// itempropValue возвращает значение свойства микроданных по правилам HTML
+func itempropValue(s *goquery.Selection) string {
+	if s.Length() == 0 {
+		return ""
+	}
+
+	for _, attr := range []string{"content", "datetime", "src", "href"} {
+		if value, ok := s.Attr(attr); ok {
+			return strings.TrimSpace(value)
+		}
+	}
+	return strings.TrimSpace(s.Text())
+}
+
+// htmlMetadata извлекает метаданные из обычной разметки страницы
+func htmlMetadata(doc *goquery.Document, meta map[string][]string) PageMetadata {
+	var tags []string
+	for _, keywords := range append(meta["keywords"], meta["news_keywords"]...) {
+		tags = append(tags, splitKeywords(keywords)...)
+	}
+
+	language := doc.Find("html").AttrOr("lang", "")
+	if language == "" {
+		language = firstMeta(meta, "content-language")
+	}
+
+	return PageMetadata{
+		Description:  firstMeta(meta, "description"),
+		Author:       firstMeta(meta, "author"),
+		CanonicalURL: doc.Find("link[rel='canonical']").First().AttrOr("href", ""),
+		AMPURL:       doc.Find("link[rel='amphtml']").First().AttrOr("href", ""),
+		Image:        doc.Find("link[rel='image_src']").First().AttrOr("href", ""),
+		Language:     language,
+		Tags:         tags,
+	}
+}
+
+// jsonLDMetadata извлекает метаданные статьи из application/ld+json
+func jsonLDMetadata(doc *goquery.Document) PageMetadata {
+	var result PageMetadata
+
+	doc.Find("script[type='application/ld+json']").Each(func(i int, s *goquery.Selection) {
+		var data interface{}
+		text := s.Text()
+		if err := json.Unmarshal([]byte(text), &data); err != nil {
+			// Переводы строк внутри строк JSON встречаются на многих сайтах
+			cleaned := strings.NewReplacer("\n", " ", "\r", " ", "\t", " ").Replace(text)
+			if json.Unmarshal([]byte(cleaned), &data) != nil {
+				return
+			}
+		}
+
+		for _, node := range jsonLDNodes(data) {
+			if isArticleNode(node) {
+				result.merge(articleNodeMetadata(node))
+			}
+		}
+	})
+
+	return result
+}
+
+// jsonLDNodes возвращает объекты JSON-LD, включая элементы массивов и @graph
+func jsonLDNodes(data interface{}) []map[string]interface{} {
+	var nodes []map[string]interface{}
+
+	switch value := data.(type) {
+	case []interface{}:
+		for _, item := range value {
+			nodes = append(nodes, jsonLDNodes(item)...)
+		}
+	case map[string]interface{}:
+		nodes = append(nodes, value)
+		if graph, ok := value["@graph"]; ok {
+			nodes = append(nodes, jsonLDNodes(graph)...)
+		}
+	}
+
+	return nodes
+}
+
+// isArticleNode проверяет, что @type объекта - статья
+func isArticleNode(node map[string]interface{}) bool {
+	for _, t := range jsonLDStrings(node["@type"]) {
+		if articleTypes[strings.TrimPrefix(t, "schema:")] {
+			return true
+		}
+	}
+	return false
+}
+
+// articleNodeMetadata извлекает поля из объекта Article
+func articleNodeMetadata(node map[string]interface{}) PageMetadata {
+	var tags []string
+	for _, keywords := range jsonLDStrings(node["keywords"]) {
+		tags = append(tags, splitKeywords(keywords)...)
+	}
+
+	canonical := jsonLDName(node["mainEntityOfPage"], "@id")
+	if canonical == "" {
+		canonical = jsonLDName(node["url"], "@id")
+	}
+
+	return PageMetadata{
+		Title:        jsonLDName(node["headline"], "name"),
+		Description:  jsonLDName(node["description"], "name"),
+		Author:       strings.Join(jsonLDNames(node["author"], "name"), ", "),
+		SiteName:     jsonLDName(node["publisher"], "name"),
+		CanonicalURL: canonical,
+		Image:        jsonLDName(node["image"], "url"),
+		Language:     jsonLDName(node["inLanguage"], "name"),
+		Tags:         tags,
+		Published:    parseMetadataDate(jsonLDName(node["datePublished"], "@value")),
+		Modified:     parseMetadataDate(jsonLDName(node["dateModified"], "@value")),
+	}
+}
+
+// jsonLDStrings возвращает строку или массив строк
+func jsonLDStrings(value interface{}) []string {
+	switch v := value.(type) {
+	case string:
+		return []string{v}
+	case []interface{}:
+		var result []string
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				result = append(result, s)
+			}
+		}
+		return result
+	}
+	return nil
+}
+
+// jsonLDNames возвращает значения, заданные строками или объектами с полем key
+func jsonLDNames(value interface{}, key string) []string {
+	switch v := value.(type) {
+	case string:
+		if strings.TrimSpace(v) != "" {
+			return []string{strings.TrimSpace(v)}
+		}
+	case map[string]interface{}:
+		if name, ok := v[key].(string); ok && strings.TrimSpace(name) != "" {
+			return []string{strings.TrimSpace(name)}
+		}
+	case []interface{}:
+		var result []string
+		for _, item := range v {
+			result = append(result, jsonLDNames(item, key)...)
+		}
+		return result
+	}
+	return nil
+}
+
+// jsonLDName возвращает первое значение, заданное строкой или объектом с полем key
+func jsonLDName(value interface{}, key string) string {
+	if names := jsonLDNames(value, key); len(names) > 0 {
+		return names[0]
+	}
+	return ""
+}
+
+// resolveMetadataURL делает адрес из метаданных абсолютным
+func resolveMetadataURL(base *url.URL, ref string) string {
+	if ref == "" {
+		return ""
+	}
+	resolved := resolveReference(base, ref)
+	if !isHTTPURL(resolved) {
+		return ""
+	}
+	return resolved
+}
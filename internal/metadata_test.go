@@ -0,0 +1,141 @@
+package internal
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+func metadataFromHTML(t *testing.T, page string) PageMetadata {
+	t.Helper()
+
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(page))
+	if err != nil {
+		t.Fatalf("Ошибка парсинга HTML: %v", err)
+	}
+	base, _ := url.Parse("https://example.com/blog/post")
+	return extractMetadata(doc, base)
+}
+
+func TestExtractMetadataJSONLD(t *testing.T) {
+	meta := metadataFromHTML(t, `<html lang="en"><head>
+<script type="application/ld+json">
+{"@context": "https://schema.org", "@graph": [
+  {"@type": "WebSite", "name": "Не статья"},
+  {"@type": ["NewsArticle"], "headline": "Заголовок",
+   "description": "Описание
+   на двух строках",
+   "author": [{"@type": "Person", "name": "Анна"}, {"@type": "Person", "name": "Борис"}],
+   "publisher": {"@type": "Organization", "name": "Новости"},
+   "datePublished": "2024-03-15T10:30:00+03:00", "dateModified": "2024-03-16",
+   "image": {"@type": "ImageObject", "url": "/img/lead.jpg"},
+   "mainEntityOfPage": {"@id": "https://example.com/canonical"},
+   "keywords": "Go, Веб", "inLanguage": "ru_RU"}
+]}
+</script>
+<meta property="og:title" content="OG заголовок">
+<meta property="article:tag" content="go">
+<meta property="article:tag" content="Тесты">
+</head><body></body></html>`)
+
+	if meta.Title != "Заголовок" || meta.Author != "Анна, Борис" || meta.SiteName != "Новости" {
+		t.Errorf("JSON-LD должен иметь приоритет: %+v", meta)
+	}
+	if meta.Description != "Описание    на двух строках" {
+		t.Errorf("JSON-LD с переводами строк внутри строк должен разбираться, описание: %q", meta.Description)
+	}
+	if meta.Image != "https://example.com/img/lead.jpg" || meta.CanonicalURL != "https://example.com/canonical" {
+		t.Errorf("Адреса должны быть абсолютными: %s, %s", meta.Image, meta.CanonicalURL)
+	}
+	if meta.Language != "ru-RU" {
+		t.Errorf("Ожидался язык ru-RU, получен %q", meta.Language)
+	}
+
+	published := time.Date(2024, 3, 15, 7, 30, 0, 0, time.UTC)
+	if !meta.Published.Equal(published) {
+		t.Errorf("Ожидалась дата публикации %v, получена %v", published, meta.Published)
+	}
+	if meta.Modified.Format("2006-01-02") != "2024-03-16" {
+		t.Errorf("Неверная дата изменения: %v", meta.Modified)
+	}
+
+	if strings.Join(meta.Tags, "|") != "Go|Веб|Тесты" {
+		t.Errorf("Теги должны объединяться без повторов, получены %v", meta.Tags)
+	}
+}
+
+func TestExtractMetadataMetaTags(t *testing.T) {
+	meta := metadataFromHTML(t, `<html><head>
+<meta property="og:site_name" content="Блог">
+<meta property="og:image" content="https://cdn.example.com/og.png">
+<meta property="article:author" content="https://facebook.com/someone">
+<meta property="article:published_time" content="2024-01-10T08:00:00Z">
+<meta name="twitter:description" content="Описание из Twitter">
+<meta name="DC.creator" content="Мария Иванова">
+<meta name="DC.language" content="ru">
+<meta name="keywords" content="один, два">
+<link rel="canonical" href="/blog/post?utm_source=x">
+</head><body></body></html>`)
+
+	if meta.Author != "Мария Иванова" {
+		t.Errorf("Ссылка в article:author не должна считаться именем, автор: %q", meta.Author)
+	}
+	if meta.SiteName != "Блог" || meta.Description != "Описание из Twitter" || meta.Language != "ru" {
+		t.Errorf("Неверные метаданные: %+v", meta)
+	}
+	if meta.CanonicalURL != "https://example.com/blog/post?utm_source=x" {
+		t.Errorf("Неверный канонический адрес: %s", meta.CanonicalURL)
+	}
+	if meta.Published.Format(time.RFC3339) != "2024-01-10T08:00:00Z" {
+		t.Errorf("Неверная дата публикации: %v", meta.Published)
+	}
+	if strings.Join(meta.Tags, "|") != "один|два" {
+		t.Errorf("Неверные теги: %v", meta.Tags)
+	}
+}
+
+func TestExtractMetadataMicrodata(t *testing.T) {
+	meta := metadataFromHTML(t, `<html><body>
+<article itemscope itemtype="https://schema.org/BlogPosting">
+  <h1 itemprop="headline">Пост</h1>
+  <span itemprop="author" itemscope itemtype="https://schema.org/Person">
+    <span itemprop="name">Петр   Сидоров</span>
+  </span>
+  <time itemprop="datePublished" datetime="2023-12-01">1 декабря</time>
+  <img itemprop="image" src="cover.png">
+</article>
+</body></html>`)
+
+	if meta.Title != "Пост" || meta.Author != "Петр Сидоров" {
+		t.Errorf("Неверные микроданные: %+v", meta)
+	}
+	if meta.Published.Format("2006-01-02") != "2023-12-01" {
+		t.Errorf("Неверная дата публикации: %v", meta.Published)
+	}
+	if meta.Image != "https://example.com/blog/cover.png" {
+		t.Errorf("Неверное изображение: %s", meta.Image)
+	}
+}
+
+func TestExtractDateSkipsRelativeText(t *testing.T) {
+	doc, _ := goquery.NewDocumentFromReader(strings.NewReader(`<html><body>
+<span class="date">3 дня назад</span>
+<span class="published">15.03.2024</span>
+</body></html>`))
+
+	date := extractDate(doc)
+	if date.Format("2006-01-02") != "2024-03-15" {
+		t.Errorf("Ожидалась дата 2024-03-15, получено %v", date)
+	}
+	if formatContentDate(date) != "2024-03-15" {
+		t.Errorf("Дата без времени должна показываться без времени: %s", formatContentDate(date))
+	}
+
+	doc, _ = goquery.NewDocumentFromReader(strings.NewReader(`<span class="date">вчера</span>`))
+	if !extractDate(doc).IsZero() {
+		t.Error("Текст без даты не должен становиться датой")
+	}
+}
@@ -0,0 +1,116 @@
+package internal
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Стадии обработки ссылки в метрике tgnip_stage_duration_seconds
+const (
+	stageFetch       = "fetch"
+	stageReadability = "readability"
+	stageConversion  = "conversion"
+	stageSend        = "send"
+)
+
+// stageBuckets покрывают стадии от разбора в миллисекунды до загрузки с повторами
+var stageBuckets = []float64{0.01, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 120}
+
+// metricsRegistry содержит метрики бота, Go runtime и процесса
+var metricsRegistry = prometheus.NewRegistry()
+
+var (
+	webhookRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "tgnip_webhook_requests_total",
+		Help: "Запросы к webhook по результату: accepted, ignored, bad_secret, bad_json, bad_method, bad_content_type.",
+	}, []string{"result"})
+
+	stageDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "tgnip_stage_duration_seconds",
+		Help:    "Длительность стадий обработки ссылки: fetch, readability, conversion, send.",
+		Buckets: stageBuckets,
+	}, []string{"stage"})
+
+	fallbackUsed = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "tgnip_fallback_total",
+		Help: "Загрузки через резервный HTTP клиент после ошибки Colly.",
+	})
+
+	cacheRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "tgnip_cache_requests_total",
+		Help: "Обращения к кэшу ответов: hit, revalidated (304 Not Modified), miss.",
+	}, []string{"result"})
+
+	blockedPages = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "tgnip_blocked_total",
+		Help: "Отказы в загрузке страниц по причине: whitelist, robots_txt, forbidden, too_many_requests, unsafe_url, other.",
+	}, []string{"reason"})
+
+	codeLanguages = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "tgnip_code_language_total",
+		Help: "Блоки кода по определенному языку, unknown - язык не определен.",
+	}, []string{"language"})
+)
+
+func init() {
+	metricsRegistry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		webhookRequests,
+		stageDuration,
+		fallbackUsed,
+		cacheRequests,
+		blockedPages,
+		codeLanguages,
+	)
+}
+
+// MetricsHandler отдает метрики в текстовом формате Prometheus. Если задан token,
+// запрос должен содержать заголовок Authorization: Bearer <token>
+func MetricsHandler(token string) http.Handler {
+	handler := promhttp.HandlerFor(metricsRegistry, promhttp.HandlerOpts{})
+	if token == "" {
+		return handler
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer "+token {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		handler.ServeHTTP(w, r)
+	})
+}
+
+// observeStage записывает длительность стадии обработки ссылки
+func observeStage(stage string, start time.Time) {
+	stageDuration.WithLabelValues(stage).Observe(time.Since(start).Seconds())
+}
+
+// observeBlocked учитывает отказ в загрузке страницы
+func observeBlocked(err error) {
+	var blocked *BlockedError
+	if !errors.As(err, &blocked) {
+		return
+	}
+
+	reason := "other"
+	switch blocked.Kind {
+	case BlockNotWhitelisted:
+		reason = "whitelist"
+	case BlockRobotsTxt:
+		reason = "robots_txt"
+	case BlockForbidden:
+		reason = "forbidden"
+	case BlockTooManyRequests:
+		reason = "too_many_requests"
+	case BlockUnsafeURL:
+		reason = "unsafe_url"
+	}
+	blockedPages.WithLabelValues(reason).Inc()
+}
@@ -0,0 +1,95 @@
+package internal
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// scrapeMetrics запрашивает метрики и возвращает ответ
+func scrapeMetrics(t *testing.T, handler http.Handler, token string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	return rr
+}
+
+func TestMetricsEndpoint(t *testing.T) {
+	ws := &WebhookServer{config: &Config{}, secretToken: "secret"}
+
+	// Запросы с неверным токеном и неверным JSON
+	req := httptest.NewRequest(http.MethodPost, "/telegram/webhook", strings.NewReader("{}"))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Telegram-Bot-Api-Secret-Token", "wrong")
+	ws.handleWebhook(httptest.NewRecorder(), req)
+
+	req = httptest.NewRequest(http.MethodPost, "/telegram/webhook", strings.NewReader("not json"))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Telegram-Bot-Api-Secret-Token", "secret")
+	ws.handleWebhook(httptest.NewRecorder(), req)
+
+	observeBlocked(&BlockedError{Kind: BlockRobotsTxt, Reason: "запрещено"})
+	observeBlocked(&BlockedError{Kind: BlockNotWhitelisted, Reason: "не в списке"})
+	observeStage(stageFetch, time.Now())
+
+	rr := scrapeMetrics(t, MetricsHandler(""), "")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Ожидался статус 200, получен %d", rr.Code)
+	}
+	if !strings.HasPrefix(rr.Header().Get("Content-Type"), "text/plain") {
+		t.Errorf("Ожидался текстовый формат Prometheus, получен %s", rr.Header().Get("Content-Type"))
+	}
+
+	body := rr.Body.String()
+	for _, expected := range []string{
+		`tgnip_webhook_requests_total{result="bad_secret"}`,
+		`tgnip_webhook_requests_total{result="bad_json"}`,
+		`tgnip_blocked_total{reason="robots_txt"}`,
+		`tgnip_blocked_total{reason="whitelist"}`,
+		`tgnip_stage_duration_seconds_count{stage="fetch"}`,
+		"go_goroutines",
+	} {
+		if !strings.Contains(body, expected) {
+			t.Errorf("В метриках нет %s", expected)
+		}
+	}
+}
+
+func TestMetricsToken(t *testing.T) {
+	handler := MetricsHandler("metrics-token")
+
+	if rr := scrapeMetrics(t, handler, ""); rr.Code != http.StatusUnauthorized {
+		t.Errorf("Без токена ожидался статус 401, получен %d", rr.Code)
+	}
+	if rr := scrapeMetrics(t, handler, "wrong"); rr.Code != http.StatusUnauthorized {
+		t.Errorf("С неверным токеном ожидался статус 401, получен %d", rr.Code)
+	}
+	if rr := scrapeMetrics(t, handler, "metrics-token"); rr.Code != http.StatusOK {
+		t.Errorf("С токеном ожидался статус 200, получен %d", rr.Code)
+	}
+}
+
+func TestPollerStatusHandler(t *testing.T) {
+	poller := NewPoller(nil, &Config{PollingTimeout: 60, MetricsToken: "metrics-token"}, nil)
+	handler := poller.statusHandler()
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	if rr.Code != http.StatusOK || !strings.Contains(rr.Body.String(), `"polling"`) {
+		t.Errorf("В режиме polling должна работать проверка здоровья: %d %s", rr.Code, rr.Body.String())
+	}
+
+	if rr := scrapeMetrics(t, handler, ""); rr.Code != http.StatusUnauthorized {
+		t.Errorf("Без токена ожидался статус 401, получен %d", rr.Code)
+	}
+	if rr := scrapeMetrics(t, handler, "metrics-token"); rr.Code != http.StatusOK {
+		t.Errorf("С токеном ожидался статус 200, получен %d", rr.Code)
+	}
+}
@@ -0,0 +1,243 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// maxRedirects ограничивает число переходов по редиректам при загрузке страницы
+const maxRedirects = 10
+
+// DefaultAllowedPorts - порты, к которым бот подключается по умолчанию
+var DefaultAllowedPorts = []int{80, 443}
+
+// reservedNetworks - диапазоны, не покрытые методами net.IP, но не ведущие
+// в публичный интернет
+var reservedNetworks = parseCIDRs(
+	"0.0.0.0/8",       // "эта" сеть
+	"100.64.0.0/10",   // CGNAT
+	"192.0.0.0/24",    // служебные адреса IETF
+	"192.0.2.0/24",    // TEST-NET-1
+	"198.18.0.0/15",   // тестирование производительности
+	"198.51.100.0/24", // TEST-NET-2
+	"203.0.113.0/24",  // TEST-NET-3
+	"240.0.0.0/4",     // зарезервировано, включая 255.255.255.255
+	"64:ff9b::/96",    // NAT64, ведет на IPv4 адреса
+	"2001:db8::/32",   // документация
+)
+
+// parseCIDRs разбирает список сетей, заданных константами
+func parseCIDRs(cidrs ...string) []*net.IPNet {
+	networks := make([]*net.IPNet, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		networks = append(networks, network)
+	}
+	return networks
+}
+
+// NetworkPolicy ограничивает адреса, к которым бот подключается по ссылкам
+// пользователей: только http и https, разрешенные порты и публичные адреса
+type NetworkPolicy struct {
+	allowPrivate bool
+	allowedPorts map[int]bool // пусто - любой порт
+	resolver     *net.Resolver
+	dialer       *net.Dialer
+}
+
+// NewNetworkPolicy создает сетевую политику. allowPrivate разрешает внутренние
+// адреса (для локальной разработки), пустой список портов разрешает любые порты
+func NewNetworkPolicy(allowPrivate bool, allowedPorts []int) *NetworkPolicy {
+	policy := &NetworkPolicy{
+		allowPrivate: allowPrivate,
+		allowedPorts: make(map[int]bool, len(allowedPorts)),
+		resolver:     net.DefaultResolver,
+	}
+	for _, port := range allowedPorts {
+		policy.allowedPorts[port] = true
+	}
+
+	policy.dialer = &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+		// Последняя проверка адреса, к которому действительно подключаемся
+		Control: func(network, address string, c syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			return policy.checkIP(net.ParseIP(host))
+		},
+	}
+
+	return policy
+}
+
+// networkPolicy - политика для загрузчиков, созданных без конфигурации
+var networkPolicy = NewNetworkPolicy(false, DefaultAllowedPorts)
+
+// SetNetworkPolicy задает сетевую политику для загрузчиков по умолчанию
+func SetNetworkPolicy(policy *NetworkPolicy) {
+	networkPolicy = policy
+}
+
+// unsafeURLError создает ошибку для ссылки, которую нельзя загружать
+func unsafeURLError(format string, args ...interface{}) error {
+	return &BlockedError{Kind: BlockUnsafeURL, Reason: fmt.Sprintf(format, args...)}
+}
+
+// CheckURL проверяет схему, порт и адрес ссылки до DNS запроса
+func (p *NetworkPolicy) CheckURL(u *url.URL) error {
+	scheme := strings.ToLower(u.Scheme)
+	if scheme != "http" && scheme != "https" {
+		return unsafeURLError("схема %q не поддерживается", u.Scheme)
+	}
+
+	if u.Hostname() == "" {
+		return unsafeURLError("в ссылке нет хоста")
+	}
+
+	if err := p.checkPort(u.Port(), scheme); err != nil {
+		return err
+	}
+
+	// Адрес, указанный прямо в ссылке, проверяем сразу
+	if ip := net.ParseIP(u.Hostname()); ip != nil {
+		return p.checkIP(ip)
+	}
+
+	if !p.allowPrivate && isInternalHostname(u.Hostname()) {
+		return unsafeURLError("внутреннее имя хоста %s", u.Hostname())
+	}
+
+	return nil
+}
+
+// checkPort проверяет, что порт входит в список разрешенных
+func (p *NetworkPolicy) checkPort(port, scheme string) error {
+	if len(p.allowedPorts) == 0 {
+		return nil
+	}
+
+	if port == "" {
+		port = "80"
+		if scheme == "https" {
+			port = "443"
+		}
+	}
+
+	number, err := strconv.Atoi(port)
+	if err != nil || !p.allowedPorts[number] {
+		return unsafeURLError("порт %s не разрешен", port)
+	}
+	return nil
+}
+
+// checkIP запрещает внутренние, локальные, широковещательные и служебные адреса
+func (p *NetworkPolicy) checkIP(ip net.IP) error {
+	if ip == nil {
+		return unsafeURLError("некорректный IP адрес")
+	}
+	if p.allowPrivate {
+		return nil
+	}
+
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4
+	}
+
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() {
+		return unsafeURLError("адрес %s относится к внутренней сети", ip)
+	}
+
+	for _, network := range reservedNetworks {
+		if network.Contains(ip) {
+			return unsafeURLError("адрес %s относится к служебной сети", ip)
+		}
+	}
+
+	return nil
+}
+
+// isInternalHostname определяет имена, которые резолвятся только во внутренней сети
+func isInternalHostname(host string) bool {
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	if host == "localhost" || !strings.Contains(host, ".") {
+		return true
+	}
+	for _, suffix := range []string{".localhost", ".local", ".internal", ".lan", ".home.arpa"} {
+		if strings.HasSuffix(host, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// DialContext резолвит хост, проверяет все его адреса и подключается к проверенному
+// адресу, поэтому повторный DNS ответ (DNS rebinding) не может подменить адрес
+func (p *NetworkPolicy) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(p.allowedPorts) > 0 {
+		if number, err := strconv.Atoi(port); err != nil || !p.allowedPorts[number] {
+			return nil, unsafeURLError("порт %s не разрешен", port)
+		}
+	}
+
+	ips, err := p.resolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+
+	// Хост с хотя бы одним внутренним адресом не загружаем вовсе
+	for _, ip := range ips {
+		if err := p.checkIP(ip.IP); err != nil {
+			return nil, err
+		}
+	}
+
+	var lastErr error
+	for _, ip := range ips {
+		conn, err := p.dialer.DialContext(ctx, network, net.JoinHostPort(ip.IP.String(), port))
+		if err == nil {
+			return conn, nil
+		}
+		lastErr = err
+	}
+	if lastErr == nil {
+		lastErr = fmt.Errorf("не найдены адреса хоста %s", host)
+	}
+	return nil, lastErr
+}
+
+// CheckRedirect проверяет каждый редирект по тем же правилам, что и исходную ссылку
+func (p *NetworkPolicy) CheckRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) >= maxRedirects {
+		return fmt.Errorf("слишком много редиректов")
+	}
+	return p.CheckURL(req.URL)
+}
+
+// Transport создает http.Transport, подключающийся только к разрешенным адресам
+func (p *NetworkPolicy) Transport() *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = p.DialContext
+	// Через прокси адрес назначения проверить нельзя
+	transport.Proxy = nil
+	return transport
+}
@@ -0,0 +1,190 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestNetworkPolicyCheckURL(t *testing.T) {
+	policy := NewNetworkPolicy(false, DefaultAllowedPorts)
+
+	tests := []struct {
+		url     string
+		allowed bool
+	}{
+		{"https://example.com/article", true},
+		{"http://example.com:80/", true},
+		{"HTTPS://Example.com:443/", true},
+		{"ftp://example.com/file", false},
+		{"file:///etc/passwd", false},
+		{"gopher://example.com", false},
+		{"https://example.com:8080/", false},
+		{"http://127.0.0.1/", false},
+		{"http://localhost/", false},
+		{"http://intranet/", false},
+		{"http://printer.local/", false},
+		{"http://10.1.2.3/", false},
+		{"http://172.16.0.1/", false},
+		{"http://192.168.1.1/", false},
+		{"http://169.254.169.254/latest/meta-data/", false},
+		{"http://100.64.0.1/", false},
+		{"http://0.0.0.0/", false},
+		{"http://224.0.0.1/", false},
+		{"http://255.255.255.255/", false},
+		{"http://[::1]/", false},
+		{"http://[fe80::1]/", false},
+		{"http://[fd00::1]/", false},
+		{"http://[::ffff:127.0.0.1]/", false},
+		{"http://[64:ff9b::a00:1]/", false},
+		{"http://8.8.8.8/", true},
+		{"http://[2606:4700::1111]/", true},
+	}
+
+	for _, tt := range tests {
+		u, err := url.Parse(tt.url)
+		if err != nil {
+			t.Fatalf("Ошибка разбора %s: %v", tt.url, err)
+		}
+
+		err = policy.CheckURL(u)
+		if tt.allowed && err != nil {
+			t.Errorf("Ссылка %s должна быть разрешена, получено %v", tt.url, err)
+		}
+		if !tt.allowed {
+			var blocked *BlockedError
+			if !errors.As(err, &blocked) || blocked.Kind != BlockUnsafeURL {
+				t.Errorf("Ссылка %s должна быть запрещена, получено %v", tt.url, err)
+			}
+		}
+	}
+}
+
+func TestNetworkPolicyAllowPrivate(t *testing.T) {
+	policy := NewNetworkPolicy(true, nil)
+
+	for _, raw := range []string{"http://127.0.0.1:8080/", "http://localhost:3000/", "http://10.0.0.1/"} {
+		u, _ := url.Parse(raw)
+		if err := policy.CheckURL(u); err != nil {
+			t.Errorf("При ALLOW_PRIVATE_NETWORKS ссылка %s должна быть разрешена: %v", raw, err)
+		}
+	}
+
+	u, _ := url.Parse("file:///etc/passwd")
+	if err := policy.CheckURL(u); err == nil {
+		t.Error("Схема file запрещена даже для внутренних сетей")
+	}
+}
+
+func TestNetworkPolicyDialResolvesAndChecks(t *testing.T) {
+	policy := NewNetworkPolicy(false, nil)
+
+	// Имя, которое резолвится во внутренний адрес, проверяется после DNS запроса
+	_, err := policy.DialContext(context.Background(), "tcp", "localhost:80")
+	var blocked *BlockedError
+	if !errors.As(err, &blocked) || blocked.Kind != BlockUnsafeURL {
+		t.Errorf("Подключение к localhost должно быть запрещено, получено %v", err)
+	}
+
+	ports := NewNetworkPolicy(true, []int{443})
+	if _, err := ports.DialContext(context.Background(), "tcp", "127.0.0.1:22"); !errors.As(err, &blocked) {
+		t.Errorf("Подключение к неразрешенному порту должно быть запрещено, получено %v", err)
+	}
+}
+
+func TestNetworkPolicyCheckRedirect(t *testing.T) {
+	policy := NewNetworkPolicy(false, DefaultAllowedPorts)
+
+	redirect, _ := http.NewRequest("GET", "http://169.254.169.254/latest/meta-data/", nil)
+	if err := policy.CheckRedirect(redirect, []*http.Request{{}}); err == nil {
+		t.Error("Редирект на внутренний адрес должен быть запрещен")
+	}
+
+	public, _ := http.NewRequest("GET", "https://example.com/", nil)
+	if err := policy.CheckRedirect(public, []*http.Request{{}}); err != nil {
+		t.Errorf("Редирект на публичный адрес должен быть разрешен: %v", err)
+	}
+
+	if err := policy.CheckRedirect(public, make([]*http.Request, maxRedirects)); err == nil {
+		t.Error("Число редиректов должно быть ограничено")
+	}
+}
+
+func TestEthicalScraperBlocksPrivateNetworks(t *testing.T) {
+	requested := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = true
+		w.Write([]byte("<html><body>секрет</body></html>"))
+	}))
+	defer server.Close()
+
+	// Порт httptest сервера разрешен, но адрес 127.0.0.1 - нет
+	fetcher := NewEthicalFetcher(NewEthicalScraper(&Config{
+		HTTPTimeout: 30,
+		CacheTTL:    1,
+		UserAgent:   "Test-Bot/1.0",
+	}))
+
+	_, err := fetcher.Fetch(context.Background(), server.URL+"/admin")
+	var blocked *BlockedError
+	if !errors.As(err, &blocked) || blocked.Kind != BlockUnsafeURL {
+		t.Fatalf("Ожидалась блокировка внутреннего адреса, получено %v", err)
+	}
+	if requested {
+		t.Error("Запрос к внутреннему адресу не должен отправляться")
+	}
+
+	locale := locales["en"]
+	if errorMessageFor(err, locale) != locale.BlockedUnsafeURLMsg {
+		t.Errorf("Ожидалось сообщение о небезопасной ссылке, получено %q", errorMessageFor(err, locale))
+	}
+}
+
+func TestEthicalScraperBlocksRedirectToPrivateNetwork(t *testing.T) {
+	internal := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("Редирект на внутренний адрес не должен выполняться")
+	}))
+	defer internal.Close()
+
+	public := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/robots.txt" {
+			http.NotFound(w, r)
+			return
+		}
+		http.Redirect(w, r, internal.URL, http.StatusFound)
+	}))
+	defer public.Close()
+
+	scraper := NewEthicalScraper(&Config{
+		HTTPTimeout:          30,
+		CacheTTL:             1,
+		UserAgent:            "Test-Bot/1.0",
+		AllowPrivateNetworks: true,
+	})
+	// Имитируем публичный сайт: исходный адрес пропускаем, редиректы проверяем строго
+	strict := NewNetworkPolicy(false, nil)
+	scraper.client.CheckRedirect = strict.CheckRedirect
+
+	result, err := scraper.ScrapeURL(context.Background(), public.URL)
+	if err != nil {
+		t.Fatalf("Неожиданная ошибка: %v", err)
+	}
+	if !result.IsBlocked || result.BlockKind != BlockUnsafeURL {
+		t.Errorf("Редирект на внутренний адрес должен блокироваться: %+v", result)
+	}
+}
+
+func TestParsePorts(t *testing.T) {
+	if ports, ok := parsePorts("80, 443,8080"); !ok || len(ports) != 3 || ports[2] != 8080 {
+		t.Errorf("Неверный разбор портов: %v", ports)
+	}
+	if ports, ok := parsePorts("*"); !ok || ports != nil {
+		t.Errorf("* должна разрешать любые порты: %v", ports)
+	}
+	if _, ok := parsePorts("80,http"); ok {
+		t.Error("Некорректный список портов должен отклоняться")
+	}
+}
@@ -0,0 +1,376 @@
+package internal
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"html/template"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// OutputFormat преобразует извлеченный контент в файл определенного формата
+type OutputFormat interface {
+	// Name возвращает имя формата для команды /format
+	Name() string
+	// Extension возвращает расширение файла с точкой
+	Extension() string
+	// Render создает содержимое файла
+	Render(ctx context.Context, content *Content, originalURL string, locale Locale) ([]byte, error)
+}
+
+// DefaultOutputFormat - формат файлов по умолчанию
+const DefaultOutputFormat = "markdown"
+
+// outputFormats содержит поддерживаемые форматы в порядке показа пользователю
+var outputFormats = []OutputFormat{
+	markdownFormat{},
+	htmlFormat{},
+	htmlFormat{printable: true},
+	epubFormat{},
+	textFormat{},
+}
+
+// GetOutputFormat возвращает формат по имени
+func GetOutputFormat(name string) (OutputFormat, bool) {
+	for _, format := range outputFormats {
+		if format.Name() == name {
+			return format, true
+		}
+	}
+	return nil, false
+}
+
+// OutputFileName возвращает имя файла с расширением формата
+func OutputFileName(originalURL, title string, format OutputFormat) string {
+	filename := GenerateFilename(originalURL, title)
+	return strings.TrimSuffix(filename, path.Ext(filename)) + format.Extension()
+}
+
+// markdownFormat создает markdown документ; если задан frontMatter,
+// метаданные записываются в front matter для Obsidian, Hugo или Jekyll,
+// если задан template - документ оформляется по шаблону оператора
+type markdownFormat struct {
+	frontMatter string
+	template    string
+}
+
+func (markdownFormat) Name() string      { return "markdown" }
+func (markdownFormat) Extension() string { return ".md" }
+
+func (f markdownFormat) Render(ctx context.Context, content *Content, originalURL string, locale Locale) ([]byte, error) {
+	if f.template != "" {
+		data := newMarkdownTemplateData(ctx, content, originalURL, locale, f.frontMatter, time.Now())
+		document, err := markdownTemplates.Render(f.template, data)
+		if err == nil {
+			return []byte(document), nil
+		}
+		// Шаблон, который не сработал, не должен лишать пользователя файла
+		logger.Warnf("Используем стандартное оформление: %v", err)
+	}
+
+	if target, ok := GetFrontMatterTarget(f.frontMatter); ok {
+		return []byte(ConvertToMarkdownWithFrontMatter(ctx, content, originalURL, target, time.Now())), nil
+	}
+	return []byte(ConvertToMarkdown(ctx, content, originalURL, locale)), nil
+}
+
+// htmlFormat создает самостоятельную HTML страницу со встроенными стилями;
+// вариант printable оформлен для печати и сохранения в PDF из браузера
+type htmlFormat struct {
+	printable bool
+}
+
+func (f htmlFormat) Name() string {
+	if f.printable {
+		return "print"
+	}
+	return "html"
+}
+
+func (htmlFormat) Extension() string { return ".html" }
+
+func (f htmlFormat) Render(ctx context.Context, content *Content, originalURL string, locale Locale) ([]byte, error) {
+	body, err := renderContentHTML(ctx, content)
+	if err != nil {
+		return nil, err
+	}
+
+	style := htmlStyle
+	if f.printable {
+		style += printStyle
+	}
+
+	data := struct {
+		Title  string
+		Style  template.CSS
+		Meta   []metadataItem
+		Body   template.HTML
+		Footer string
+	}{
+		Title:  content.Title,
+		Style:  template.CSS(style),
+		Meta:   contentMetadata(content, originalURL, locale),
+		Body:   template.HTML(body),
+		Footer: strings.Trim(locale.FooterText, "*"),
+	}
+
+	var buf bytes.Buffer
+	if err := htmlPageTemplate.Execute(&buf, data); err != nil {
+		return nil, fmt.Errorf("ошибка создания HTML: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// htmlPageTemplate - шаблон самостоятельной HTML страницы
+var htmlPageTemplate = template.Must(template.New("page").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Title}}</title>
+<style>{{.Style}}</style>
+</head>
+<body>
+<article>
+<h1>{{.Title}}</h1>
+<ul class="meta">
+{{- range .Meta}}
+<li><strong>{{.Label}}</strong> {{if .Link}}<a href="{{.Link}}">{{.Value}}</a>{{else}}{{.Value}}{{end}}</li>
+{{- end}}
+</ul>
+<hr>
+<div class="content">
+{{.Body}}
+</div>
+<hr>
+<footer>{{.Footer}}</footer>
+</article>
+</body>
+</html>
+`))
+
+const htmlStyle = `
+body { margin: 0; padding: 2rem 1rem; background: #fff; color: #222; font: 18px/1.6 Georgia, "Times New Roman", serif; }
+article { max-width: 42rem; margin: 0 auto; }
+h1, h2, h3, h4 { font-family: -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; line-height: 1.25; }
+a { color: #0b57d0; }
+img { max-width: 100%; height: auto; }
+pre { overflow-x: auto; padding: 1rem; background: #f5f5f5; border-radius: 4px; }
+code { font: 0.9em Menlo, Consolas, monospace; }
+blockquote { margin: 1em 0; padding-left: 1em; border-left: 4px solid #ddd; color: #555; }
+table { border-collapse: collapse; }
+th, td { padding: 0.3em 0.6em; border: 1px solid #ccc; }
+.meta { padding: 0; list-style: none; color: #555; font-size: 0.9em; }
+footer { color: #777; font-size: 0.8em; font-style: italic; }
+`
+
+const printStyle = `
+@page { size: A4; margin: 2cm; }
+@media print {
+  body { padding: 0; font-size: 11pt; }
+  article { max-width: none; }
+  a { color: inherit; text-decoration: none; }
+  .content a[href^="http"]::after { content: " (" attr(href) ")"; font-size: 0.85em; color: #555; }
+  h1, h2, h3, h4 { break-after: avoid; }
+  pre, blockquote, table, img { break-inside: avoid; }
+  pre { white-space: pre-wrap; }
+}
+`
+
+// metadataItem представляет строку метаинформации документа
+type metadataItem struct {
+	Label string
+	Value string
+	Link  string
+}
+
+// contentMetadata собирает метаинформацию для HTML, EPUB и текста
+func contentMetadata(content *Content, originalURL string, locale Locale) []metadataItem {
+	items := []metadataItem{{
+		Label: plainLabel(locale.SourceLabel),
+		Value: getDomain(originalURL, locale),
+		Link:  originalURL,
+	}}
+
+	if content.Author != "" {
+		items = append(items, metadataItem{Label: plainLabel(locale.AuthorLabel), Value: content.Author})
+	}
+	if content.Date != "" {
+		items = append(items, metadataItem{Label: plainLabel(locale.DateLabel), Value: content.Date})
+	}
+
+	items = append(items, metadataItem{
+		Label: plainLabel(locale.ProcessedLabel),
+		Value: time.Now().Format("2006-01-02 15:04:05"),
+	})
+
+	return items
+}
+
+// plainLabel убирает markdown выделение из подписи
+func plainLabel(label string) string {
+	return strings.Trim(label, "*")
+}
+
+// markdownRenderer преобразует markdown в HTML; сырой HTML из страницы не пропускается
+var markdownRenderer = goldmark.New(goldmark.WithExtensions(extension.GFM))
+
+// renderContentHTML преобразует markdown контента в фрагмент HTML
+func renderContentHTML(ctx context.Context, content *Content) (string, error) {
+	source := processMarkdownWithLanguageDetection(ctx, content.Markdown)
+
+	var buf bytes.Buffer
+	if err := markdownRenderer.Convert([]byte(source), &buf); err != nil {
+		return "", fmt.Errorf("ошибка преобразования markdown в HTML: %w", err)
+	}
+	return buf.String(), nil
+}
+
+// textFormat создает простой текстовый документ
+type textFormat struct{}
+
+func (textFormat) Name() string      { return "text" }
+func (textFormat) Extension() string { return ".txt" }
+
+func (textFormat) Render(ctx context.Context, content *Content, originalURL string, locale Locale) ([]byte, error) {
+	body, err := renderContentHTML(ctx, content)
+	if err != nil {
+		return nil, err
+	}
+
+	var text strings.Builder
+	text.WriteString(content.Title + "\n")
+	text.WriteString(strings.Repeat("=", len([]rune(content.Title))) + "\n\n")
+
+	for _, item := range contentMetadata(content, originalURL, locale) {
+		value := item.Value
+		if item.Link != "" {
+			value = item.Link
+		}
+		text.WriteString(fmt.Sprintf("%s %s\n", item.Label, value))
+	}
+
+	text.WriteString("\n")
+	text.WriteString(htmlToText(body))
+	text.WriteString("\n\n")
+	text.WriteString(strings.Trim(locale.FooterText, "*") + "\n")
+
+	return []byte(text.String()), nil
+}
+
+// extraNewlines находит больше одной пустой строки подряд
+var extraNewlines = regexp.MustCompile(`\n{3,}`)
+
+// htmlToText преобразует фрагмент HTML в читаемый текст
+func htmlToText(fragment string) string {
+	nodes, err := parseHTMLFragment(fragment)
+	if err != nil {
+		return fragment
+	}
+
+	var b strings.Builder
+	for _, node := range nodes {
+		writeNodeText(&b, node, false)
+	}
+
+	return strings.TrimSpace(extraNewlines.ReplaceAllString(b.String(), "\n\n"))
+}
+
+// writeNodeText записывает текст узла, разделяя блоки пустыми строками
+func writeNodeText(b *strings.Builder, node *html.Node, pre bool) {
+	switch node.Type {
+	case html.TextNode:
+		if pre {
+			b.WriteString(node.Data)
+		} else {
+			b.WriteString(strings.Join(strings.Fields(node.Data), " "))
+			if strings.HasSuffix(node.Data, " ") || strings.HasSuffix(node.Data, "\n") {
+				b.WriteString(" ")
+			}
+		}
+		return
+	case html.ElementNode:
+	default:
+		for child := node.FirstChild; child != nil; child = child.NextSibling {
+			writeNodeText(b, child, pre)
+		}
+		return
+	}
+
+	switch node.Data {
+	case "br":
+		b.WriteString("\n")
+		return
+	case "hr":
+		b.WriteString("\n\n----------\n\n")
+		return
+	case "img":
+		if alt := nodeAttr(node, "alt"); alt != "" {
+			b.WriteString("[" + alt + "]")
+		}
+		return
+	case "li":
+		b.WriteString("\n- ")
+	case "p", "div", "h1", "h2", "h3", "h4", "h5", "h6", "blockquote", "pre", "table", "ul", "ol":
+		b.WriteString("\n\n")
+	case "tr":
+		b.WriteString("\n")
+	case "td", "th":
+		b.WriteString(" | ")
+	}
+
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		writeNodeText(b, child, pre || node.Data == "pre")
+	}
+
+	switch node.Data {
+	case "a":
+		// Ссылку показываем после текста, если текст не совпадает с адресом
+		href := nodeAttr(node, "href")
+		if strings.HasPrefix(href, "http") && strings.TrimSpace(nodeText(node)) != href {
+			b.WriteString(" (" + href + ")")
+		}
+	case "p", "div", "h1", "h2", "h3", "h4", "h5", "h6", "blockquote", "pre", "table", "ul", "ol":
+		b.WriteString("\n\n")
+	}
+}
+
+// parseHTMLFragment разбирает фрагмент HTML как содержимое body
+func parseHTMLFragment(fragment string) ([]*html.Node, error) {
+	return html.ParseFragment(strings.NewReader(fragment), &html.Node{
+		Type:     html.ElementNode,
+		Data:     "body",
+		DataAtom: atom.Body,
+	})
+}
+
+// nodeText возвращает весь текст внутри узла
+func nodeText(node *html.Node) string {
+	if node.Type == html.TextNode {
+		return node.Data
+	}
+
+	var text strings.Builder
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		text.WriteString(nodeText(child))
+	}
+	return text.String()
+}
+
+// nodeAttr возвращает значение атрибута HTML узла
+func nodeAttr(node *html.Node, name string) string {
+	for _, attr := range node.Attr {
+		if attr.Key == name {
+			return attr.Val
+		}
+	}
+	return ""
+}
@@ -0,0 +1,266 @@
+package internal
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/xml"
+	"io"
+	"strings"
+	"testing"
+)
+
+func testContent() *Content {
+	return &Content{
+		Title:  "Go & <Generics>",
+		Author: "Иван Петров",
+		Date:   "2024-03-15",
+		URL:    "https://example.com/go",
+		Markdown: `## Введение
+
+Текст со [ссылкой](https://go.dev) и **выделением**.
+
+- первый
+- второй
+
+` + "```go\nfunc main() {}\n```" + `
+
+| A | B |
+|---|---|
+| 1 | 2 |
+`,
+	}
+}
+
+func TestGetOutputFormat(t *testing.T) {
+	for _, name := range []string{"markdown", "html", "print", "epub", "text"} {
+		format, ok := GetOutputFormat(name)
+		if !ok || format.Name() != name {
+			t.Errorf("Формат %s должен поддерживаться", name)
+		}
+	}
+
+	if _, ok := GetOutputFormat("docx"); ok {
+		t.Error("Неизвестный формат не должен находиться")
+	}
+}
+
+func TestOutputFileName(t *testing.T) {
+	format, _ := GetOutputFormat("epub")
+	if name := OutputFileName("https://example.com/a", "Version 1.2", format); name != "Version_1.2.epub" {
+		t.Errorf("Ожидалось 'Version_1.2.epub', получено '%s'", name)
+	}
+}
+
+func TestHTMLFormat(t *testing.T) {
+	data, err := htmlFormat{}.Render(context.Background(), testContent(), "https://example.com/go", locales["ru"])
+	if err != nil {
+		t.Fatalf("Ошибка создания HTML: %v", err)
+	}
+	page := string(data)
+
+	for _, expected := range []string{
+		"<!DOCTYPE html>",
+		"<title>Go &amp; &lt;Generics&gt;</title>",
+		"<style>",
+		`<a href="https://go.dev">ссылкой</a>`,
+		"<table>",
+		`class="language-go"`,
+		"Иван Петров",
+	} {
+		if !strings.Contains(page, expected) {
+			t.Errorf("HTML должен содержать %q", expected)
+		}
+	}
+
+	if strings.Contains(page, "@page") {
+		t.Error("Обычный HTML не должен содержать стили печати")
+	}
+
+	printable, _ := htmlFormat{printable: true}.Render(context.Background(), testContent(), "https://example.com/go", locales["ru"])
+	if !strings.Contains(string(printable), "@page") {
+		t.Error("HTML для печати должен содержать стили @page")
+	}
+}
+
+func TestHTMLFormatDropsRawHTML(t *testing.T) {
+	content := &Content{Title: "T", Markdown: "text <script>alert(1)</script>"}
+
+	data, err := htmlFormat{}.Render(context.Background(), content, "https://example.com", locales["en"])
+	if err != nil {
+		t.Fatalf("Ошибка создания HTML: %v", err)
+	}
+	if strings.Contains(string(data), "<script>alert") {
+		t.Error("Сырой HTML из страницы не должен попадать в документ")
+	}
+}
+
+func TestTextFormat(t *testing.T) {
+	data, err := textFormat{}.Render(context.Background(), testContent(), "https://example.com/go", locales["en"])
+	if err != nil {
+		t.Fatalf("Ошибка создания текста: %v", err)
+	}
+	text := string(data)
+
+	for _, expected := range []string{
+		"Go & <Generics>\n===============",
+		"Author: Иван Петров",
+		"ссылкой (https://go.dev)",
+		"- первый",
+		"func main() {}",
+	} {
+		if !strings.Contains(text, expected) {
+			t.Errorf("Текст должен содержать %q:\n%s", expected, text)
+		}
+	}
+
+	if strings.Contains(text, "<") && !strings.Contains(text, "<Generics>") {
+		t.Error("Текст не должен содержать HTML теги")
+	}
+	if strings.Contains(text, "**") {
+		t.Error("Текст не должен содержать markdown выделение")
+	}
+}
+
+func TestEPUBFormat(t *testing.T) {
+	data, err := epubFormat{}.Render(context.Background(), testContent(), "https://example.com/go", locales["ru"])
+	if err != nil {
+		t.Fatalf("Ошибка создания EPUB: %v", err)
+	}
+
+	reader, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("EPUB должен быть zip архивом: %v", err)
+	}
+
+	first := reader.File[0]
+	if first.Name != "mimetype" || first.Method != zip.Store {
+		t.Errorf("Первым файлом должен быть несжатый mimetype, получен %s", first.Name)
+	}
+
+	files := make(map[string]string)
+	for _, file := range reader.File {
+		rc, err := file.Open()
+		if err != nil {
+			t.Fatalf("Ошибка открытия %s: %v", file.Name, err)
+		}
+		content, _ := io.ReadAll(rc)
+		rc.Close()
+		files[file.Name] = string(content)
+	}
+
+	if files["mimetype"] != "application/epub+zip" {
+		t.Errorf("Неверный mimetype: %s", files["mimetype"])
+	}
+
+	// Все XML файлы книги должны быть корректными
+	for _, name := range []string{"META-INF/container.xml", "OEBPS/content.opf", "OEBPS/nav.xhtml",
+		"OEBPS/cover.svg", "OEBPS/cover.xhtml", "OEBPS/content.xhtml"} {
+		if err := checkWellFormedXML(files[name]); err != nil {
+			t.Errorf("Файл %s не является корректным XML: %v", name, err)
+		}
+	}
+
+	opf := files["OEBPS/content.opf"]
+	for _, expected := range []string{
+		"<dc:title>Go &amp; &lt;Generics&gt;</dc:title>",
+		"<dc:creator>Иван Петров</dc:creator>",
+		"<dc:date>2024-03-15</dc:date>",
+		`properties="cover-image"`,
+		"urn:uuid:",
+	} {
+		if !strings.Contains(opf, expected) {
+			t.Errorf("content.opf должен содержать %q", expected)
+		}
+	}
+
+	cover := files["OEBPS/cover.svg"]
+	if !strings.Contains(cover, "Иван Петров") || !strings.Contains(cover, "2024-03-15") {
+		t.Error("Обложка должна содержать автора и дату")
+	}
+
+	if !strings.Contains(files["OEBPS/content.xhtml"], "<table>") {
+		t.Error("Содержимое книги должно включать таблицу")
+	}
+}
+
+func TestEPUBRemoteImages(t *testing.T) {
+	content := testContent()
+	content.Markdown = "![Схема](https://example.com/img/scheme.png)\n\n![Фото](https://cdn.example.com/photo?id=1&size=2)\n\n![Повтор](https://example.com/img/scheme.png)\n\n![Локальное](assets/01-a.png)\n"
+
+	data, err := epubFormat{}.Render(context.Background(), content, "https://example.com/go", locales["ru"])
+	if err != nil {
+		t.Fatalf("Ошибка создания EPUB: %v", err)
+	}
+
+	reader, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("EPUB должен быть zip архивом: %v", err)
+	}
+	var opf string
+	for _, file := range reader.File {
+		if file.Name == "OEBPS/content.opf" {
+			rc, _ := file.Open()
+			raw, _ := io.ReadAll(rc)
+			rc.Close()
+			opf = string(raw)
+		}
+	}
+
+	if err := checkWellFormedXML(opf); err != nil {
+		t.Fatalf("content.opf не является корректным XML: %v", err)
+	}
+	for _, expected := range []string{
+		`href="content.xhtml" media-type="application/xhtml+xml" properties="remote-resources"`,
+		`<item id="remote-image-1" href="https://example.com/img/scheme.png" media-type="image/png"/>`,
+		`<item id="remote-image-2" href="https://cdn.example.com/photo?id=1&amp;size=2" media-type="image/jpeg"/>`,
+	} {
+		if !strings.Contains(opf, expected) {
+			t.Errorf("content.opf должен содержать %s:\n%s", expected, opf)
+		}
+	}
+	if strings.Contains(opf, "remote-image-3") || strings.Contains(opf, "assets/01-a.png") {
+		t.Errorf("В манифест попадают только различные внешние изображения:\n%s", opf)
+	}
+
+	if images := epubRemoteImages("<p>без изображений</p>"); len(images) != 0 {
+		t.Errorf("Без внешних изображений свойство remote-resources не нужно: %+v", images)
+	}
+}
+
+func TestEPUBIdentifierStable(t *testing.T) {
+	first := epubIdentifier("https://example.com/a")
+	if first != epubIdentifier("https://example.com/a") {
+		t.Error("Идентификатор книги должен зависеть только от URL")
+	}
+	if first == epubIdentifier("https://example.com/b") {
+		t.Error("Разные URL должны давать разные идентификаторы")
+	}
+}
+
+func TestWrapText(t *testing.T) {
+	lines := wrapText("Очень длинный заголовок статьи про Go", 15)
+	for _, line := range lines {
+		if len([]rune(line)) > 15 {
+			t.Errorf("Строка длиннее 15 символов: %q", line)
+		}
+	}
+	if strings.Join(lines, " ") != "Очень длинный заголовок статьи про Go" {
+		t.Errorf("Перенос не должен терять слова: %v", lines)
+	}
+}
+
+// checkWellFormedXML проверяет, что документ разбирается XML парсером
+func checkWellFormedXML(document string) error {
+	decoder := xml.NewDecoder(strings.NewReader(document))
+	decoder.Strict = true
+	for {
+		_, err := decoder.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,186 @@
+package internal
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// DefaultMaxBodySize - максимальный размер загружаемой страницы по умолчанию
+const DefaultMaxBodySize int64 = 10 * 1024 * 1024
+
+// DefaultContentTypes - типы содержимого страниц, которые бот умеет обрабатывать
+var DefaultContentTypes = []string{"text/html", "application/xhtml+xml", "application/pdf"}
+
+// PageLimits ограничивает размер и тип загружаемых страниц
+type PageLimits struct {
+	MaxBodySize  int64    // в байтах, 0 - без ограничения
+	ContentTypes []string // разрешенные типы, "text/*" - любой подтип
+}
+
+// pageLimits - ограничения страниц для загрузчиков и извлечения контента
+var pageLimits = PageLimits{
+	MaxBodySize:  DefaultMaxBodySize,
+	ContentTypes: DefaultContentTypes,
+}
+
+// SetPageLimits задает ограничения размера и типа загружаемых страниц
+func SetPageLimits(limits PageLimits) {
+	pageLimits = limits
+}
+
+// pageLimitsKey - ключ контекста для ограничений отдельной загрузки
+type pageLimitsKey struct{}
+
+// withPageLimits задает ограничения для загрузки, отличной от страницы, например изображения
+func withPageLimits(ctx context.Context, limits PageLimits) context.Context {
+	return context.WithValue(ctx, pageLimitsKey{}, limits)
+}
+
+// pageLimitsFromContext возвращает ограничения загрузки из контекста или fallback
+func pageLimitsFromContext(ctx context.Context, fallback PageLimits) PageLimits {
+	if limits, ok := ctx.Value(pageLimitsKey{}).(PageLimits); ok {
+		return limits
+	}
+	return fallback
+}
+
+// BodyTooLargeError возвращается, когда ответ сайта больше допустимого размера
+type BodyTooLargeError struct {
+	Limit int64
+}
+
+func (e *BodyTooLargeError) Error() string {
+	return fmt.Sprintf("размер ответа превышает ограничение %d байт", e.Limit)
+}
+
+// UnsupportedContentError возвращается для ответов, которые не являются страницей
+type UnsupportedContentError struct {
+	ContentType string
+}
+
+func (e *UnsupportedContentError) Error() string {
+	return fmt.Sprintf("тип содержимого %s не поддерживается", e.ContentType)
+}
+
+// readLimitedBody читает тело ответа, но не больше maxSize байт
+func readLimitedBody(resp *http.Response, maxSize int64) ([]byte, error) {
+	if maxSize <= 0 {
+		return io.ReadAll(resp.Body)
+	}
+
+	// Размер из заголовка позволяет отказаться сразу, не читая тело
+	if resp.ContentLength > maxSize {
+		return nil, &BodyTooLargeError{Limit: maxSize}
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(body)) > maxSize {
+		return nil, &BodyTooLargeError{Limit: maxSize}
+	}
+	return body, nil
+}
+
+// responseContentType возвращает тип содержимого ответа из заголовка
+// или, если заголовка нет, определенный по первым байтам тела
+func responseContentType(result *FetchResult) string {
+	header := ""
+	if result.Headers != nil {
+		header = result.Headers.Get("Content-Type")
+	}
+	if header == "" {
+		header = http.DetectContentType(result.Body)
+	}
+
+	mediaType, _, err := mime.ParseMediaType(header)
+	if err != nil {
+		mediaType = strings.ToLower(strings.TrimSpace(strings.Split(header, ";")[0]))
+	}
+
+	// PDF часто отдают как произвольные двоичные данные
+	if genericBinaryTypes[mediaType] && bytes.HasPrefix(result.Body, []byte("%PDF-")) {
+		return "application/pdf"
+	}
+	return mediaType
+}
+
+// genericBinaryTypes - типы, под которыми сайты отдают файлы без указания формата
+var genericBinaryTypes = map[string]bool{
+	"application/octet-stream": true,
+	"binary/octet-stream":      true,
+	"application/x-pdf":        true,
+	"application/download":     true,
+}
+
+// checkContentType проверяет, что загруженный ответ - страница разрешенного типа
+func checkContentType(result *FetchResult, allowed []string) error {
+	if len(allowed) == 0 {
+		return nil
+	}
+
+	contentType := responseContentType(result)
+	if contentTypeAllowed(contentType, allowed) {
+		return nil
+	}
+	return &UnsupportedContentError{ContentType: contentType}
+}
+
+// checkHeaderContentType проверяет тип из заголовка до чтения тела. Ответ без
+// заголовка или с двоичным типом пропускается: его тип определяется по телу
+func checkHeaderContentType(header http.Header, allowed []string) error {
+	if len(allowed) == 0 || header.Get("Content-Type") == "" {
+		return nil
+	}
+
+	contentType := responseContentType(&FetchResult{Headers: header})
+	if genericBinaryTypes[contentType] || contentTypeAllowed(contentType, allowed) {
+		return nil
+	}
+	return &UnsupportedContentError{ContentType: contentType}
+}
+
+// contentTypeAllowed сопоставляет тип содержимого со списком разрешенных
+func contentTypeAllowed(contentType string, allowed []string) bool {
+	for _, pattern := range allowed {
+		pattern = strings.ToLower(strings.TrimSpace(pattern))
+		if pattern == contentType {
+			return true
+		}
+		if prefix, ok := strings.CutSuffix(pattern, "/*"); ok && strings.HasPrefix(contentType, prefix+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// unsupportedContentMessage возвращает локализованное сообщение для неподдерживаемого типа
+func unsupportedContentMessage(contentType string, locale Locale) string {
+	switch {
+	case contentType == "application/pdf":
+		return locale.UnsupportedPDFMsg
+	case strings.HasPrefix(contentType, "image/"):
+		return locale.UnsupportedImageMsg
+	case strings.HasPrefix(contentType, "video/"):
+		return locale.UnsupportedVideoMsg
+	case strings.HasPrefix(contentType, "audio/"):
+		return locale.UnsupportedAudioMsg
+	default:
+		return fmt.Sprintf(locale.UnsupportedContentMsg, contentType)
+	}
+}
+
+// formatMegabytes форматирует размер в мегабайтах для сообщений пользователю
+func formatMegabytes(size int64) string {
+	mb := float64(size) / (1024 * 1024)
+	if mb == float64(int64(mb)) {
+		return fmt.Sprintf("%d", int64(mb))
+	}
+	return fmt.Sprintf("%.1f", mb)
+}
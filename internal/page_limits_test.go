@@ -0,0 +1,193 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCheckContentType(t *testing.T) {
+	htmlOnly := []string{"text/html", "application/xhtml+xml"}
+
+	tests := []struct {
+		contentType string
+		body        string
+		allowed     []string
+		expected    string // пусто - тип разрешен
+	}{
+		{"text/html; charset=utf-8", "<html></html>", DefaultContentTypes, ""},
+		{"Application/XHTML+XML", "<html></html>", DefaultContentTypes, ""},
+		{"application/pdf", "%PDF-1.7", DefaultContentTypes, ""},
+		{"application/pdf", "%PDF-1.7", htmlOnly, "application/pdf"},
+		{"application/octet-stream", "%PDF-1.7", htmlOnly, "application/pdf"},
+		{"application/octet-stream", "MZ", DefaultContentTypes, "application/octet-stream"},
+		{"video/mp4", "", DefaultContentTypes, "video/mp4"},
+		{"", "<!DOCTYPE html><html></html>", DefaultContentTypes, ""},
+		{"", "%PDF-1.4 binary", htmlOnly, "application/pdf"},
+		{"text/plain", "текст", []string{"text/*"}, ""},
+		{"image/png", "", nil, ""},
+	}
+
+	for _, tt := range tests {
+		result := &FetchResult{Body: []byte(tt.body), Headers: http.Header{}}
+		if tt.contentType != "" {
+			result.Headers.Set("Content-Type", tt.contentType)
+		}
+
+		err := checkContentType(result, tt.allowed)
+		if tt.expected == "" {
+			if err != nil {
+				t.Errorf("Тип %q должен быть разрешен: %v", tt.contentType, err)
+			}
+			continue
+		}
+
+		var unsupported *UnsupportedContentError
+		if !errors.As(err, &unsupported) || unsupported.ContentType != tt.expected {
+			t.Errorf("Для %q ожидался неподдерживаемый тип %s, получено %v", tt.contentType, tt.expected, err)
+		}
+	}
+}
+
+func TestExtractContentRejectsUnsupportedType(t *testing.T) {
+	f := &imageFetcher{responses: map[string]*FetchResult{
+		"https://example.com/photo.png": imageResult("image/png", []byte("\x89PNG\r\n\x1a\n")),
+	}}
+
+	config := DefaultCollyConfig()
+	config.Fetcher = f
+
+	_, err := ExtractContentWithConfig(context.Background(), "https://example.com/photo.png", config)
+	locale := locales["ru"]
+	if got := errorMessageFor(err, locale); got != locale.UnsupportedImageMsg {
+		t.Errorf("Ожидалось сообщение об изображении, получено %q (%v)", got, err)
+	}
+}
+
+func TestUnsupportedContentMessages(t *testing.T) {
+	locale := locales["en"]
+
+	tests := []struct {
+		contentType string
+		expected    string
+	}{
+		{"application/pdf", locale.UnsupportedPDFMsg},
+		{"image/jpeg", locale.UnsupportedImageMsg},
+		{"video/webm", locale.UnsupportedVideoMsg},
+		{"audio/mpeg", locale.UnsupportedAudioMsg},
+	}
+
+	for _, tt := range tests {
+		if got := unsupportedContentMessage(tt.contentType, locale); got != tt.expected {
+			t.Errorf("Для %s ожидалось %q, получено %q", tt.contentType, tt.expected, got)
+		}
+	}
+
+	if got := unsupportedContentMessage("application/zip", locale); !strings.Contains(got, "application/zip") {
+		t.Errorf("Сообщение должно содержать тип файла: %q", got)
+	}
+
+	got := errorMessageFor(&BodyTooLargeError{Limit: 10 * 1024 * 1024}, locale)
+	if !strings.Contains(got, "10 MB") {
+		t.Errorf("Сообщение должно содержать ограничение размера: %q", got)
+	}
+}
+
+func TestEthicalScraperMaxBodySize(t *testing.T) {
+	large := strings.Repeat("a", 1024*1024+1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/robots.txt":
+			http.NotFound(w, r)
+		case "/chunked":
+			// Без Content-Length размер проверяется при чтении
+			w.(http.Flusher).Flush()
+			w.Write([]byte(large))
+		default:
+			w.Write([]byte(large))
+		}
+	}))
+	defer server.Close()
+
+	scraper := NewEthicalScraper(&Config{
+		HTTPTimeout:          30,
+		CacheTTL:             1,
+		UserAgent:            "Test-Bot/1.0",
+		AllowPrivateNetworks: true,
+		MaxBodySizeMB:        1,
+	})
+
+	for _, path := range []string{"/page", "/chunked"} {
+		_, err := scraper.ScrapeURL(context.Background(), server.URL+path)
+		var tooLarge *BodyTooLargeError
+		if !errors.As(err, &tooLarge) {
+			t.Errorf("Для %s ожидалась ошибка размера, получено %v", path, err)
+		}
+	}
+}
+
+func TestEthicalScraperRejectsUnsupportedType(t *testing.T) {
+	large := strings.Repeat("a", 1024*1024+1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/robots.txt":
+			http.NotFound(w, r)
+		case "/video":
+			// Тип из заголовка проверяется раньше размера
+			w.Header().Set("Content-Type", "video/mp4")
+			w.Write([]byte(large))
+		case "/sniffed":
+			// Без заголовка тип определяется по телу
+			w.Header()["Content-Type"] = nil
+			w.Write([]byte("\x89PNG\r\n\x1a\n"))
+		}
+	}))
+	defer server.Close()
+
+	scraper := NewEthicalScraper(&Config{
+		HTTPTimeout:          30,
+		CacheTTL:             1,
+		UserAgent:            "Test-Bot/1.0",
+		AllowPrivateNetworks: true,
+		MaxBodySizeMB:        1,
+		AllowedContentTypes:  DefaultContentTypes,
+	})
+
+	for path, expected := range map[string]string{"/video": "video/mp4", "/sniffed": "image/png"} {
+		_, err := scraper.ScrapeURL(context.Background(), server.URL+path)
+		var unsupported *UnsupportedContentError
+		if !errors.As(err, &unsupported) || unsupported.ContentType != expected {
+			t.Errorf("Для %s ожидался неподдерживаемый тип %s, получено %v", path, expected, err)
+		}
+		if scraper.cache.GetStale(CacheKey(server.URL+path)) != nil {
+			t.Errorf("Отклоненный ответ %s не должен попадать в кэш", path)
+		}
+	}
+
+	// Изображения статьи загружаются с разрешенными типами image/*
+	ctx := withPageLimits(context.Background(), PageLimits{ContentTypes: imageContentTypes})
+	if _, err := scraper.ScrapeURL(ctx, server.URL+"/sniffed"); err != nil {
+		t.Errorf("Изображение должно загружаться: %v", err)
+	}
+}
+
+func TestCollyFetcherMaxBodySize(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte("<html><body>" + strings.Repeat("a", 2048) + "</body></html>"))
+	}))
+	defer server.Close()
+
+	config := DefaultCollyConfig()
+	config.Network = NewNetworkPolicy(true, nil)
+	config.MaxBodySize = 1024
+
+	_, err := NewCollyFetcher(config).Fetch(context.Background(), server.URL)
+	var tooLarge *BodyTooLargeError
+	if !errors.As(err, &tooLarge) {
+		t.Errorf("Ожидалась ошибка размера, получено %v", err)
+	}
+}
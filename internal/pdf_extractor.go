@@ -0,0 +1,382 @@
+package internal
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"net/url"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/ledongthuc/pdf"
+)
+
+// maxPDFPages ограничивает число страниц PDF документа, из которых извлекается текст
+const maxPDFPages = 500
+
+// pdfDateLayouts - варианты даты PDF (D:YYYYMMDDHHmmSSOHH'mm') после удаления "D:" и апострофов
+var pdfDateLayouts = []string{
+	"20060102150405-0700",
+	"20060102150405Z",
+	"20060102150405",
+	"200601021504",
+	"2006010215",
+	"20060102",
+	"200601",
+	"2006",
+}
+
+// pdfBullets - маркеры списков, которые в PDF набраны символами
+var pdfBullets = []string{"•", "◦", "▪", "‣", "●", "○", "■", "–", "—"}
+
+// pdfPageNumberPattern находит строки, состоящие только из номера страницы
+var pdfPageNumberPattern = regexp.MustCompile(`^(?i:(page|стр\.?|страница)\s*)?\d+(\s*(/|of|из)\s*\d+)?$`)
+
+// pdfGeneratedTitlePattern находит заголовки, подставленные программой вместо названия документа
+var pdfGeneratedTitlePattern = regexp.MustCompile(`(?i)^(microsoft (word|powerpoint) - |untitled|.*\.(docx?|pptx?|pdf|tex|dvi)$)`)
+
+// pdfLine - строка текста PDF страницы
+type pdfLine struct {
+	Text     string
+	FontSize float64
+	Y        float64
+}
+
+// parsePDFContent извлекает текст, заголовки и метаданные PDF документа
+func parsePDFContent(body []byte, finalURL string) (content *Content, err error) {
+	// Библиотека паникует на поврежденных документах
+	defer func() {
+		if r := recover(); r != nil {
+			content = nil
+			err = fmt.Errorf("ошибка при чтении PDF: %v", r)
+		}
+	}()
+
+	reader, err := pdf.NewReader(bytes.NewReader(body), int64(len(body)))
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при чтении PDF: %w", err)
+	}
+
+	info := reader.Trailer().Key("Info")
+	content = &Content{
+		URL:         finalURL,
+		Title:       pdfInfoText(info, "Title"),
+		Author:      pdfInfoText(info, "Author"),
+		Description: pdfInfoText(info, "Subject"),
+		Tags:        mergeTags(nil, strings.FieldsFunc(pdfInfoText(info, "Keywords"), isPDFKeywordSeparator)),
+		Published:   parsePDFDate(pdfInfoText(info, "CreationDate")),
+		Modified:    parsePDFDate(pdfInfoText(info, "ModDate")),
+	}
+	content.Date = formatContentDate(content.Published)
+
+	var lines []pdfLine
+	pages := reader.NumPage()
+	if pages > maxPDFPages {
+		pages = maxPDFPages
+	}
+	for i := 1; i <= pages; i++ {
+		page := reader.Page(i)
+		if page.V.IsNull() {
+			continue
+		}
+		lines = append(lines, pdfPageLines(page)...)
+	}
+
+	headings := pdfOutlineLevels(reader.Outline(), 0, make(map[string]int))
+	content.Markdown = pdfLinesToMarkdown(lines, headings)
+	if strings.TrimSpace(content.Markdown) == "" {
+		return nil, fmt.Errorf("в PDF документе нет текста (возможно, это скан)")
+	}
+
+	if content.Title == "" || pdfGeneratedTitlePattern.MatchString(content.Title) {
+		content.Title = firstNonEmpty(firstMarkdownHeading(content.Markdown), pdfFileTitle(finalURL), content.Title)
+	}
+
+	return content, nil
+}
+
+// pdfInfoText возвращает текстовое поле словаря Info без лишних пробелов
+func pdfInfoText(info pdf.Value, key string) string {
+	return strings.Join(strings.Fields(info.Key(key).Text()), " ")
+}
+
+// isPDFKeywordSeparator определяет разделители ключевых слов: запятая или точка с запятой
+func isPDFKeywordSeparator(r rune) bool {
+	return r == ',' || r == ';'
+}
+
+// parsePDFDate разбирает дату PDF вида D:20240315103000+03'00'
+func parsePDFDate(raw string) time.Time {
+	raw = strings.TrimPrefix(strings.TrimSpace(raw), "D:")
+	raw = strings.ReplaceAll(raw, "'", "")
+	// Z00'00' означает UTC, смещение после Z не нужно
+	if i := strings.IndexByte(raw, 'Z'); i >= 0 {
+		raw = raw[:i+1]
+	}
+	if raw == "" {
+		return time.Time{}
+	}
+
+	for _, layout := range pdfDateLayouts {
+		if date, err := time.Parse(layout, raw); err == nil {
+			return date
+		}
+	}
+	return time.Time{}
+}
+
+// pdfPageLines собирает символы страницы в строки в порядке их вывода
+func pdfPageLines(page pdf.Page) []pdfLine {
+	var lines []pdfLine
+	var current strings.Builder
+	var line pdfLine
+	var lastEnd float64
+	started := false
+
+	flush := func() {
+		if text := strings.TrimSpace(current.String()); text != "" {
+			line.Text = strings.Join(strings.Fields(text), " ")
+			lines = append(lines, line)
+		}
+		current.Reset()
+		started = false
+	}
+
+	for _, text := range page.Content().Text {
+		size := math.Abs(text.FontSize)
+		if size == 0 {
+			size = 1
+		}
+
+		if started && math.Abs(text.Y-line.Y) > size/2 {
+			flush()
+		}
+
+		if !started {
+			line = pdfLine{FontSize: size, Y: text.Y}
+			started = true
+		} else if lastEnd > 0 && text.X-lastEnd > size*0.2 {
+			// Пробелы часто не набраны символом, а заданы смещением
+			current.WriteByte(' ')
+		}
+
+		current.WriteString(text.S)
+		if size > line.FontSize {
+			line.FontSize = size
+		}
+
+		lastEnd = 0
+		if text.W > 0 {
+			lastEnd = text.X + text.W
+		}
+	}
+	flush()
+
+	// Номера страниц в колонтитулах не относятся к тексту
+	filtered := lines[:0]
+	for _, l := range lines {
+		if !pdfPageNumberPattern.MatchString(l.Text) {
+			filtered = append(filtered, l)
+		}
+	}
+	return filtered
+}
+
+// pdfOutlineLevels собирает заголовки из оглавления PDF с уровнем вложенности
+func pdfOutlineLevels(outline pdf.Outline, depth int, levels map[string]int) map[string]int {
+	if title := strings.ToLower(strings.Join(strings.Fields(outline.Title), " ")); title != "" {
+		if _, exists := levels[title]; !exists {
+			levels[title] = depth
+		}
+	}
+	for _, child := range outline.Child {
+		pdfOutlineLevels(child, depth+1, levels)
+	}
+	return levels
+}
+
+// pdfBodyFontSize определяет размер шрифта основного текста: самый частый по числу символов
+func pdfBodyFontSize(lines []pdfLine) float64 {
+	counts := make(map[float64]int)
+	for _, line := range lines {
+		counts[math.Round(line.FontSize*2)/2] += len([]rune(line.Text))
+	}
+
+	sizes := make([]float64, 0, len(counts))
+	for size := range counts {
+		sizes = append(sizes, size)
+	}
+	sort.Float64s(sizes)
+
+	body, best := 0.0, -1
+	for _, size := range sizes {
+		if counts[size] > best {
+			body, best = size, counts[size]
+		}
+	}
+	return body
+}
+
+// pdfHeadingLevel определяет уровень заголовка строки, 0 - обычный текст.
+// Заголовок документа выводится как #, поэтому разделы начинаются с ##
+func pdfHeadingLevel(line pdfLine, bodySize float64, outline map[string]int) int {
+	if depth, ok := outline[strings.ToLower(line.Text)]; ok {
+		return min(depth+1, 4)
+	}
+
+	if bodySize == 0 || len([]rune(line.Text)) > 120 {
+		return 0
+	}
+	switch ratio := line.FontSize / bodySize; {
+	case ratio >= 1.5:
+		return 2
+	case ratio >= 1.15:
+		return 3
+	default:
+		return 0
+	}
+}
+
+// pdfLinesToMarkdown собирает строки в абзацы, заголовки и списки markdown
+func pdfLinesToMarkdown(lines []pdfLine, outline map[string]int) string {
+	bodySize := pdfBodyFontSize(lines)
+
+	var blocks []string
+	var paragraph []string
+	var previous *pdfLine
+
+	flush := func() {
+		if len(paragraph) > 0 {
+			blocks = append(blocks, joinPDFLines(paragraph))
+			paragraph = nil
+		}
+	}
+
+	for i := range lines {
+		line := lines[i]
+
+		if level := pdfHeadingLevel(line, bodySize, outline); level > 0 {
+			flush()
+			// Заголовок, перенесенный на несколько строк, собираем в один
+			if previous != nil && len(blocks) > 0 && pdfHeadingLevel(*previous, bodySize, outline) == level &&
+				strings.HasPrefix(blocks[len(blocks)-1], strings.Repeat("#", level)+" ") &&
+				previous.Y-line.Y < line.FontSize*1.6 && previous.Y > line.Y {
+				blocks[len(blocks)-1] += " " + escapePDFText(line.Text)
+			} else {
+				blocks = append(blocks, strings.Repeat("#", level)+" "+escapePDFText(line.Text))
+			}
+			previous = &lines[i]
+			continue
+		}
+
+		text, bullet := pdfListItem(line.Text)
+		if bullet || (previous != nil && pdfParagraphBreak(*previous, line, bodySize)) {
+			flush()
+		}
+		if bullet {
+			text = "- " + text
+		}
+
+		paragraph = append(paragraph, text)
+		previous = &lines[i]
+	}
+	flush()
+
+	return strings.Join(blocks, "\n\n")
+}
+
+// pdfParagraphBreak определяет начало нового абзаца по расстоянию между строками
+func pdfParagraphBreak(previous, line pdfLine, bodySize float64) bool {
+	size := math.Max(line.FontSize, bodySize)
+	gap := previous.Y - line.Y
+
+	// Переход на новую страницу или колонку: абзац продолжается,
+	// только если предыдущая строка не закончила предложение
+	if gap < 0 {
+		return strings.ContainsAny(lastRune(previous.Text), ".!?:") || !startsLower(line.Text)
+	}
+	return gap > size*1.8
+}
+
+// pdfListItem убирает маркер списка, набранный символом
+func pdfListItem(text string) (string, bool) {
+	for _, bullet := range pdfBullets {
+		if rest, ok := strings.CutPrefix(text, bullet); ok && strings.HasPrefix(rest, " ") {
+			return escapePDFText(strings.TrimSpace(rest)), true
+		}
+	}
+	return escapePDFText(text), false
+}
+
+// joinPDFLines склеивает строки абзаца, убирая переносы слов
+func joinPDFLines(lines []string) string {
+	var result strings.Builder
+	for i, line := range lines {
+		if i > 0 {
+			previous := result.String()
+			if strings.HasSuffix(previous, "-") && startsLower(line) && !strings.HasSuffix(previous, " -") {
+				result.Reset()
+				result.WriteString(strings.TrimSuffix(previous, "-"))
+			} else {
+				result.WriteByte(' ')
+			}
+		}
+		result.WriteString(line)
+	}
+	return result.String()
+}
+
+// escapePDFText экранирует символы, которые markdown принял бы за разметку
+func escapePDFText(text string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, "*", `\*`, "_", `\_`, "`", "\\`", "[", `\[`, "]", `\]`, "<", `\<`)
+	text = replacer.Replace(text)
+	if strings.HasPrefix(text, "#") || strings.HasPrefix(text, ">") ||
+		strings.HasPrefix(text, "- ") || strings.HasPrefix(text, "+ ") {
+		text = `\` + text
+	}
+	return text
+}
+
+// lastRune возвращает последний символ строки
+func lastRune(text string) string {
+	runes := []rune(text)
+	if len(runes) == 0 {
+		return ""
+	}
+	return string(runes[len(runes)-1])
+}
+
+// startsLower проверяет, что строка начинается со строчной буквы
+func startsLower(text string) bool {
+	for _, r := range text {
+		return unicode.IsLower(r)
+	}
+	return false
+}
+
+// firstMarkdownHeading возвращает текст первого заголовка markdown
+func firstMarkdownHeading(markdown string) string {
+	for _, line := range strings.Split(markdown, "\n") {
+		if strings.HasPrefix(line, "#") {
+			return strings.ReplaceAll(strings.TrimSpace(strings.TrimLeft(line, "#")), `\`, "")
+		}
+	}
+	return ""
+}
+
+// pdfFileTitle возвращает имя файла из ссылки на документ
+func pdfFileTitle(pageURL string) string {
+	u, err := url.Parse(pageURL)
+	if err != nil {
+		return ""
+	}
+	name := strings.TrimSuffix(path.Base(u.Path), path.Ext(u.Path))
+	if name == "." || name == "/" {
+		return ""
+	}
+	return strings.NewReplacer("_", " ", "-", " ").Replace(name)
+}
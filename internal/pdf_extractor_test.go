@@ -0,0 +1,143 @@
+package internal
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+// buildTestPDF собирает минимальный PDF документ со шрифтом Helvetica,
+// словарем Info и страницами с заданными потоками команд
+func buildTestPDF(info string, pages ...string) []byte {
+	var objects []string
+	objects = append(objects, "<< /Type /Catalog /Pages 2 0 R >>")
+
+	fontID := 3
+	infoID := 4
+	kids := make([]string, len(pages))
+	for i := range pages {
+		kids[i] = fmt.Sprintf("%d 0 R", 5+2*i)
+	}
+	objects = append(objects,
+		fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(pages)),
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>",
+		"<< "+info+" >>",
+	)
+	for i, stream := range pages {
+		objects = append(objects,
+			fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 612 792] /Resources << /Font << /F1 %d 0 R >> >> /Contents %d 0 R >>", fontID, 6+2*i),
+			fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(stream)+1, stream),
+		)
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, object := range objects {
+		offsets[i] = buf.Len()
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", i+1, object)
+	}
+
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R /Info %d 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, infoID, xref)
+
+	return buf.Bytes()
+}
+
+// pdfTextLine возвращает команды вывода строки текста шрифтом заданного размера
+func pdfTextLine(size, x, y int, text string) string {
+	return fmt.Sprintf("BT /F1 %d Tf %d %d Td (%s) Tj ET\n", size, x, y, text)
+}
+
+func testReportPDF(title string) []byte {
+	return buildTestPDF(
+		"/Title ("+title+") /Author (Anna Petrova) /Subject (Annual results) /Keywords (finance, report) /CreationDate (D:20240315103000+03'00')",
+		pdfTextLine(24, 72, 720, "Introduction")+
+			pdfTextLine(12, 72, 690, "First line of the para-")+
+			pdfTextLine(12, 72, 676, "graph continues here.")+
+			pdfTextLine(12, 72, 640, `\225 First item`)+
+			pdfTextLine(12, 300, 40, "1"),
+		pdfTextLine(12, 72, 720, "Second page text with *stars*."),
+	)
+}
+
+func TestExtractContentFromPDF(t *testing.T) {
+	f := &imageFetcher{responses: map[string]*FetchResult{
+		"https://example.com/files/report.pdf": imageResult("application/octet-stream", testReportPDF("Annual Report")),
+	}}
+
+	config := DefaultCollyConfig()
+	config.Fetcher = f
+
+	content, err := ExtractContentWithConfig(context.Background(), "https://example.com/files/report.pdf", config)
+	if err != nil {
+		t.Fatalf("Ошибка извлечения PDF: %v", err)
+	}
+
+	if content.Title != "Annual Report" || content.Author != "Anna Petrova" || content.Description != "Annual results" {
+		t.Errorf("Метаданные PDF должны переноситься в контент: %+v", content)
+	}
+	if !content.Published.Equal(time.Date(2024, 3, 15, 7, 30, 0, 0, time.UTC)) || content.Date == "" {
+		t.Errorf("Неверная дата создания: %v (%q)", content.Published, content.Date)
+	}
+	if strings.Join(content.Tags, "|") != "finance|report" {
+		t.Errorf("Неверные ключевые слова: %v", content.Tags)
+	}
+
+	expected := "## Introduction\n\n" +
+		"First line of the paragraph continues here.\n\n" +
+		"- First item\n\n" +
+		`Second page text with \*stars\*.`
+	if content.Markdown != expected {
+		t.Errorf("Неверный markdown:\n%s\nожидалось:\n%s", content.Markdown, expected)
+	}
+
+	markdown := ConvertToMarkdown(context.Background(), content, content.URL, locales["en"])
+	if !strings.Contains(markdown, "# Annual Report") || !strings.Contains(markdown, "First item") {
+		t.Errorf("PDF должен оформляться как обычная статья:\n%s", markdown)
+	}
+}
+
+func TestParsePDFContentTitleFallback(t *testing.T) {
+	content, err := parsePDFContent(testReportPDF("Microsoft Word - draft.docx"), "https://example.com/report.pdf")
+	if err != nil {
+		t.Fatalf("Ошибка извлечения PDF: %v", err)
+	}
+	if content.Title != "Introduction" {
+		t.Errorf("Вместо сгенерированного заголовка ожидался первый заголовок, получено %q", content.Title)
+	}
+
+	if _, err := parsePDFContent([]byte("%PDF-1.4 поврежденный файл"), "https://example.com/broken.pdf"); err == nil {
+		t.Error("Поврежденный PDF должен возвращать ошибку")
+	}
+}
+
+func TestParsePDFDate(t *testing.T) {
+	tests := []struct {
+		raw      string
+		expected string
+	}{
+		{"D:20240315103000+03'00'", "2024-03-15T10:30:00+03:00"},
+		{"D:20240315103000Z00'00'", "2024-03-15T10:30:00Z"},
+		{"D:20240315103000Z", "2024-03-15T10:30:00Z"},
+		{"D:20240315", "2024-03-15T00:00:00Z"},
+		{"20231201120000", "2023-12-01T12:00:00Z"},
+	}
+
+	for _, tt := range tests {
+		if got := parsePDFDate(tt.raw); got.Format(time.RFC3339) != tt.expected {
+			t.Errorf("parsePDFDate(%q) = %s, ожидалось %s", tt.raw, got.Format(time.RFC3339), tt.expected)
+		}
+	}
+
+	if !parsePDFDate("вчера").IsZero() {
+		t.Error("Некорректная дата должна давать нулевое время")
+	}
+}
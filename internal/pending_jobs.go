@@ -0,0 +1,80 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// pendingJob - задача, не завершенная к остановке бота
+type pendingJob struct {
+	UpdateID int               `json:"update_id"`
+	Message  *tgbotapi.Message `json:"message"`
+}
+
+// savePendingJobs сохраняет незавершенные задачи, чтобы обработать их после
+// перезапуска. Telegram не доставит эти обновления повторно: на webhook уже получен ответ
+func savePendingJobs(path string, jobs []*Job) error {
+	if path == "" || len(jobs) == 0 {
+		return nil
+	}
+
+	pending := make([]pendingJob, 0, len(jobs))
+	for _, job := range jobs {
+		pending = append(pending, pendingJob{UpdateID: job.UpdateID, Message: job.Message})
+	}
+	sort.Slice(pending, func(i, j int) bool {
+		return pending[i].UpdateID < pending[j].UpdateID
+	})
+
+	data, err := json.Marshal(pending)
+	if err != nil {
+		return fmt.Errorf("ошибка сериализации задач: %w", err)
+	}
+	return writeFileAtomic(path, data)
+}
+
+// loadPendingJobs читает сохраненные задачи. Файл остается на месте, пока
+// задачи не приняты в очередь: его удаляет removePendingJobs
+func loadPendingJobs(path string) ([]*Job, error) {
+	if path == "" {
+		return nil, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("ошибка чтения %s: %w", path, err)
+	}
+
+	var pending []pendingJob
+	if err := json.Unmarshal(data, &pending); err != nil {
+		return nil, fmt.Errorf("ошибка разбора %s: %w", path, err)
+	}
+
+	jobs := make([]*Job, 0, len(pending))
+	for _, job := range pending {
+		if job.Message == nil || job.Message.Chat == nil {
+			continue
+		}
+		jobs = append(jobs, &Job{UpdateID: job.UpdateID, Message: job.Message})
+	}
+	return jobs, nil
+}
+
+// removePendingJobs удаляет файл задач, чтобы они не выполнялись повторно
+// при следующем перезапуске
+func removePendingJobs(path string) error {
+	if path == "" {
+		return nil
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("ошибка удаления %s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,252 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+func pendingTestMessage(text string) *tgbotapi.Message {
+	return &tgbotapi.Message{
+		Text: text,
+		Chat: &tgbotapi.Chat{ID: 42},
+		From: &tgbotapi.User{ID: 42, LanguageCode: "ru"},
+	}
+}
+
+func TestPendingJobsRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pending.json")
+
+	jobs := []*Job{
+		{UpdateID: 7, Message: pendingTestMessage("https://example.com/b")},
+		{UpdateID: 3, Message: pendingTestMessage("https://example.com/a")},
+	}
+	if err := savePendingJobs(path, jobs); err != nil {
+		t.Fatalf("Ошибка сохранения: %v", err)
+	}
+
+	loaded, err := loadPendingJobs(path)
+	if err != nil {
+		t.Fatalf("Ошибка загрузки: %v", err)
+	}
+	if len(loaded) != 2 || loaded[0].UpdateID != 3 || loaded[1].Message.Text != "https://example.com/b" {
+		t.Fatalf("Задачи должны восстанавливаться в порядке обновлений: %+v", loaded)
+	}
+	if loaded[0].Message.From.LanguageCode != "ru" {
+		t.Error("Язык пользователя должен сохраняться для локализации ответа")
+	}
+
+	// Файл удаляется только после того, как задачи приняты
+	if _, err := os.Stat(path); err != nil {
+		t.Errorf("Файл задач не должен удаляться при загрузке: %v", err)
+	}
+	if err := removePendingJobs(path); err != nil {
+		t.Fatalf("Ошибка удаления: %v", err)
+	}
+	if again, err := loadPendingJobs(path); err != nil || len(again) != 0 {
+		t.Errorf("Без файла задач не должно быть: %v, %v", again, err)
+	}
+}
+
+func TestDispatcherShutdownSavesPendingJobs(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pending.json")
+
+	d := NewDispatcher(nil, &Config{
+		WorkerCount:     1,
+		JobQueueSize:    10,
+		DedupTTL:        1,
+		DedupMaxEntries: 10,
+		PendingJobsFile: path,
+	})
+
+	started := make(chan struct{}, 1)
+	d.pool = NewWorkerPool(1, 10, func(job *Job) {
+		defer d.jobs.remove(messageUserID(job.Message), job)
+		if job.Context().Err() != nil {
+			return
+		}
+		started <- struct{}{}
+		<-job.Context().Done()
+	})
+	d.Start()
+
+	d.enqueue(&Job{UpdateID: 1, Message: pendingTestMessage("https://example.com/slow")})
+	d.enqueue(&Job{UpdateID: 2, Message: pendingTestMessage("https://example.com/queued")})
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	if err := d.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Ожидалось истечение времени остановки, получено %v", err)
+	}
+
+	jobs, err := loadPendingJobs(path)
+	if err != nil {
+		t.Fatalf("Ошибка загрузки задач: %v", err)
+	}
+	if len(jobs) != 2 || jobs[0].UpdateID != 1 || jobs[1].UpdateID != 2 {
+		t.Errorf("Выполняемая и ожидающая задачи должны быть сохранены: %+v", jobs)
+	}
+}
+
+func TestDispatcherShutdownSkipsSentJobs(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pending.json")
+
+	d := NewDispatcher(nil, &Config{
+		WorkerCount:     1,
+		JobQueueSize:    10,
+		DedupTTL:        1,
+		DedupMaxEntries: 10,
+		PendingJobsFile: path,
+	})
+
+	sending := make(chan struct{}, 1)
+	d.pool = NewWorkerPool(1, 10, func(job *Job) {
+		defer d.jobs.remove(messageUserID(job.Message), job)
+		if job.Context().Err() != nil {
+			return
+		}
+		// Отправка файла началась и зависла
+		markSendStarted(job.Context())
+		sending <- struct{}{}
+		<-job.Context().Done()
+	})
+	d.Start()
+
+	d.enqueue(&Job{UpdateID: 1, Message: pendingTestMessage("https://example.com/sending")})
+	d.enqueue(&Job{UpdateID: 2, Message: pendingTestMessage("https://example.com/queued")})
+	<-sending
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	d.Shutdown(ctx)
+
+	jobs, err := loadPendingJobs(path)
+	if err != nil {
+		t.Fatalf("Ошибка загрузки задач: %v", err)
+	}
+	if len(jobs) != 1 || jobs[0].UpdateID != 2 {
+		t.Errorf("Задача, начавшая отправку файла, не должна сохраняться: %+v", jobs)
+	}
+}
+
+func TestDispatcherShutdownDrainsJobs(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pending.json")
+
+	d := NewDispatcher(nil, &Config{
+		WorkerCount:     1,
+		JobQueueSize:    10,
+		DedupTTL:        1,
+		DedupMaxEntries: 10,
+		PendingJobsFile: path,
+	})
+
+	processed := 0
+	d.pool = NewWorkerPool(1, 10, func(job *Job) {
+		defer d.jobs.remove(messageUserID(job.Message), job)
+		time.Sleep(10 * time.Millisecond)
+		processed++
+	})
+	d.Start()
+
+	for i := 1; i <= 3; i++ {
+		d.enqueue(&Job{UpdateID: i, Message: pendingTestMessage("https://example.com")})
+	}
+
+	if err := d.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Ошибка остановки: %v", err)
+	}
+	if processed != 3 {
+		t.Errorf("Принятые задачи должны обрабатываться до остановки, обработано %d", processed)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Error("Без незавершенных задач файл не создается")
+	}
+}
+
+func TestDispatcherRestoresPendingJobsBeyondQueue(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pending.json")
+
+	var saved []*Job
+	for i := 1; i <= 5; i++ {
+		saved = append(saved, &Job{UpdateID: i, Message: pendingTestMessage("https://example.com")})
+	}
+	if err := savePendingJobs(path, saved); err != nil {
+		t.Fatalf("Ошибка сохранения: %v", err)
+	}
+
+	d := NewDispatcher(&tgbotapi.BotAPI{}, &Config{
+		WorkerCount:     1,
+		JobQueueSize:    1,
+		DedupTTL:        1,
+		DedupMaxEntries: 10,
+		PendingJobsFile: path,
+	})
+
+	var processed []int
+	d.pool = NewWorkerPool(1, 1, func(job *Job) {
+		defer d.jobs.remove(messageUserID(job.Message), job)
+		time.Sleep(5 * time.Millisecond)
+		processed = append(processed, job.UpdateID)
+	})
+	d.Start()
+
+	if err := d.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Ошибка остановки: %v", err)
+	}
+	if len(processed) != 5 {
+		t.Errorf("Все восстановленные задачи должны быть обработаны, обработаны %v", processed)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Error("Файл задач должен удаляться после того, как все задачи приняты")
+	}
+}
+
+func TestDispatcherKeepsUnrestoredPendingJobs(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pending.json")
+
+	var saved []*Job
+	for i := 1; i <= 4; i++ {
+		saved = append(saved, &Job{UpdateID: i, Message: pendingTestMessage("https://example.com")})
+	}
+	if err := savePendingJobs(path, saved); err != nil {
+		t.Fatalf("Ошибка сохранения: %v", err)
+	}
+
+	restoreTimeout := pendingRestoreTimeout
+	pendingRestoreTimeout = 50 * time.Millisecond
+	defer func() { pendingRestoreTimeout = restoreTimeout }()
+
+	d := NewDispatcher(&tgbotapi.BotAPI{}, &Config{
+		WorkerCount:     1,
+		JobQueueSize:    1,
+		DedupTTL:        1,
+		DedupMaxEntries: 10,
+		PendingJobsFile: path,
+	})
+
+	// Обработчик занят первой задачей, в очередь помещается только вторая
+	release := make(chan struct{})
+	d.pool = NewWorkerPool(1, 1, func(job *Job) {
+		defer d.jobs.remove(messageUserID(job.Message), job)
+		<-release
+	})
+	d.Start()
+
+	jobs, err := loadPendingJobs(path)
+	if err != nil {
+		t.Fatalf("Ошибка загрузки задач: %v", err)
+	}
+	if len(jobs) != 2 || jobs[0].UpdateID != 3 || jobs[1].UpdateID != 4 {
+		t.Errorf("Не принятые в очередь задачи должны остаться в файле: %+v", jobs)
+	}
+
+	close(release)
+	d.Stop()
+}
@@ -0,0 +1,136 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Poller получает обновления через long polling (getUpdates)
+type Poller struct {
+	bot        *tgbotapi.BotAPI
+	dispatcher *Dispatcher
+	timeout    int
+	done       chan struct{}
+
+	// В режиме polling нет webhook сервера, поэтому метрики и проверка
+	// здоровья отдаются отдельным сервером
+	metricsPort  string
+	metricsToken string
+	server       *http.Server
+}
+
+// NewPoller создает обработчик long polling
+func NewPoller(bot *tgbotapi.BotAPI, config *Config, dispatcher *Dispatcher) *Poller {
+	return &Poller{
+		bot:        bot,
+		dispatcher: dispatcher,
+		timeout:    config.PollingTimeout,
+
+		metricsPort:  config.MetricsPort,
+		metricsToken: config.MetricsToken,
+	}
+}
+
+// Start удаляет webhook и запускает цикл получения обновлений
+func (p *Poller) Start() error {
+	if p.bot == nil {
+		return fmt.Errorf("polling режим требует настоящего бота")
+	}
+
+	// getUpdates не работает, пока установлен webhook
+	if _, err := p.bot.Request(tgbotapi.DeleteWebhookConfig{}); err != nil {
+		return fmt.Errorf("не удалось удалить webhook: %w", err)
+	}
+
+	if err := p.startStatusServer(); err != nil {
+		return err
+	}
+
+	u := tgbotapi.NewUpdate(0)
+	u.Timeout = p.timeout
+	updates := p.bot.GetUpdatesChan(u)
+
+	p.done = make(chan struct{})
+	go func() {
+		defer close(p.done)
+		for update := range updates {
+			if update.Message != nil {
+				logger.Infof("Получено сообщение от пользователя %d", update.Message.Chat.ID)
+			}
+			p.dispatcher.Dispatch(update)
+		}
+	}()
+
+	logger.Infof("Polling запущен (таймаут %d сек)", p.timeout)
+	return nil
+}
+
+// Stop останавливает получение обновлений. Текущий запрос getUpdates может
+// длиться до таймаута polling, поэтому ожидание ограничено ctx
+func (p *Poller) Stop(ctx context.Context) {
+	if p.bot == nil || p.done == nil {
+		return
+	}
+
+	p.bot.StopReceivingUpdates()
+	select {
+	case <-p.done:
+		logger.Info("Polling остановлен")
+	case <-ctx.Done():
+		logger.Warn("Polling не остановился вовремя, последний запрос getUpdates прерывается")
+	}
+
+	if p.server != nil {
+		// Проверке здоровья и метрикам отвечать нечего, соединения закрываем сразу
+		p.server.Close()
+	}
+}
+
+// statusHandler возвращает маршруты /healthz и /metrics
+func (p *Poller) statusHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", p.handleHealth)
+	mux.Handle("/metrics", MetricsHandler(p.metricsToken))
+	return mux
+}
+
+// startStatusServer запускает сервер метрик и проверки здоровья, если задан порт
+func (p *Poller) startStatusServer() error {
+	if p.metricsPort == "" {
+		return nil
+	}
+
+	addr := "0.0.0.0:" + p.metricsPort
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("не удалось запустить сервер метрик на %s: %w", addr, err)
+	}
+
+	p.server = &http.Server{Handler: p.statusHandler()}
+	go func() {
+		if err := p.server.Serve(listener); err != nil && err != http.ErrServerClosed {
+			logger.Errorf("Ошибка сервера метрик: %v", err)
+		}
+	}()
+
+	logger.Infof("Метрики и проверка здоровья доступны на %s", addr)
+	return nil
+}
+
+// handleHealth обрабатывает проверку здоровья в режиме polling
+func (p *Poller) handleHealth(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	response := map[string]interface{}{
+		"status": "healthy",
+		"mode":   "polling",
+	}
+
+	json.NewEncoder(w).Encode(response)
+}
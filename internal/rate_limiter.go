@@ -0,0 +1,304 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaultIdleTTL - через сколько неактивный домен удаляется из лимитера
+const defaultIdleTTL = 10 * time.Minute
+
+// RatePolicy описывает ограничение частоты запросов (token bucket)
+type RatePolicy struct {
+	Interval time.Duration // время восстановления одного токена, 0 - без ограничения
+	Burst    int           // сколько запросов можно выполнить подряд
+}
+
+// tokenBucket хранит состояние корзины токенов
+type tokenBucket struct {
+	policy   RatePolicy
+	tokens   float64
+	updated  time.Time
+	lastUsed time.Time
+}
+
+// newTokenBucket создает полную корзину
+func newTokenBucket(policy RatePolicy, now time.Time) *tokenBucket {
+	return &tokenBucket{
+		policy:   policy,
+		tokens:   float64(policy.burst()),
+		updated:  now,
+		lastUsed: now,
+	}
+}
+
+// burst возвращает размер корзины не меньше одного токена
+func (p RatePolicy) burst() int {
+	if p.Burst < 1 {
+		return 1
+	}
+	return p.Burst
+}
+
+// refill пополняет корзину за прошедшее время
+func (b *tokenBucket) refill(now time.Time) {
+	if b.policy.Interval <= 0 {
+		b.tokens = float64(b.policy.burst())
+		b.updated = now
+		return
+	}
+
+	elapsed := now.Sub(b.updated)
+	if elapsed > 0 {
+		b.tokens += float64(elapsed) / float64(b.policy.Interval)
+		if capacity := float64(b.policy.burst()); b.tokens > capacity {
+			b.tokens = capacity
+		}
+		b.updated = now
+	}
+}
+
+// reserve забирает токен и возвращает, сколько нужно подождать до его появления
+func (b *tokenBucket) reserve(now time.Time) time.Duration {
+	b.refill(now)
+	b.lastUsed = now
+
+	if b.policy.Interval <= 0 {
+		return 0
+	}
+
+	// Отрицательный баланс означает очередь из уже зарезервированных запросов
+	b.tokens--
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens * float64(b.policy.Interval))
+}
+
+// cancel возвращает токен при отмене ожидания
+func (b *tokenBucket) cancel() {
+	if b.policy.Interval > 0 {
+		b.tokens++
+	}
+}
+
+// idle проверяет, что корзина полна и давно не использовалась
+func (b *tokenBucket) idle(now time.Time, ttl time.Duration) bool {
+	b.refill(now)
+	return now.Sub(b.lastUsed) > ttl && b.tokens >= float64(b.policy.burst())
+}
+
+// crawlDelay хранит интервал, запрошенный сайтом, и время последнего обращения к нему
+type crawlDelay struct {
+	interval time.Duration
+	lastUsed time.Time
+}
+
+// RateLimiter ограничивает частоту запросов к доменам и общий поток запросов
+type RateLimiter struct {
+	mu          sync.Mutex
+	policy      RatePolicy             // политика по умолчанию
+	overrides   map[string]RatePolicy  // политики для отдельных доменов
+	crawlDelays map[string]*crawlDelay // интервалы, запрошенные сайтами (Crawl-delay)
+	buckets     map[string]*tokenBucket
+	global      *tokenBucket // nil - без общего ограничения
+	idleTTL     time.Duration
+	lastSweep   time.Time
+}
+
+// NewRateLimiter создает лимитер с политикой по умолчанию и общим ограничением
+func NewRateLimiter(policy RatePolicy, global RatePolicy) *RateLimiter {
+	now := time.Now()
+
+	rl := &RateLimiter{
+		policy:      policy,
+		overrides:   make(map[string]RatePolicy),
+		crawlDelays: make(map[string]*crawlDelay),
+		buckets:     make(map[string]*tokenBucket),
+		idleTTL:     defaultIdleTTL,
+		lastSweep:   now,
+	}
+
+	if global.Interval > 0 {
+		rl.global = newTokenBucket(global, now)
+	}
+
+	return rl
+}
+
+// SetDomainPolicy задает политику для домена и его поддоменов
+func (rl *RateLimiter) SetDomainPolicy(domain string, policy RatePolicy) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	rl.overrides[strings.ToLower(domain)] = policy
+	rl.resetBuckets()
+}
+
+// SetDomainInterval задает минимальный интервал между запросами к домену
+func (rl *RateLimiter) SetDomainInterval(domain string, interval time.Duration) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	domain = strings.ToLower(domain)
+	now := time.Now()
+	rl.sweep(now)
+
+	if delay, exists := rl.crawlDelays[domain]; exists {
+		delay.lastUsed = now
+		if delay.interval == interval {
+			return
+		}
+		delay.interval = interval
+	} else {
+		rl.crawlDelays[domain] = &crawlDelay{interval: interval, lastUsed: now}
+	}
+
+	if bucket, exists := rl.buckets[domain]; exists {
+		bucket.policy = rl.policyFor(domain)
+	}
+}
+
+// Wait ожидает разрешения на запрос к домену или отмены контекста
+func (rl *RateLimiter) Wait(ctx context.Context, domain string) error {
+	domain = strings.ToLower(domain)
+
+	rl.mu.Lock()
+	now := time.Now()
+	rl.sweep(now)
+
+	bucket, exists := rl.buckets[domain]
+	if !exists {
+		bucket = newTokenBucket(rl.policyFor(domain), now)
+		rl.buckets[domain] = bucket
+	}
+	if delay, exists := rl.crawlDelays[domain]; exists {
+		delay.lastUsed = now
+	}
+
+	wait := bucket.reserve(now)
+	if rl.global != nil {
+		if globalWait := rl.global.reserve(now); globalWait > wait {
+			wait = globalWait
+		}
+	}
+	rl.mu.Unlock()
+
+	if wait <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		// Возвращаем токены, чтобы отмененный запрос не задерживал следующие
+		rl.mu.Lock()
+		bucket.cancel()
+		if rl.global != nil {
+			rl.global.cancel()
+		}
+		rl.mu.Unlock()
+		return ctx.Err()
+	}
+}
+
+// policyFor возвращает политику домена с учетом Crawl-delay
+func (rl *RateLimiter) policyFor(domain string) RatePolicy {
+	policy := rl.policy
+
+	if override, exists := rl.overrides[domain]; exists {
+		policy = override
+	} else {
+		for overrideDomain, override := range rl.overrides {
+			if strings.HasSuffix(domain, "."+overrideDomain) {
+				policy = override
+				break
+			}
+		}
+	}
+
+	// Crawl-delay требует паузы между каждыми двумя запросами
+	if delay, exists := rl.crawlDelays[domain]; exists && delay.interval > 0 {
+		if delay.interval > policy.Interval {
+			policy.Interval = delay.interval
+		}
+		policy.Burst = 1
+	}
+
+	return policy
+}
+
+// resetBuckets применяет новые политики к существующим корзинам
+func (rl *RateLimiter) resetBuckets() {
+	for domain, bucket := range rl.buckets {
+		bucket.policy = rl.policyFor(domain)
+	}
+}
+
+// sweep удаляет давно неактивные домены, чтобы карты не росли бесконечно
+func (rl *RateLimiter) sweep(now time.Time) {
+	if now.Sub(rl.lastSweep) < rl.idleTTL/2 {
+		return
+	}
+	rl.lastSweep = now
+
+	for domain, bucket := range rl.buckets {
+		if bucket.idle(now, rl.idleTTL) {
+			delete(rl.buckets, domain)
+		}
+	}
+
+	// Crawl-delay задается и для доменов без корзины, поэтому удаляется по своему времени
+	for domain, delay := range rl.crawlDelays {
+		if now.Sub(delay.lastUsed) > rl.idleTTL {
+			delete(rl.crawlDelays, domain)
+			if bucket, exists := rl.buckets[domain]; exists {
+				bucket.policy = rl.policyFor(domain)
+			}
+		}
+	}
+}
+
+// ParseDomainRatePolicies разбирает список вида "example.com=2s:3,news.ru=500ms"
+func ParseDomainRatePolicies(value string) (map[string]RatePolicy, error) {
+	policies := make(map[string]RatePolicy)
+
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		domain, spec, ok := strings.Cut(item, "=")
+		if !ok {
+			return nil, fmt.Errorf("некорректная политика %q, ожидается домен=интервал[:burst]", item)
+		}
+
+		intervalStr, burstStr, hasBurst := strings.Cut(spec, ":")
+		interval, err := time.ParseDuration(strings.TrimSpace(intervalStr))
+		if err != nil || interval < 0 {
+			return nil, fmt.Errorf("некорректный интервал в %q", item)
+		}
+
+		policy := RatePolicy{Interval: interval, Burst: 1}
+		if hasBurst {
+			burst, err := strconv.Atoi(strings.TrimSpace(burstStr))
+			if err != nil || burst < 1 {
+				return nil, fmt.Errorf("некорректный burst в %q", item)
+			}
+			policy.Burst = burst
+		}
+
+		policies[strings.ToLower(strings.TrimSpace(domain))] = policy
+	}
+
+	return policies, nil
+}
@@ -0,0 +1,173 @@
+package internal
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRateLimiterBurst(t *testing.T) {
+	limiter := NewRateLimiter(RatePolicy{Interval: time.Second, Burst: 3}, RatePolicy{})
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		if err := limiter.Wait(context.Background(), "example.com"); err != nil {
+			t.Fatalf("Запрос %d не должен возвращать ошибку: %v", i, err)
+		}
+	}
+
+	if elapsed := time.Since(start); elapsed > 100*time.Millisecond {
+		t.Errorf("Запросы в пределах burst не должны ждать, ждали %v", elapsed)
+	}
+}
+
+func TestRateLimiterContextCancel(t *testing.T) {
+	limiter := NewRateLimiter(RatePolicy{Interval: time.Hour, Burst: 1}, RatePolicy{})
+
+	if err := limiter.Wait(context.Background(), "example.com"); err != nil {
+		t.Fatalf("Первый запрос не должен возвращать ошибку: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	err := limiter.Wait(ctx, "example.com")
+	if err != context.DeadlineExceeded {
+		t.Errorf("Ожидалась ошибка context.DeadlineExceeded, получено %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Ожидание должно прерываться отменой контекста, ждали %v", elapsed)
+	}
+
+	// Отмененный запрос возвращает токен и не удлиняет очередь
+	if tokens := limiter.buckets["example.com"].tokens; tokens < -0.01 {
+		t.Errorf("Токен отмененного запроса должен быть возвращен, баланс %v", tokens)
+	}
+}
+
+func TestRateLimiterGlobalCap(t *testing.T) {
+	limiter := NewRateLimiter(RatePolicy{}, RatePolicy{Interval: 100 * time.Millisecond, Burst: 1})
+
+	start := time.Now()
+	if err := limiter.Wait(context.Background(), "a.com"); err != nil {
+		t.Fatalf("Неожиданная ошибка: %v", err)
+	}
+	if err := limiter.Wait(context.Background(), "b.com"); err != nil {
+		t.Fatalf("Неожиданная ошибка: %v", err)
+	}
+
+	if elapsed := time.Since(start); elapsed < 100*time.Millisecond {
+		t.Errorf("Общее ограничение должно действовать для разных доменов, ждали %v", elapsed)
+	}
+}
+
+func TestRateLimiterDomainPolicy(t *testing.T) {
+	limiter := NewRateLimiter(RatePolicy{Interval: time.Hour, Burst: 1}, RatePolicy{})
+	limiter.SetDomainPolicy("example.com", RatePolicy{Interval: time.Millisecond, Burst: 5})
+
+	if policy := limiter.policyFor("news.example.com"); policy.Burst != 5 {
+		t.Errorf("Политика домена должна действовать для поддоменов, получен burst %d", policy.Burst)
+	}
+	if policy := limiter.policyFor("other.com"); policy.Interval != time.Hour {
+		t.Errorf("Для других доменов ожидалась политика по умолчанию, получен интервал %v", policy.Interval)
+	}
+
+	limiter.SetDomainInterval("example.com", 2*time.Second)
+	policy := limiter.policyFor("example.com")
+	if policy.Interval != 2*time.Second || policy.Burst != 1 {
+		t.Errorf("Crawl-delay должен задавать интервал 2s и burst 1, получено %+v", policy)
+	}
+}
+
+func TestRateLimiterEvictsIdleDomains(t *testing.T) {
+	limiter := NewRateLimiter(RatePolicy{Interval: time.Millisecond, Burst: 1}, RatePolicy{})
+	limiter.idleTTL = 20 * time.Millisecond
+
+	if err := limiter.Wait(context.Background(), "old.com"); err != nil {
+		t.Fatalf("Неожиданная ошибка: %v", err)
+	}
+	limiter.SetDomainInterval("old.com", time.Millisecond)
+
+	time.Sleep(50 * time.Millisecond)
+
+	if err := limiter.Wait(context.Background(), "new.com"); err != nil {
+		t.Fatalf("Неожиданная ошибка: %v", err)
+	}
+
+	if _, exists := limiter.buckets["old.com"]; exists {
+		t.Error("Неактивный домен должен быть удален")
+	}
+	if _, exists := limiter.crawlDelays["old.com"]; exists {
+		t.Error("Crawl-delay неактивного домена должен быть удален")
+	}
+	if _, exists := limiter.buckets["new.com"]; !exists {
+		t.Error("Активный домен не должен удаляться")
+	}
+}
+
+func TestRateLimiterEvictsCrawlDelayWithoutBucket(t *testing.T) {
+	limiter := NewRateLimiter(RatePolicy{}, RatePolicy{})
+	limiter.idleTTL = 50 * time.Millisecond
+
+	// Crawl-delay задан, но запросов к домену не было
+	limiter.SetDomainInterval("robots-only.com", time.Second)
+	limiter.SetDomainInterval("active.com", time.Second)
+
+	time.Sleep(80 * time.Millisecond)
+	limiter.SetDomainInterval("active.com", time.Second)
+
+	// Следующее обращение выполняет очистку
+	limiter.lastSweep = time.Time{}
+	limiter.SetDomainInterval("other.com", time.Second)
+
+	if _, exists := limiter.crawlDelays["robots-only.com"]; exists {
+		t.Error("Crawl-delay домена без корзины должен удаляться по своему времени")
+	}
+	if _, exists := limiter.crawlDelays["active.com"]; !exists {
+		t.Error("Недавно обновленный Crawl-delay не должен удаляться")
+	}
+}
+
+func TestRateLimiterConcurrent(t *testing.T) {
+	limiter := NewRateLimiter(RatePolicy{Interval: 10 * time.Millisecond, Burst: 1}, RatePolicy{})
+
+	var wg sync.WaitGroup
+	start := time.Now()
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := limiter.Wait(context.Background(), "example.com"); err != nil {
+				t.Errorf("Неожиданная ошибка: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	// 5 запросов с burst 1 требуют минимум 4 интервала
+	if elapsed := time.Since(start); elapsed < 40*time.Millisecond {
+		t.Errorf("Параллельные запросы должны выстраиваться в очередь, ждали %v", elapsed)
+	}
+}
+
+func TestParseDomainRatePolicies(t *testing.T) {
+	policies, err := ParseDomainRatePolicies("Example.com=2s:3, news.ru=500ms")
+	if err != nil {
+		t.Fatalf("Неожиданная ошибка: %v", err)
+	}
+
+	if policy := policies["example.com"]; policy.Interval != 2*time.Second || policy.Burst != 3 {
+		t.Errorf("Неверная политика example.com: %+v", policy)
+	}
+	if policy := policies["news.ru"]; policy.Interval != 500*time.Millisecond || policy.Burst != 1 {
+		t.Errorf("Неверная политика news.ru: %+v", policy)
+	}
+
+	for _, value := range []string{"example.com", "example.com=abc", "example.com=1s:0"} {
+		if _, err := ParseDomainRatePolicies(value); err == nil {
+			t.Errorf("Ожидалась ошибка для %q", value)
+		}
+	}
+}
@@ -0,0 +1,208 @@
+package internal
+
+import (
+	"container/list"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Cache хранит ответы сайтов между запросами
+type Cache interface {
+	// Get возвращает неустаревший ответ или nil
+	Get(key string) *CachedResponse
+	// GetStale возвращает ответ даже после истечения срока, для условного запроса
+	GetStale(key string) *CachedResponse
+	// Set сохраняет ответ
+	Set(key string, response *CachedResponse)
+	// Delete удаляет ответ
+	Delete(key string)
+	// TTL возвращает время жизни ответа по умолчанию
+	TTL() time.Duration
+}
+
+// CachedResponse представляет кэшированный ответ
+type CachedResponse struct {
+	Content      []byte
+	Headers      http.Header
+	StatusCode   int
+	ExpiresAt    time.Time
+	ETag         string
+	LastModified string
+}
+
+// revalidatable проверяет, можно ли подтвердить устаревший ответ условным запросом
+func (r *CachedResponse) revalidatable() bool {
+	return r.ETag != "" || r.LastModified != ""
+}
+
+// size оценивает объем памяти, занимаемый ответом
+func (r *CachedResponse) size() int64 {
+	size := int64(len(r.Content) + len(r.ETag) + len(r.LastModified))
+	for name, values := range r.Headers {
+		size += int64(len(name))
+		for _, value := range values {
+			size += int64(len(value))
+		}
+	}
+	return size
+}
+
+// ResponseCache кэширует ответы в памяти с вытеснением давно неиспользуемых (LRU)
+type ResponseCache struct {
+	mu         sync.Mutex
+	cache      map[string]*CachedResponse
+	ttl        time.Duration
+	maxEntries int   // 0 - без ограничения
+	maxBytes   int64 // 0 - без ограничения
+	size       int64
+	order      *list.List // ключи от недавно использованных к давним
+	elements   map[string]*list.Element
+}
+
+// NewResponseCache создает кэш в памяти с ограничениями по количеству и объему
+func NewResponseCache(ttl time.Duration, maxEntries int, maxBytes int64) *ResponseCache {
+	return &ResponseCache{
+		cache:      make(map[string]*CachedResponse),
+		ttl:        ttl,
+		maxEntries: maxEntries,
+		maxBytes:   maxBytes,
+	}
+}
+
+// TTL возвращает время жизни ответа по умолчанию
+func (rc *ResponseCache) TTL() time.Duration {
+	return rc.ttl
+}
+
+// Get получает кэшированный ответ
+func (rc *ResponseCache) Get(key string) *CachedResponse {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	rc.ensureIndex()
+
+	cached, exists := rc.cache[key]
+	if !exists {
+		return nil
+	}
+
+	if time.Now().Before(cached.ExpiresAt) {
+		rc.order.MoveToFront(rc.elements[key])
+		return cached
+	}
+
+	// Устаревший ответ храним только если его можно подтвердить условным запросом
+	if !cached.revalidatable() {
+		rc.remove(key)
+	}
+	return nil
+}
+
+// GetStale получает кэшированный ответ независимо от срока его жизни
+func (rc *ResponseCache) GetStale(key string) *CachedResponse {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	rc.ensureIndex()
+
+	cached, exists := rc.cache[key]
+	if !exists {
+		return nil
+	}
+
+	rc.order.MoveToFront(rc.elements[key])
+	return cached
+}
+
+// Set сохраняет ответ в кэш
+func (rc *ResponseCache) Set(key string, response *CachedResponse) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	rc.ensureIndex()
+
+	rc.remove(key)
+
+	// Ответ больше всего кэша не сохраняем
+	if rc.maxBytes > 0 && response.size() > rc.maxBytes {
+		return
+	}
+
+	rc.cache[key] = response
+	rc.elements[key] = rc.order.PushFront(key)
+	rc.size += response.size()
+
+	rc.evict()
+}
+
+// Delete удаляет ответ из кэша
+func (rc *ResponseCache) Delete(key string) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	rc.ensureIndex()
+
+	rc.remove(key)
+}
+
+// ensureIndex инициализирует структуры LRU для кэша, созданного без конструктора
+func (rc *ResponseCache) ensureIndex() {
+	if rc.cache == nil {
+		rc.cache = make(map[string]*CachedResponse)
+	}
+	if rc.order != nil {
+		return
+	}
+
+	rc.order = list.New()
+	rc.elements = make(map[string]*list.Element, len(rc.cache))
+	rc.size = 0
+	for key, response := range rc.cache {
+		rc.elements[key] = rc.order.PushBack(key)
+		rc.size += response.size()
+	}
+}
+
+// remove удаляет запись без блокировки
+func (rc *ResponseCache) remove(key string) {
+	response, exists := rc.cache[key]
+	if !exists {
+		return
+	}
+
+	delete(rc.cache, key)
+	if element, ok := rc.elements[key]; ok {
+		rc.order.Remove(element)
+		delete(rc.elements, key)
+	}
+	rc.size -= response.size()
+}
+
+// evict вытесняет давно неиспользуемые записи сверх лимитов
+func (rc *ResponseCache) evict() {
+	for rc.order.Len() > 0 {
+		overEntries := rc.maxEntries > 0 && len(rc.cache) > rc.maxEntries
+		overBytes := rc.maxBytes > 0 && rc.size > rc.maxBytes
+		if !overEntries && !overBytes {
+			return
+		}
+
+		oldest := rc.order.Back()
+		rc.remove(oldest.Value.(string))
+	}
+}
+
+// NewCache создает кэш ответов в соответствии с конфигурацией
+func NewCache(config *Config) Cache {
+	ttl := time.Duration(config.CacheTTL) * time.Hour
+	maxBytes := int64(config.CacheMaxSizeMB) * 1024 * 1024
+
+	if config.CacheBackend == "disk" {
+		cache, err := NewDiskCache(config.CacheDir, ttl, config.CacheMaxEntries, maxBytes)
+		if err == nil {
+			return cache
+		}
+		logrus.Warnf("Не удалось открыть дисковый кэш, используем память: %v", err)
+	}
+
+	return NewResponseCache(ttl, config.CacheMaxEntries, maxBytes)
+}
@@ -0,0 +1,146 @@
+package internal
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+func newTestResponse(content string) *CachedResponse {
+	return &CachedResponse{
+		Content:    []byte(content),
+		StatusCode: 200,
+		ExpiresAt:  time.Now().Add(time.Hour),
+	}
+}
+
+func TestResponseCacheMaxEntries(t *testing.T) {
+	cache := NewResponseCache(time.Hour, 2, 0)
+
+	cache.Set("a", newTestResponse("a"))
+	cache.Set("b", newTestResponse("b"))
+
+	// Обращение к "a" делает "b" самым давним
+	cache.Get("a")
+	cache.Set("c", newTestResponse("c"))
+
+	if cache.Get("b") != nil {
+		t.Error("Давно неиспользуемая запись должна быть вытеснена")
+	}
+	if cache.Get("a") == nil || cache.Get("c") == nil {
+		t.Error("Недавние записи должны остаться в кэше")
+	}
+}
+
+func TestResponseCacheMaxBytes(t *testing.T) {
+	cache := NewResponseCache(time.Hour, 0, 10)
+
+	cache.Set("a", newTestResponse("12345"))
+	cache.Set("b", newTestResponse("67890"))
+	cache.Set("c", newTestResponse("abcde"))
+
+	if cache.Get("a") != nil {
+		t.Error("Запись сверх лимита объема должна быть вытеснена")
+	}
+	if cache.size > 10 {
+		t.Errorf("Объем кэша %d превышает лимит 10", cache.size)
+	}
+
+	// Ответ больше всего кэша не сохраняется
+	cache.Set("big", newTestResponse("this content is too large"))
+	if cache.Get("big") != nil {
+		t.Error("Слишком большой ответ не должен кэшироваться")
+	}
+}
+
+func TestResponseCacheConcurrentAccess(t *testing.T) {
+	cache := NewResponseCache(time.Hour, 50, 0)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(worker int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				key := fmt.Sprintf("key-%d", (worker*j)%70)
+				cache.Set(key, newTestResponse(key))
+				cache.Get(key)
+				if j%10 == 0 {
+					cache.Delete(key)
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if len(cache.cache) > 50 {
+		t.Errorf("Кэш содержит %d записей при лимите 50", len(cache.cache))
+	}
+}
+
+func TestDiskCachePersistence(t *testing.T) {
+	dir := t.TempDir()
+
+	cache, err := NewDiskCache(dir, time.Hour, 10, 0)
+	if err != nil {
+		t.Fatalf("Ошибка открытия кэша: %v", err)
+	}
+
+	response := newTestResponse("<html>cached page</html>")
+	response.ETag = "etag-1"
+	cache.Set("https://example.com/", response)
+
+	// Открываем кэш заново, как после перезапуска
+	reopened, err := NewDiskCache(dir, time.Hour, 10, 0)
+	if err != nil {
+		t.Fatalf("Ошибка повторного открытия кэша: %v", err)
+	}
+
+	cached := reopened.Get("https://example.com/")
+	if cached == nil {
+		t.Fatal("Запись должна сохраниться после перезапуска")
+	}
+	if string(cached.Content) != "<html>cached page</html>" {
+		t.Errorf("Неверный контент: %s", cached.Content)
+	}
+	if cached.ETag != "etag-1" {
+		t.Errorf("Неверный ETag: %s", cached.ETag)
+	}
+}
+
+func TestDiskCacheExpiration(t *testing.T) {
+	cache, err := NewDiskCache(t.TempDir(), time.Hour, 0, 0)
+	if err != nil {
+		t.Fatalf("Ошибка открытия кэша: %v", err)
+	}
+
+	expired := newTestResponse("old")
+	expired.ExpiresAt = time.Now().Add(-time.Minute)
+	cache.Set("old", expired)
+
+	if cache.Get("old") != nil {
+		t.Error("Устаревшая запись не должна возвращаться")
+	}
+}
+
+func TestDiskCacheEviction(t *testing.T) {
+	cache, err := NewDiskCache(t.TempDir(), time.Hour, 2, 0)
+	if err != nil {
+		t.Fatalf("Ошибка открытия кэша: %v", err)
+	}
+
+	cache.Set("a", newTestResponse("a"))
+	time.Sleep(time.Millisecond)
+	cache.Set("b", newTestResponse("b"))
+	time.Sleep(time.Millisecond)
+	cache.Get("a")
+	cache.Set("c", newTestResponse("c"))
+
+	if cache.Get("b") != nil {
+		t.Error("Давно неиспользуемая запись должна быть вытеснена")
+	}
+	if len(cache.index) != 2 {
+		t.Errorf("Ожидалось 2 записи, получено %d", len(cache.index))
+	}
+}
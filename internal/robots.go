@@ -0,0 +1,398 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// robotsMaxSize - RFC 9309 требует разбирать как минимум 500 KiB
+	robotsMaxSize = 500 * 1024
+	// robotsCacheTTL - RFC 9309 рекомендует не кэшировать robots.txt дольше 24 часов
+	robotsCacheTTL = 24 * time.Hour
+	// robotsErrorTTL - как долго помнить недоступность robots.txt
+	robotsErrorTTL = 10 * time.Minute
+	// maxCrawlDelay ограничивает Crawl-delay, чтобы один сайт не занимал обработчик надолго
+	maxCrawlDelay = 60 * time.Second
+)
+
+// robotsRule представляет одно правило Allow или Disallow
+type robotsRule struct {
+	allow   bool
+	pattern string
+}
+
+// RobotsRules содержит правила robots.txt, применимые к нашему user-agent
+type RobotsRules struct {
+	rules       []robotsRule
+	crawlDelay  time.Duration
+	disallowAll bool
+}
+
+// robotsGroup представляет группу правил для набора user-agent
+type robotsGroup struct {
+	agents     []string
+	rules      []robotsRule
+	crawlDelay time.Duration
+	hasDelay   bool
+}
+
+// allowAllRobots разрешает доступ ко всем путям
+func allowAllRobots() *RobotsRules {
+	return &RobotsRules{}
+}
+
+// disallowAllRobots запрещает доступ ко всем путям
+func disallowAllRobots() *RobotsRules {
+	return &RobotsRules{disallowAll: true}
+}
+
+// ParseRobotsTxt разбирает robots.txt по RFC 9309 и выбирает группы для productToken
+func ParseRobotsTxt(data []byte, productToken string) *RobotsRules {
+	if len(data) > robotsMaxSize {
+		data = data[:robotsMaxSize]
+	}
+
+	var groups []*robotsGroup
+	var current *robotsGroup
+	collectingAgents := false
+
+	text := strings.TrimPrefix(string(data), "\uFEFF")
+	for _, line := range strings.Split(text, "\n") {
+		// Убираем комментарии и пробелы
+		if idx := strings.IndexByte(line, '#'); idx >= 0 {
+			line = line[:idx]
+		}
+		line = strings.TrimSpace(line)
+
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+
+		switch key {
+		case "user-agent":
+			// Несколько строк user-agent подряд относятся к одной группе
+			if current == nil || !collectingAgents {
+				current = &robotsGroup{}
+				groups = append(groups, current)
+			}
+			current.agents = append(current.agents, strings.ToLower(value))
+			collectingAgents = true
+
+		case "allow", "disallow":
+			if current == nil {
+				continue
+			}
+			collectingAgents = false
+
+			// Пустой Disallow ничего не запрещает
+			if value == "" {
+				continue
+			}
+			current.rules = append(current.rules, robotsRule{
+				allow:   key == "allow",
+				pattern: normalizeRobotsPath(value),
+			})
+
+		case "crawl-delay":
+			if current == nil {
+				continue
+			}
+			collectingAgents = false
+
+			if seconds, err := strconv.ParseFloat(value, 64); err == nil && seconds >= 0 {
+				current.crawlDelay = time.Duration(seconds * float64(time.Second))
+				current.hasDelay = true
+			}
+		}
+	}
+
+	return selectRobotsGroups(groups, strings.ToLower(productToken))
+}
+
+// selectRobotsGroups объединяет группы для нашего токена, иначе группы "*"
+func selectRobotsGroups(groups []*robotsGroup, token string) *RobotsRules {
+	var matched []*robotsGroup
+	for _, group := range groups {
+		for _, agent := range group.agents {
+			if agent == token {
+				matched = append(matched, group)
+				break
+			}
+		}
+	}
+
+	if len(matched) == 0 {
+		for _, group := range groups {
+			for _, agent := range group.agents {
+				if agent == "*" {
+					matched = append(matched, group)
+					break
+				}
+			}
+		}
+	}
+
+	rules := &RobotsRules{}
+	for _, group := range matched {
+		rules.rules = append(rules.rules, group.rules...)
+		if group.hasDelay && group.crawlDelay > rules.crawlDelay {
+			rules.crawlDelay = group.crawlDelay
+		}
+	}
+
+	return rules
+}
+
+// IsAllowed проверяет путь (с query) по правилу самого длинного совпадения
+func (r *RobotsRules) IsAllowed(path string) bool {
+	// robots.txt всегда доступен
+	if path == "/robots.txt" {
+		return true
+	}
+
+	if r.disallowAll {
+		return false
+	}
+
+	path = normalizeRobotsPath(path)
+
+	longest := -1
+	allowed := true
+	for _, rule := range r.rules {
+		if !robotsPatternMatch(rule.pattern, path) {
+			continue
+		}
+
+		// При равной длине побеждает Allow
+		length := len(rule.pattern)
+		if length > longest || (length == longest && rule.allow) {
+			longest = length
+			allowed = rule.allow
+		}
+	}
+
+	return allowed
+}
+
+// CrawlDelay возвращает задержку между запросами, запрошенную сайтом
+func (r *RobotsRules) CrawlDelay() time.Duration {
+	if r.crawlDelay > maxCrawlDelay {
+		return maxCrawlDelay
+	}
+	return r.crawlDelay
+}
+
+// robotsPatternMatch сопоставляет путь с шаблоном, поддерживая * и $
+func robotsPatternMatch(pattern, path string) bool {
+	anchored := strings.HasSuffix(pattern, "$")
+	if anchored {
+		pattern = strings.TrimSuffix(pattern, "$")
+	}
+
+	parts := strings.Split(pattern, "*")
+	if !strings.HasPrefix(path, parts[0]) {
+		return false
+	}
+	pos := len(parts[0])
+
+	if len(parts) == 1 {
+		return !anchored || pos == len(path)
+	}
+
+	for _, part := range parts[1 : len(parts)-1] {
+		idx := strings.Index(path[pos:], part)
+		if idx < 0 {
+			return false
+		}
+		pos += idx + len(part)
+	}
+
+	last := parts[len(parts)-1]
+	if anchored {
+		return len(path)-pos >= len(last) && strings.HasSuffix(path, last)
+	}
+	return strings.Contains(path[pos:], last)
+}
+
+// normalizeRobotsPath приводит путь к единому виду percent-encoding
+func normalizeRobotsPath(path string) string {
+	const hex = "0123456789ABCDEF"
+
+	var b strings.Builder
+	for i := 0; i < len(path); i++ {
+		c := path[i]
+		switch {
+		case c == '%' && i+2 < len(path) && isHexDigit(path[i+1]) && isHexDigit(path[i+2]):
+			b.WriteByte('%')
+			b.WriteString(strings.ToUpper(path[i+1 : i+3]))
+			i += 2
+		case c >= 0x80 || c == ' ':
+			b.WriteByte('%')
+			b.WriteByte(hex[c>>4])
+			b.WriteByte(hex[c&0x0F])
+		default:
+			b.WriteByte(c)
+		}
+	}
+
+	return b.String()
+}
+
+// isHexDigit проверяет, является ли байт шестнадцатеричной цифрой
+func isHexDigit(c byte) bool {
+	return ('0' <= c && c <= '9') || ('a' <= c && c <= 'f') || ('A' <= c && c <= 'F')
+}
+
+// robotsProductToken извлекает токен продукта из строки User-Agent
+func robotsProductToken(userAgent string) string {
+	token := userAgent
+	if idx := strings.IndexAny(token, "/ "); idx >= 0 {
+		token = token[:idx]
+	}
+	return token
+}
+
+// robotsPath возвращает путь URL с query для проверки по robots.txt
+func robotsPath(u *url.URL) string {
+	path := u.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	if u.RawQuery != "" {
+		path += "?" + u.RawQuery
+	}
+	return path
+}
+
+// robotsEntry представляет закэшированные правила для хоста
+type robotsEntry struct {
+	rules     *RobotsRules
+	expiresAt time.Time
+}
+
+// RobotsCache загружает и кэширует robots.txt для каждого хоста
+type RobotsCache struct {
+	mu        sync.Mutex
+	client    *http.Client
+	userAgent string
+	entries   map[string]*robotsEntry
+}
+
+// NewRobotsCache создает кэш robots.txt
+func NewRobotsCache(client *http.Client, userAgent string) *RobotsCache {
+	return &RobotsCache{
+		client:    client,
+		userAgent: userAgent,
+		entries:   make(map[string]*robotsEntry),
+	}
+}
+
+// Rules возвращает правила robots.txt для хоста страницы. Отмена загрузки и
+// отказ сетевой политики возвращаются ошибкой и не кэшируются
+func (rc *RobotsCache) Rules(ctx context.Context, pageURL *url.URL) (*RobotsRules, error) {
+	// robots.txt действует для конкретной схемы, хоста и порта
+	key := pageURL.Scheme + "://" + pageURL.Host
+
+	rc.mu.Lock()
+	if entry, exists := rc.entries[key]; exists && time.Now().Before(entry.expiresAt) {
+		rc.mu.Unlock()
+		return entry.rules, nil
+	}
+	rc.mu.Unlock()
+
+	rules, ttl, err := rc.fetch(ctx, pageURL)
+	if err != nil {
+		return nil, err
+	}
+
+	rc.mu.Lock()
+	rc.entries[key] = &robotsEntry{rules: rules, expiresAt: time.Now().Add(ttl)}
+	rc.evictExpired()
+	rc.mu.Unlock()
+
+	return rules, nil
+}
+
+// evictExpired удаляет устаревшие записи
+func (rc *RobotsCache) evictExpired() {
+	now := time.Now()
+	for key, entry := range rc.entries {
+		if !now.Before(entry.expiresAt) {
+			delete(rc.entries, key)
+		}
+	}
+}
+
+// fetch загружает robots.txt и возвращает правила и срок их хранения.
+// Ошибка возвращается, только если загрузка прервана или запрещена сетевой
+// политикой: такой результат ничего не говорит о правилах сайта
+func (rc *RobotsCache) fetch(ctx context.Context, pageURL *url.URL) (*RobotsRules, time.Duration, error) {
+	robotsURL := fmt.Sprintf("%s://%s/robots.txt", pageURL.Scheme, pageURL.Host)
+
+	req, err := http.NewRequestWithContext(ctx, "GET", robotsURL, nil)
+	if err != nil {
+		logrus.Warnf("Ошибка создания запроса robots.txt: %v", err)
+		return disallowAllRobots(), robotsErrorTTL, nil
+	}
+	req.Header.Set("User-Agent", rc.userAgent)
+
+	resp, err := rc.client.Do(req)
+	if err != nil {
+		if abortErr := robotsAbortError(ctx, err); abortErr != nil {
+			return nil, 0, abortErr
+		}
+		// Недоступный robots.txt по RFC 9309 означает полный запрет
+		logrus.Warnf("robots.txt недоступен для %s: %v", pageURL.Host, err)
+		return disallowAllRobots(), robotsErrorTTL, nil
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		data, err := io.ReadAll(io.LimitReader(resp.Body, robotsMaxSize))
+		if err != nil {
+			if abortErr := robotsAbortError(ctx, err); abortErr != nil {
+				return nil, 0, abortErr
+			}
+			logrus.Warnf("Ошибка чтения robots.txt для %s: %v", pageURL.Host, err)
+			return disallowAllRobots(), robotsErrorTTL, nil
+		}
+		return ParseRobotsTxt(data, robotsProductToken(rc.userAgent)), robotsCacheTTL, nil
+
+	case resp.StatusCode >= 400 && resp.StatusCode < 500:
+		// robots.txt отсутствует, доступ разрешен
+		return allowAllRobots(), robotsCacheTTL, nil
+
+	default:
+		// Ошибка сервера по RFC 9309 означает полный запрет
+		logrus.Warnf("robots.txt недоступен для %s (статус %d)", pageURL.Host, resp.StatusCode)
+		return disallowAllRobots(), robotsErrorTTL, nil
+	}
+}
+
+// robotsAbortError возвращает ошибку загрузки robots.txt, если она вызвана
+// отменой задачи или сетевой политикой, а не недоступностью сайта
+func robotsAbortError(ctx context.Context, err error) error {
+	var blocked *BlockedError
+	if errors.As(err, &blocked) {
+		return blocked
+	}
+	if ctx.Err() != nil {
+		return stageError(ctx, err)
+	}
+	return nil
+}
@@ -0,0 +1,198 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRobotsRulesMatching(t *testing.T) {
+	robots := `# Пример robots.txt
+User-agent: *
+Disallow: /private
+Allow: /private/public
+Disallow: /*.pdf$
+Disallow: /search?*q=
+Disallow:
+
+user-agent: OtherBot
+DISALLOW: /
+`
+	rules := ParseRobotsTxt([]byte(robots), "TGNIP-Bot")
+
+	tests := []struct {
+		path     string
+		expected bool
+	}{
+		{"/", true},
+		{"/private", false},
+		{"/private/secret", false},
+		{"/private/public", true},
+		{"/private/public/page", true},
+		{"/docs/file.pdf", false},
+		{"/docs/file.pdf?download=1", true},
+		{"/docs/file.pdfx", true},
+		{"/search?lang=en&q=test", false},
+		{"/search?lang=en", true},
+		{"/robots.txt", true},
+	}
+
+	for _, tt := range tests {
+		if got := rules.IsAllowed(tt.path); got != tt.expected {
+			t.Errorf("IsAllowed(%q) = %v, want %v", tt.path, got, tt.expected)
+		}
+	}
+}
+
+func TestRobotsSpecificGroupOverridesWildcard(t *testing.T) {
+	robots := `User-agent: *
+Disallow: /
+
+User-agent: googlebot
+User-agent: tgnip-bot
+Disallow: /admin
+Crawl-delay: 2.5
+
+User-agent: TGNIP-Bot
+Disallow: /tmp
+`
+	rules := ParseRobotsTxt([]byte(robots), "TGNIP-Bot")
+
+	if !rules.IsAllowed("/articles/1") {
+		t.Error("Группа нашего бота должна заменять группу *")
+	}
+	if rules.IsAllowed("/admin/panel") {
+		t.Error("/admin должен быть запрещен группой с несколькими user-agent")
+	}
+	if rules.IsAllowed("/tmp/file") {
+		t.Error("Правила всех групп нашего бота должны объединяться")
+	}
+	if rules.CrawlDelay() != 2500*time.Millisecond {
+		t.Errorf("Ожидался Crawl-delay 2.5s, получен %v", rules.CrawlDelay())
+	}
+}
+
+func TestRobotsLongestMatchTieAllow(t *testing.T) {
+	rules := ParseRobotsTxt([]byte("User-agent: *\nDisallow: /page\nAllow: /page\n"), "TGNIP-Bot")
+	if !rules.IsAllowed("/page") {
+		t.Error("При равной длине правил должен побеждать Allow")
+	}
+}
+
+func TestRobotsPercentEncoding(t *testing.T) {
+	rules := ParseRobotsTxt([]byte("User-agent: *\nDisallow: /статьи\n"), "TGNIP-Bot")
+
+	u, _ := url.Parse("https://example.com/статьи/1")
+	if rules.IsAllowed(robotsPath(u)) {
+		t.Error("Путь с кириллицей должен сопоставляться с закодированным правилом")
+	}
+}
+
+// mustRobotsRules возвращает правила robots.txt или завершает тест
+func mustRobotsRules(t *testing.T, cache *RobotsCache, ctx context.Context, pageURL *url.URL) *RobotsRules {
+	t.Helper()
+
+	rules, err := cache.Rules(ctx, pageURL)
+	if err != nil {
+		t.Fatalf("Ошибка загрузки robots.txt: %v", err)
+	}
+	return rules
+}
+
+func TestRobotsCacheStatusHandling(t *testing.T) {
+	var requests int32
+	status := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.WriteHeader(status)
+		w.Write([]byte("User-agent: *\nDisallow: /private\n"))
+	}))
+	defer server.Close()
+
+	pageURL, _ := url.Parse(server.URL + "/private/page")
+	ctx := context.Background()
+
+	cache := NewRobotsCache(server.Client(), "TGNIP-Bot/1.0")
+	if mustRobotsRules(t, cache, ctx, pageURL).IsAllowed("/private/page") {
+		t.Error("Путь должен быть запрещен")
+	}
+
+	// Повторная проверка использует кэш
+	mustRobotsRules(t, cache, ctx, pageURL)
+	if atomic.LoadInt32(&requests) != 1 {
+		t.Errorf("robots.txt должен загружаться один раз, загружен %d", requests)
+	}
+
+	// 4xx - доступ разрешен
+	status = http.StatusNotFound
+	cache = NewRobotsCache(server.Client(), "TGNIP-Bot/1.0")
+	if !mustRobotsRules(t, cache, ctx, pageURL).IsAllowed("/private/page") {
+		t.Error("При 404 доступ должен быть разрешен")
+	}
+
+	// 5xx - полный запрет
+	status = http.StatusServiceUnavailable
+	cache = NewRobotsCache(server.Client(), "TGNIP-Bot/1.0")
+	if mustRobotsRules(t, cache, ctx, pageURL).IsAllowed("/") {
+		t.Error("При 5xx доступ должен быть запрещен")
+	}
+}
+
+func TestRobotsCacheAbortNotCached(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.Write([]byte("User-agent: *\nDisallow: /private\n"))
+	}))
+	defer server.Close()
+
+	pageURL, _ := url.Parse(server.URL + "/page")
+	cache := NewRobotsCache(server.Client(), "TGNIP-Bot/1.0")
+
+	// Отмененная задача не превращается в запрет и не попадает в кэш
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := cache.Rules(ctx, pageURL); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Ожидалась ошибка отмены, получено %v", err)
+	}
+	if !mustRobotsRules(t, cache, context.Background(), pageURL).IsAllowed("/page") {
+		t.Error("После отмены robots.txt должен загружаться заново")
+	}
+	if atomic.LoadInt32(&requests) != 1 {
+		t.Errorf("Ожидался один запрос robots.txt, выполнено %d", requests)
+	}
+
+	// Отказ сетевой политики возвращается как есть
+	policy := NewNetworkPolicy(false, nil)
+	blockedCache := NewRobotsCache(&http.Client{Transport: policy.Transport()}, "TGNIP-Bot/1.0")
+	var blocked *BlockedError
+	if _, err := blockedCache.Rules(context.Background(), pageURL); !errors.As(err, &blocked) || blocked.Kind != BlockUnsafeURL {
+		t.Errorf("Ожидался отказ сетевой политики, получено %v", err)
+	}
+}
+
+func TestCrawlDelayAppliedToRateLimiter(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/robots.txt" {
+			w.Write([]byte("User-agent: *\nCrawl-delay: 5\n"))
+			return
+		}
+		w.Write([]byte("<html><body>ok</body></html>"))
+	}))
+	defer server.Close()
+
+	scraper := NewEthicalScraper(&Config{HTTPTimeout: 30, CacheTTL: 1, UserAgent: "Test-Bot/1.0", AllowPrivateNetworks: true})
+	if _, err := scraper.ScrapeURL(context.Background(), server.URL); err != nil {
+		t.Fatalf("Ошибка запроса: %v", err)
+	}
+
+	u, _ := url.Parse(server.URL)
+	if got := scraper.rateLimit.policyFor(u.Hostname()).Interval; got != 5*time.Second {
+		t.Errorf("Ожидался интервал 5s из Crawl-delay, получен %v", got)
+	}
+}
@@ -0,0 +1,96 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Ограничения времени стадий обработки ссылки по умолчанию
+const (
+	DefaultFetchTimeout   = 120 * time.Second
+	DefaultProcessTimeout = 30 * time.Second
+	DefaultSendTimeout    = 60 * time.Second
+)
+
+// StageTimeouts ограничивает время каждой стадии обработки ссылки, 0 - без ограничения
+type StageTimeouts struct {
+	Fetch   time.Duration // загрузка и разбор страницы вместе с robots.txt, повторами и изображениями
+	Process time.Duration // оформление файла; по истечении блоки кода остаются без языка
+	Send    time.Duration // отправка файла в Telegram
+}
+
+// stageTimeouts применяется ко всем задачам обработки ссылок
+var stageTimeouts = StageTimeouts{
+	Fetch:   DefaultFetchTimeout,
+	Process: DefaultProcessTimeout,
+	Send:    DefaultSendTimeout,
+}
+
+// SetStageTimeouts задает ограничения времени стадий обработки ссылки
+func SetStageTimeouts(timeouts StageTimeouts) {
+	stageTimeouts = timeouts
+}
+
+// withStageTimeout ограничивает контекст временем стадии, при timeout <= 0
+// стадия завершается только вместе с задачей
+func withStageTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// stageError добавляет к ошибке стадии причину прерывания, если контекст стадии
+// завершен, а библиотека загрузки не сохранила ее при оборачивании ошибки
+func stageError(ctx context.Context, err error) error {
+	if err == nil || ctx.Err() == nil || errors.Is(err, ctx.Err()) {
+		return err
+	}
+	return fmt.Errorf("%w: %v", ctx.Err(), err)
+}
+
+// sendContext отправляет файл в Telegram с ограничением времени стадии отправки.
+// Библиотека Telegram не принимает контекст, поэтому при отмене обработчик
+// освобождается сразу, а начатый запрос завершается в фоне и файл еще может прийти
+func sendContext(ctx context.Context, bot *tgbotapi.BotAPI, c tgbotapi.Chattable) (tgbotapi.Message, error) {
+	ctx, cancel := withStageTimeout(ctx, stageTimeouts.Send)
+	defer cancel()
+
+	if err := ctx.Err(); err != nil {
+		return tgbotapi.Message{}, err
+	}
+
+	defer observeStage(stageSend, time.Now())
+	markSendStarted(ctx)
+
+	type sendResult struct {
+		message tgbotapi.Message
+		err     error
+	}
+
+	done := make(chan sendResult, 1)
+	go func() {
+		message, err := bot.Send(c)
+		done <- sendResult{message, err}
+	}()
+
+	select {
+	case result := <-done:
+		return result.message, result.err
+	case <-ctx.Done():
+		return tgbotapi.Message{}, ctx.Err()
+	}
+}
+
+// sendErrorMessage возвращает ответ об ошибке отправки файла. По истечении
+// времени отправки запрос продолжается в фоне, поэтому файл еще может прийти
+func sendErrorMessage(err error, locale Locale) string {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return locale.SendTimeoutMsg
+	}
+	return locale.ErrorSendingMsg
+}
@@ -0,0 +1,107 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// hangingFetcher ждет отмены контекста и теряет ее причину, как библиотеки загрузки
+type hangingFetcher struct{}
+
+func (hangingFetcher) Fetch(ctx context.Context, pageURL string) (*FetchResult, error) {
+	<-ctx.Done()
+	return nil, fmt.Errorf("загрузка %s прервана", pageURL)
+}
+
+func TestProcessURLFetchTimeout(t *testing.T) {
+	previousFetcher, previousTimeouts := fetcher, stageTimeouts
+	SetFetcher(hangingFetcher{})
+	SetStageTimeouts(StageTimeouts{Fetch: 50 * time.Millisecond})
+	defer func() {
+		SetFetcher(previousFetcher)
+		SetStageTimeouts(previousTimeouts)
+	}()
+
+	_, err := processURL(context.Background(), "https://example.com/slow", locales["en"], UserSettings{}.withDefaults())
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Ожидалось истечение времени загрузки, получено %v", err)
+	}
+	if msg := errorMessageFor(err, locales["en"]); msg != locales["en"].TimeoutMsg {
+		t.Errorf("Ожидалось сообщение о превышении времени, получено %q", msg)
+	}
+
+	// Отмена задачи не считается превышением времени
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := processURL(ctx, "https://example.com/slow", locales["en"], UserSettings{}.withDefaults()); !errors.Is(err, context.Canceled) {
+		t.Errorf("Ожидалась отмена, получено %v", err)
+	}
+}
+
+func TestCollyFetcherContext(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(5 * time.Second):
+		}
+	}))
+	defer server.Close()
+
+	config := DefaultCollyConfig()
+	config.Network = NewNetworkPolicy(true, nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := NewCollyFetcher(config).Fetch(ctx, server.URL+"/slow")
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Ожидалось истечение времени, получено %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Загрузка должна прерываться по контексту, прошло %v", elapsed)
+	}
+}
+
+func TestLanguageDetectionStopsOnCancel(t *testing.T) {
+	markdown := "Пример:\n\n```\npackage main\n\nimport \"fmt\"\n\nfunc main() {\n\tfmt.Println(\"hello\")\n}\n```\n"
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if result := processMarkdownWithLanguageDetection(ctx, markdown); result != markdown {
+		t.Errorf("После отмены блоки кода должны остаться без изменений: %s", result)
+	}
+}
+
+func TestProcessURLsCancelled(t *testing.T) {
+	stub := &countingFetcher{}
+	previous := fetcher
+	SetFetcher(stub)
+	defer SetFetcher(previous)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	results := processURLs(ctx, []string{"https://example.com/1", "https://example.com/2"}, locales["en"], UserSettings{}.withDefaults(), 1)
+	for _, result := range results {
+		if !errors.Is(result.Err, context.Canceled) {
+			t.Errorf("Ссылка %s не должна обрабатываться после отмены: %v", result.URL, result.Err)
+		}
+	}
+}
+
+func TestSendErrorMessage(t *testing.T) {
+	locale := locales["ru"]
+
+	if got := sendErrorMessage(fmt.Errorf("отправка: %w", context.DeadlineExceeded), locale); got != locale.SendTimeoutMsg {
+		t.Errorf("По истечении времени отправки нужно предупредить, что файл может прийти: %q", got)
+	}
+	if got := sendErrorMessage(errors.New("Bad Request"), locale); got != locale.ErrorSendingMsg {
+		t.Errorf("Ошибка Telegram должна сообщаться как неудачная отправка: %q", got)
+	}
+}
@@ -0,0 +1,92 @@
+package internal
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+	"unicode/utf16"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// urlPattern находит ссылки в тексте, которые Telegram не пометил сущностями
+var urlPattern = regexp.MustCompile(`(?i)https?://[^\s<>"'«»]+`)
+
+// ExtractURLs извлекает ссылки из текста сообщения и его сущностей (url и text_link)
+// в порядке появления, без повторов
+func ExtractURLs(message *tgbotapi.Message) []string {
+	var candidates []string
+
+	for _, entity := range message.Entities {
+		switch entity.Type {
+		case "url":
+			link := utf16Substring(message.Text, entity.Offset, entity.Length)
+			// Telegram распознает ссылки без схемы, например example.com/page
+			if !strings.Contains(link, "://") {
+				link = "http://" + link
+			}
+			candidates = append(candidates, link)
+		case "text_link":
+			candidates = append(candidates, entity.URL)
+		}
+	}
+
+	for _, match := range urlPattern.FindAllString(message.Text, -1) {
+		candidates = append(candidates, trimURLPunctuation(match))
+	}
+
+	seen := make(map[string]bool)
+	var urls []string
+	for _, candidate := range candidates {
+		candidate = strings.TrimSpace(candidate)
+		if !isHTTPURL(candidate) {
+			continue
+		}
+		// Варианты одной ссылки (http и https, с utm_ и без) обрабатываем один раз
+		key := CacheKey(candidate)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		urls = append(urls, candidate)
+	}
+
+	return urls
+}
+
+// utf16Substring возвращает часть строки по смещению и длине в единицах UTF-16,
+// в которых Telegram передает позиции сущностей
+func utf16Substring(text string, offset, length int) string {
+	units := utf16.Encode([]rune(text))
+	if offset < 0 || length < 0 || offset+length > len(units) {
+		return ""
+	}
+	return string(utf16.Decode(units[offset : offset+length]))
+}
+
+// trimURLPunctuation убирает знаки препинания, завершающие предложение после ссылки
+func trimURLPunctuation(link string) string {
+	for len(link) > 0 {
+		last := link[len(link)-1]
+		switch {
+		case strings.IndexByte(".,;:!?", last) >= 0:
+			link = link[:len(link)-1]
+		case last == ')' && strings.Count(link, "(") < strings.Count(link, ")"):
+			// Закрывающая скобка без пары относится к тексту, а не к ссылке
+			link = link[:len(link)-1]
+		default:
+			return link
+		}
+	}
+	return link
+}
+
+// isHTTPURL проверяет, что строка является ссылкой http или https
+func isHTTPURL(str string) bool {
+	if !IsValidURL(str) {
+		return false
+	}
+	u, _ := url.Parse(str)
+	scheme := strings.ToLower(u.Scheme)
+	return scheme == "http" || scheme == "https"
+}
@@ -0,0 +1,92 @@
+package internal
+
+import (
+	"reflect"
+	"testing"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+func TestExtractURLsFromText(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		expected []string
+	}{
+		{
+			name:     "Одна ссылка",
+			text:     "https://example.com/article",
+			expected: []string{"https://example.com/article"},
+		},
+		{
+			name:     "Ссылка в предложении",
+			text:     "Посмотри статью https://example.com/a, там интересно.",
+			expected: []string{"https://example.com/a"},
+		},
+		{
+			name:     "Несколько ссылок и повтор",
+			text:     "https://a.com\nhttps://b.com/x?y=1 и снова https://a.com",
+			expected: []string{"https://a.com", "https://b.com/x?y=1"},
+		},
+		{
+			name:     "Скобки",
+			text:     "(см. https://en.wikipedia.org/wiki/Go_(programming_language))",
+			expected: []string{"https://en.wikipedia.org/wiki/Go_(programming_language)"},
+		},
+		{
+			name:     "Без ссылок",
+			text:     "просто текст",
+			expected: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			urls := ExtractURLs(&tgbotapi.Message{Text: tt.text})
+			if !reflect.DeepEqual(urls, tt.expected) {
+				t.Errorf("Ожидалось %v, получено %v", tt.expected, urls)
+			}
+		})
+	}
+}
+
+func TestExtractURLsFromEntities(t *testing.T) {
+	// Эмодзи занимает две единицы UTF-16, кириллица - одну
+	text := "👉 Статья: example.com/page и ссылка"
+	message := &tgbotapi.Message{
+		Text: text,
+		Entities: []tgbotapi.MessageEntity{
+			{Type: "url", Offset: 11, Length: 16},
+			{Type: "text_link", Offset: 30, Length: 6, URL: "https://hidden.example.org/post"},
+			{Type: "bold", Offset: 0, Length: 2},
+		},
+	}
+
+	urls := ExtractURLs(message)
+	expected := []string{"http://example.com/page", "https://hidden.example.org/post"}
+	if !reflect.DeepEqual(urls, expected) {
+		t.Errorf("Ожидалось %v, получено %v", expected, urls)
+	}
+}
+
+func TestExtractURLsSkipsNonHTTP(t *testing.T) {
+	message := &tgbotapi.Message{
+		Text: "ftp://example.com/file",
+		Entities: []tgbotapi.MessageEntity{
+			{Type: "text_link", Offset: 0, Length: 3, URL: "tg://user?id=1"},
+		},
+	}
+
+	if urls := ExtractURLs(message); len(urls) != 0 {
+		t.Errorf("Ссылки не http(s) должны пропускаться, получено %v", urls)
+	}
+}
+
+func TestUTF16Substring(t *testing.T) {
+	if got := utf16Substring("😀abc", 2, 3); got != "abc" {
+		t.Errorf("Ожидалось 'abc', получено '%s'", got)
+	}
+	if got := utf16Substring("abc", 2, 5); got != "" {
+		t.Errorf("Для выхода за границы ожидалась пустая строка, получено '%s'", got)
+	}
+}
@@ -0,0 +1,297 @@
+package internal
+
+import (
+	"net"
+	"net/url"
+	"path"
+	"strings"
+	"sync"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// maxRedirectorUnwraps ограничивает вложенность ссылок-переадресаций
+const maxRedirectorUnwraps = 5
+
+// redirectorParams сопоставляет адреса переадресаций с параметром, в котором лежит
+// настоящая ссылка. Ключ - хост и путь, для Google хост проверяется отдельно
+var redirectorParams = map[string]string{
+	"l.facebook.com/l.php":     "u",
+	"lm.facebook.com/l.php":    "u",
+	"m.facebook.com/l.php":     "u",
+	"www.facebook.com/l.php":   "u",
+	"l.instagram.com/":         "u",
+	"vk.com/away.php":          "to",
+	"m.vk.com/away.php":        "to",
+	"www.youtube.com/redirect": "q",
+	"away.vk.com/away.php":     "to",
+}
+
+// shortenerHosts - сервисы коротких ссылок. Их страницы иногда переадресуют
+// через <meta http-equiv="refresh"> вместо HTTP редиректа
+var shortenerHosts = map[string]bool{
+	"t.co":        true,
+	"bit.ly":      true,
+	"goo.gl":      true,
+	"tinyurl.com": true,
+	"ow.ly":       true,
+	"buff.ly":     true,
+	"lnkd.in":     true,
+}
+
+// googleDomainSuffixes - зоны доменов поиска Google (google.com, google.co.uk, ...)
+var googleDomainSuffixes = map[string]bool{
+	"com": true, "ru": true, "by": true, "kz": true, "com.ua": true, "az": true, "am": true, "ge": true,
+	"co.uk": true, "ie": true, "de": true, "at": true, "ch": true, "fr": true, "be": true, "nl": true,
+	"lu": true, "it": true, "es": true, "pt": true, "pl": true, "cz": true, "sk": true, "hu": true,
+	"ro": true, "bg": true, "gr": true, "hr": true, "rs": true, "si": true, "lt": true, "lv": true,
+	"ee": true, "fi": true, "se": true, "no": true, "dk": true, "is": true, "com.tr": true, "co.il": true,
+	"ae": true, "com.sa": true, "com.eg": true, "co.za": true, "co.in": true, "com.pk": true, "co.id": true,
+	"com.sg": true, "com.my": true, "co.th": true, "com.vn": true, "com.ph": true, "com.hk": true,
+	"com.tw": true, "co.jp": true, "co.kr": true, "com.au": true, "co.nz": true, "ca": true,
+	"com.mx": true, "com.br": true, "com.ar": true, "cl": true, "com.co": true, "com.pe": true,
+}
+
+// NormalizeURL приводит ссылку к виду, пригодному для загрузки: разворачивает
+// переадресации Google, Facebook и подобных сервисов и AMP кэша, приводит хост
+// к нижнему регистру, убирает порт по умолчанию, фрагмент и параметры отслеживания
+func NormalizeURL(raw string) string {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil || u.Host == "" {
+		return raw
+	}
+
+	for i := 0; i < maxRedirectorUnwraps; i++ {
+		target, ok := unwrapRedirector(u)
+		if !ok {
+			break
+		}
+		u = target
+	}
+
+	u.Scheme = strings.ToLower(u.Scheme)
+	u.Host = strings.ToLower(u.Host)
+	if port := u.Port(); (u.Scheme == "http" && port == "80") || (u.Scheme == "https" && port == "443") {
+		u.Host = u.Hostname()
+	}
+	if u.Path == "" {
+		u.Path = "/"
+	}
+	u.Fragment = ""
+	u.RawFragment = ""
+	StripTrackingParams(u)
+
+	return u.String()
+}
+
+// unwrapRedirector возвращает настоящую ссылку из адреса переадресации
+func unwrapRedirector(u *url.URL) (*url.URL, bool) {
+	host := strings.ToLower(u.Hostname())
+	query := u.Query()
+
+	target := ""
+	switch {
+	case isGoogleHost(host) && u.Path == "/url":
+		target = query.Get("q")
+		if target == "" {
+			target = query.Get("url")
+		}
+	case isGoogleHost(host) && strings.HasPrefix(u.Path, "/amp/s/"):
+		// www.google.com/amp/s/example.com/article
+		target = "https://" + strings.TrimPrefix(u.Path, "/amp/s/")
+	case strings.HasSuffix(host, ".cdn.ampproject.org"):
+		// example-com.cdn.ampproject.org/c/s/example.com/article
+		for _, prefix := range []string{"/c/s/", "/v/s/", "/i/s/"} {
+			if strings.HasPrefix(u.Path, prefix) {
+				target = "https://" + strings.TrimPrefix(u.Path, prefix)
+				if u.RawQuery != "" {
+					target += "?" + u.RawQuery
+				}
+			}
+		}
+	default:
+		if param, ok := redirectorParams[host+u.Path]; ok {
+			target = query.Get(param)
+		}
+	}
+
+	if target == "" {
+		return nil, false
+	}
+
+	parsed, err := url.Parse(target)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return nil, false
+	}
+	return parsed, true
+}
+
+// isGoogleHost проверяет, что хост принадлежит поиску Google (google.com, google.ru, ...):
+// после "google." должна идти известная зона, иначе google.evil.com сошел бы за Google
+func isGoogleHost(host string) bool {
+	suffix, ok := strings.CutPrefix(strings.TrimPrefix(host, "www."), "google.")
+	return ok && googleDomainSuffixes[suffix]
+}
+
+// CacheKey возвращает ключ, одинаковый для вариантов одной страницы:
+// http и https, со слэшем в конце и без, с параметрами отслеживания и известная
+// каноническая ссылка. AMP версия получает свой ключ, пока ее каноническая
+// страница неизвестна: урезанный ответ не должен попасть в кэш полной страницы
+func CacheKey(raw string) string {
+	normalized := NormalizeURL(raw)
+	if canonical, ok := canonicalURLs.lookup(normalized); ok {
+		normalized = canonical
+	}
+	return urlIdentity(normalized)
+}
+
+// urlIdentity убирает из нормализованной ссылки различия, не меняющие страницу
+func urlIdentity(normalized string) string {
+	u, err := url.Parse(normalized)
+	if err != nil || u.Host == "" {
+		return normalized
+	}
+
+	if u.Scheme == "http" {
+		u.Scheme = "https"
+	}
+
+	u.Path = strings.TrimSuffix(u.Path, "/")
+	if u.Path == "" {
+		u.Path = "/"
+	}
+
+	// Порядок параметров не важен
+	u.RawQuery = u.Query().Encode()
+
+	return u.String()
+}
+
+// ResolveURL возвращает адрес, по которому нужно загружать страницу: нормализованную
+// ссылку или каноническую ссылку, если она уже известна для этой страницы
+func ResolveURL(raw string) string {
+	normalized := NormalizeURL(raw)
+	if canonical, ok := canonicalURLs.lookup(normalized); ok {
+		return canonical
+	}
+	return normalized
+}
+
+// isAMPDocument проверяет, что страница - AMP версия (<html amp> или <html ⚡>)
+func isAMPDocument(doc *goquery.Document) bool {
+	html := doc.Find("html").First()
+	_, amp := html.Attr("amp")
+	_, bolt := html.Attr("⚡")
+	return amp || bolt
+}
+
+// metaRefreshURL возвращает адрес из <meta http-equiv="refresh" content="0;URL=...">
+func metaRefreshURL(doc *goquery.Document, base *url.URL) string {
+	var target string
+	doc.Find("meta[http-equiv]").EachWithBreak(func(i int, s *goquery.Selection) bool {
+		if !strings.EqualFold(s.AttrOr("http-equiv", ""), "refresh") {
+			return true
+		}
+
+		content := s.AttrOr("content", "")
+		index := strings.Index(strings.ToLower(content), "url=")
+		if index < 0 {
+			return true
+		}
+
+		ref := strings.Trim(strings.TrimSpace(content[index+len("url="):]), `'"`)
+		target = resolveMetadataURL(base, ref)
+		return target == ""
+	})
+	return target
+}
+
+// isShortenerURL проверяет, что ссылка ведет на сервис коротких ссылок
+func isShortenerURL(raw string) bool {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return false
+	}
+	return shortenerHosts[strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")]
+}
+
+// sameSite проверяет, что ссылки ведут на один сайт (без учета www. и amp.)
+func sameSite(a, b string) bool {
+	ua, errA := url.Parse(a)
+	ub, errB := url.Parse(b)
+	if errA != nil || errB != nil {
+		return false
+	}
+	return siteHost(ua.Hostname()) == siteHost(ub.Hostname())
+}
+
+// siteHost убирает из хоста префиксы www. и amp.
+func siteHost(host string) string {
+	host = strings.ToLower(host)
+	if ip := net.ParseIP(host); ip != nil {
+		return host
+	}
+	for _, prefix := range []string{"www.", "amp.", "m."} {
+		host = strings.TrimPrefix(host, prefix)
+	}
+	return host
+}
+
+// usableCanonical проверяет, что каноническая ссылка указывает на ту же статью,
+// а не, например, на главную страницу сайта
+func usableCanonical(pageURL, canonical string) bool {
+	if canonical == "" || !sameSite(pageURL, canonical) {
+		return false
+	}
+
+	page, _ := url.Parse(pageURL)
+	target, _ := url.Parse(canonical)
+	pagePath := path.Clean("/" + page.Path)
+	targetPath := path.Clean("/" + target.Path)
+	return targetPath != "/" || pagePath == "/"
+}
+
+// maxCanonicalURLs ограничивает число запомненных канонических ссылок
+const maxCanonicalURLs = 10000
+
+// canonicalIndex запоминает канонические ссылки страниц, чтобы повторные запросы
+// AMP версии или варианта ссылки сразу шли к канонической странице
+type canonicalIndex struct {
+	mu   sync.RWMutex
+	urls map[string]string // urlIdentity -> каноническая ссылка
+}
+
+// canonicalURLs - канонические ссылки страниц, загруженных ботом
+var canonicalURLs = &canonicalIndex{urls: make(map[string]string)}
+
+// lookup возвращает известную каноническую ссылку для нормализованной ссылки
+func (c *canonicalIndex) lookup(normalized string) (string, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	canonical, ok := c.urls[urlIdentity(normalized)]
+	return canonical, ok
+}
+
+// remember связывает варианты ссылки на страницу с ее канонической ссылкой
+func (c *canonicalIndex) remember(canonical string, variants ...string) {
+	canonical = NormalizeURL(canonical)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// Простая защита от неограниченного роста: начинаем заново
+	if len(c.urls) >= maxCanonicalURLs {
+		c.urls = make(map[string]string)
+	}
+
+	for _, variant := range variants {
+		if variant == "" {
+			continue
+		}
+		key := urlIdentity(NormalizeURL(variant))
+		if key != urlIdentity(canonical) {
+			c.urls[key] = canonical
+		}
+	}
+}
@@ -0,0 +1,170 @@
+package internal
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestNormalizeURL(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"HTTPS://Example.COM:443/Path?b=1&utm_source=tg#section", "https://example.com/Path?b=1"},
+		{"http://example.com", "http://example.com/"},
+		{"https://www.google.com/url?q=https://example.com/a%3Fx%3D1&sa=D", "https://example.com/a?x=1"},
+		{"https://www.google.ru/url?url=https://example.com/b", "https://example.com/b"},
+		{"https://l.facebook.com/l.php?u=https%3A%2F%2Fexample.com%2Fc%3Ffbclid%3D1&h=AT0", "https://example.com/c"},
+		{"https://www.google.com/amp/s/example.com/news/1.amp", "https://example.com/news/1.amp"},
+		{"https://example-com.cdn.ampproject.org/c/s/example.com/news/2", "https://example.com/news/2"},
+		{"https://vk.com/away.php?to=https%3A%2F%2Fexample.com%2Fd", "https://example.com/d"},
+		{"https://www.google.com/search?q=go", "https://www.google.com/search?q=go"},
+		{"https://evilgoogle.com/url?q=https://example.com", "https://evilgoogle.com/url?q=https://example.com"},
+		{"https://google.evil.com/url?q=https://x.org/a", "https://google.evil.com/url?q=https://x.org/a"},
+		{"https://www.google.co.uk/url?q=https://x.org/a", "https://x.org/a"},
+	}
+
+	for _, tt := range tests {
+		if got := NormalizeURL(tt.input); got != tt.expected {
+			t.Errorf("Для %s ожидалось %s, получено %s", tt.input, tt.expected, got)
+		}
+	}
+}
+
+func TestCacheKeyVariants(t *testing.T) {
+	variants := []string{
+		"https://example.com/article/",
+		"http://example.com/article",
+		"https://example.com/article?utm_campaign=x",
+		"https://www.google.com/url?q=https://example.com/article",
+	}
+
+	expected := CacheKey("https://example.com/article")
+	for _, variant := range variants {
+		if key := CacheKey(variant); key != expected {
+			t.Errorf("Ключ для %s должен совпадать с %s, получен %s", variant, expected, key)
+		}
+	}
+
+	// Урезанная AMP версия не должна попадать в кэш полной страницы
+	for _, amp := range []string{
+		"https://example.com/article/amp",
+		"https://example.com/article.amp.html",
+		"https://example.com/article?amp=1",
+		"https://amp.example.com/article",
+	} {
+		if CacheKey(amp) == expected {
+			t.Errorf("Ключ AMP версии %s не должен совпадать с ключом канонической страницы", amp)
+		}
+	}
+
+	if CacheKey("https://example.com/a?b=2&a=1") != CacheKey("https://example.com/a?a=1&b=2") {
+		t.Error("Порядок параметров не должен влиять на ключ")
+	}
+	if CacheKey("https://example.com/a?id=1") == CacheKey("https://example.com/a?id=2") {
+		t.Error("Значимые параметры должны различать страницы")
+	}
+}
+
+func TestUsableCanonical(t *testing.T) {
+	tests := []struct {
+		page      string
+		canonical string
+		expected  bool
+	}{
+		{"https://amp.example.com/a", "https://www.example.com/a", true},
+		{"https://example.com/a", "https://example.com/", false},
+		{"https://example.com/a", "https://other.com/a", false},
+		{"https://example.com/", "https://example.com/", true},
+		{"https://example.com/a", "", false},
+	}
+
+	for _, tt := range tests {
+		if got := usableCanonical(tt.page, tt.canonical); got != tt.expected {
+			t.Errorf("usableCanonical(%s, %s) = %t, ожидалось %t", tt.page, tt.canonical, got, tt.expected)
+		}
+	}
+}
+
+func articleHTML(head, text string) []byte {
+	return []byte(`<html` + head + `<body><article><h1>Статья</h1>
+<p>` + text + ` This paragraph is long enough for readability to treat it as the main content of the page.</p>
+<p>Another paragraph with more words so that the article is not considered empty by the extractor.</p>
+</article></body></html>`)
+}
+
+func TestExtractContentFollowsAMPCanonical(t *testing.T) {
+	f := &imageFetcher{responses: map[string]*FetchResult{
+		"https://amp-test.example.com/news/1": imageResult("text/html", articleHTML(
+			` amp><head><link rel="canonical" href="https://www.amp-test.example.com/news/1"></head>`, "AMP version.")),
+		"https://www.amp-test.example.com/news/1": imageResult("text/html", articleHTML(
+			`><head><link rel="canonical" href="/news/1"><link rel="amphtml" href="https://amp-test.example.com/news/1"></head>`, "Full version.")),
+	}}
+
+	config := DefaultCollyConfig()
+	config.Fetcher = f
+
+	content, err := ExtractContentWithConfig(context.Background(), "https://www.google.com/url?q=https://amp-test.example.com/news/1", config)
+	if err != nil {
+		t.Fatalf("Ошибка извлечения: %v", err)
+	}
+
+	if !strings.Contains(content.Markdown, "Full version.") {
+		t.Errorf("Для AMP страницы должна загружаться каноническая: %s", content.Markdown)
+	}
+	if content.SourceURL() != "https://www.amp-test.example.com/news/1" {
+		t.Errorf("Источником должна быть каноническая ссылка, получено %s", content.SourceURL())
+	}
+
+	// Повторный запрос AMP версии сразу идет к канонической странице
+	if resolved := ResolveURL("https://amp-test.example.com/news/1?utm_source=x"); resolved != "https://www.amp-test.example.com/news/1" {
+		t.Errorf("Ожидалась каноническая ссылка, получено %s", resolved)
+	}
+}
+
+func TestExtractContentFollowsAMPCanonicalForms(t *testing.T) {
+	tests := []struct {
+		amp       string
+		canonical string
+	}{
+		{"https://amp-path.example.org/article/amp", "https://amp-path.example.org/article"},
+		{"https://amp.amp-host.example.com/article", "https://amp-host.example.com/article"},
+	}
+
+	for _, tt := range tests {
+		f := &imageFetcher{responses: map[string]*FetchResult{
+			tt.amp: imageResult("text/html", articleHTML(
+				` amp><head><link rel="canonical" href="`+tt.canonical+`"></head>`, "AMP version.")),
+			tt.canonical: imageResult("text/html", articleHTML(
+				`><head><link rel="canonical" href="`+tt.canonical+`"></head>`, "Full version.")),
+		}}
+
+		config := DefaultCollyConfig()
+		config.Fetcher = f
+
+		content, err := ExtractContentWithConfig(context.Background(), tt.amp, config)
+		if err != nil {
+			t.Fatalf("Ошибка извлечения %s: %v", tt.amp, err)
+		}
+		if !strings.Contains(content.Markdown, "Full version.") {
+			t.Errorf("Для %s должна загружаться каноническая %s: %s", tt.amp, tt.canonical, content.Markdown)
+		}
+	}
+}
+
+func TestFetchPageFollowsShortenerRefresh(t *testing.T) {
+	f := &imageFetcher{responses: map[string]*FetchResult{
+		"https://t.co/abc": imageResult("text/html", []byte(
+			`<html><head><meta http-equiv="refresh" content="0;URL=https://example.com/target?utm_medium=x"></head></html>`)),
+		"https://example.com/target": imageResult("text/html", []byte("<html>ok</html>")),
+	}}
+
+	result, err := fetchPage(context.Background(), f, "https://t.co/abc")
+	if err != nil {
+		t.Fatalf("Ошибка загрузки: %v", err)
+	}
+	if result.FinalURL != "https://example.com/target" || string(result.Body) != "<html>ok</html>" {
+		t.Errorf("Ожидался переход по meta refresh, получен %s", result.FinalURL)
+	}
+}
@@ -0,0 +1,180 @@
+package internal
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// userIdleTTL - через сколько неактивный пользователь удаляется из лимитера
+const userIdleTTL = 24 * time.Hour
+
+// UserLimitError сообщает, что пользователь превысил ограничение
+type UserLimitError struct {
+	Daily      bool          // исчерпана дневная квота, иначе превышен лимит в минуту
+	RetryAfter time.Duration // через сколько можно повторить запрос
+}
+
+func (e *UserLimitError) Error() string {
+	if e.Daily {
+		return fmt.Sprintf("дневная квота исчерпана, повтор через %v", e.RetryAfter)
+	}
+	return fmt.Sprintf("превышен лимит запросов в минуту, повтор через %v", e.RetryAfter)
+}
+
+// userUsage хранит историю запросов одного пользователя
+type userUsage struct {
+	recent   []time.Time // запросы за последнюю минуту
+	day      time.Time   // начало суток, к которым относится dayCount
+	dayCount int
+	lastSeen time.Time
+}
+
+// UserLimiter ограничивает число ссылок от одного пользователя
+// в минуту (скользящее окно) и за сутки (по UTC)
+type UserLimiter struct {
+	mu         sync.Mutex
+	perMinute  int // 0 - без ограничения
+	dailyQuota int // 0 - без ограничения
+	admins     map[int64]bool
+	users      map[int64]*userUsage
+	lastSweep  time.Time
+	now        func() time.Time
+}
+
+// NewUserLimiter создает лимитер пользователей; администраторы не ограничиваются
+func NewUserLimiter(perMinute, dailyQuota int, admins []int64) *UserLimiter {
+	ul := &UserLimiter{
+		perMinute:  perMinute,
+		dailyQuota: dailyQuota,
+		admins:     make(map[int64]bool, len(admins)),
+		users:      make(map[int64]*userUsage),
+		now:        time.Now,
+	}
+
+	for _, id := range admins {
+		ul.admins[id] = true
+	}
+	ul.lastSweep = ul.now()
+
+	return ul
+}
+
+// Allow учитывает count ссылок пользователя или возвращает *UserLimitError.
+// Ссылки одного сообщения учитываются вместе: сообщение либо принимается
+// целиком, либо отклоняется. Сообщение с большим числом ссылок, чем лимит
+// в минуту, принимается только при пустом окне и занимает его полностью
+func (ul *UserLimiter) Allow(userID int64, count int) error {
+	if count <= 0 || ul.admins[userID] || (ul.perMinute <= 0 && ul.dailyQuota <= 0) {
+		return nil
+	}
+
+	ul.mu.Lock()
+	defer ul.mu.Unlock()
+
+	now := ul.now()
+	ul.sweep(now)
+
+	usage, exists := ul.users[userID]
+	if !exists {
+		usage = &userUsage{}
+		ul.users[userID] = usage
+	}
+	usage.lastSeen = now
+
+	// Дневная квота сбрасывается в полночь UTC
+	day := now.UTC().Truncate(24 * time.Hour)
+	if !usage.day.Equal(day) {
+		usage.day = day
+		usage.dayCount = 0
+	}
+	if ul.dailyQuota > 0 && usage.dayCount+count > ul.dailyQuota {
+		return &UserLimitError{Daily: true, RetryAfter: day.Add(24 * time.Hour).Sub(now)}
+	}
+
+	// Оставляем только запросы за последнюю минуту
+	windowStart := now.Add(-time.Minute)
+	kept := usage.recent[:0]
+	for _, t := range usage.recent {
+		if t.After(windowStart) {
+			kept = append(kept, t)
+		}
+	}
+	usage.recent = kept
+
+	if ul.perMinute > 0 && len(usage.recent) > 0 && len(usage.recent)+count > ul.perMinute {
+		// Ждем, пока из окна выйдет достаточно ссылок
+		expire := min(len(usage.recent), len(usage.recent)+count-ul.perMinute)
+		return &UserLimitError{RetryAfter: usage.recent[expire-1].Add(time.Minute).Sub(now)}
+	}
+
+	for i := 0; i < count; i++ {
+		usage.recent = append(usage.recent, now)
+	}
+	usage.dayCount += count
+	return nil
+}
+
+// sweep удаляет давно неактивных пользователей, чтобы карта не росла бесконечно
+func (ul *UserLimiter) sweep(now time.Time) {
+	if now.Sub(ul.lastSweep) < time.Hour {
+		return
+	}
+	ul.lastSweep = now
+
+	for id, usage := range ul.users {
+		if now.Sub(usage.lastSeen) > userIdleTTL {
+			delete(ul.users, id)
+		}
+	}
+}
+
+// ParseUserIDs разбирает список идентификаторов пользователей через запятую
+func ParseUserIDs(value string) ([]int64, error) {
+	var ids []int64
+
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		id, err := strconv.ParseInt(item, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("некорректный идентификатор пользователя %q", item)
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, nil
+}
+
+// formatRetryAfter форматирует время ожидания для сообщения пользователю
+func formatRetryAfter(d time.Duration, locale Locale) string {
+	seconds := int((d + time.Second - 1) / time.Second)
+	if seconds < 1 {
+		seconds = 1
+	}
+
+	if seconds < 60 {
+		return fmt.Sprintf(locale.SecondsFormat, seconds)
+	}
+
+	minutes := (seconds + 59) / 60
+	if minutes < 60 {
+		return fmt.Sprintf(locale.MinutesFormat, minutes)
+	}
+
+	return fmt.Sprintf(locale.HoursFormat, minutes/60, minutes%60)
+}
+
+// userLimitMessage возвращает локализованный ответ о превышении ограничения
+func userLimitMessage(err *UserLimitError, locale Locale) string {
+	retry := formatRetryAfter(err.RetryAfter, locale)
+	if err.Daily {
+		return fmt.Sprintf(locale.DailyQuotaMsg, retry)
+	}
+	return fmt.Sprintf(locale.UserRateLimitMsg, retry)
+}
@@ -0,0 +1,194 @@
+package internal
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeClock позволяет управлять временем в тестах лимитера
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func newTestUserLimiter(perMinute, dailyQuota int, admins []int64) (*UserLimiter, *fakeClock) {
+	clock := &fakeClock{now: time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)}
+	limiter := NewUserLimiter(perMinute, dailyQuota, admins)
+	limiter.now = clock.Now
+	limiter.lastSweep = clock.now
+	return limiter, clock
+}
+
+func TestUserLimiterPerMinute(t *testing.T) {
+	limiter, clock := newTestUserLimiter(2, 0, nil)
+
+	for i := 0; i < 2; i++ {
+		if err := limiter.Allow(1, 1); err != nil {
+			t.Fatalf("Запрос %d должен быть разрешен: %v", i, err)
+		}
+		clock.now = clock.now.Add(10 * time.Second)
+	}
+
+	err := limiter.Allow(1, 1)
+	var limitErr *UserLimitError
+	if !errors.As(err, &limitErr) {
+		t.Fatalf("Ожидалась ошибка UserLimitError, получено %v", err)
+	}
+	if limitErr.Daily {
+		t.Error("Ожидалось превышение лимита в минуту, а не дневной квоты")
+	}
+	if limitErr.RetryAfter != 40*time.Second {
+		t.Errorf("Ожидалось время ожидания 40s, получено %v", limitErr.RetryAfter)
+	}
+
+	// Другой пользователь не ограничен
+	if err := limiter.Allow(2, 1); err != nil {
+		t.Errorf("Лимит одного пользователя не должен влиять на другого: %v", err)
+	}
+
+	// После окончания окна запросы снова разрешены
+	clock.now = clock.now.Add(41 * time.Second)
+	if err := limiter.Allow(1, 1); err != nil {
+		t.Errorf("После окончания окна запрос должен быть разрешен: %v", err)
+	}
+}
+
+func TestUserLimiterDailyQuota(t *testing.T) {
+	limiter, clock := newTestUserLimiter(0, 2, nil)
+
+	limiter.Allow(1, 1)
+	limiter.Allow(1, 1)
+
+	err := limiter.Allow(1, 1)
+	var limitErr *UserLimitError
+	if !errors.As(err, &limitErr) || !limitErr.Daily {
+		t.Fatalf("Ожидалось исчерпание дневной квоты, получено %v", err)
+	}
+	if limitErr.RetryAfter != 12*time.Hour {
+		t.Errorf("Квота должна сбрасываться в полночь UTC, получено ожидание %v", limitErr.RetryAfter)
+	}
+
+	clock.now = clock.now.Add(12 * time.Hour)
+	if err := limiter.Allow(1, 1); err != nil {
+		t.Errorf("В новые сутки запрос должен быть разрешен: %v", err)
+	}
+}
+
+func TestUserLimiterAdmins(t *testing.T) {
+	limiter, _ := newTestUserLimiter(1, 1, []int64{42})
+
+	for i := 0; i < 5; i++ {
+		if err := limiter.Allow(42, 1); err != nil {
+			t.Fatalf("Администратор не должен ограничиваться: %v", err)
+		}
+	}
+}
+
+func TestUserLimiterCountsURLs(t *testing.T) {
+	limiter, clock := newTestUserLimiter(5, 8, nil)
+
+	if err := limiter.Allow(1, 3); err != nil {
+		t.Fatalf("Три ссылки должны быть разрешены: %v", err)
+	}
+
+	// Сообщение принимается целиком или отклоняется
+	clock.now = clock.now.Add(10 * time.Second)
+	var limitErr *UserLimitError
+	if err := limiter.Allow(1, 3); !errors.As(err, &limitErr) || limitErr.Daily {
+		t.Fatalf("Ожидалось превышение лимита в минуту, получено %v", err)
+	}
+	if limitErr.RetryAfter != 50*time.Second {
+		t.Errorf("Ожидалось время ожидания 50s, получено %v", limitErr.RetryAfter)
+	}
+	if err := limiter.Allow(1, 2); err != nil {
+		t.Fatalf("Две ссылки должны уложиться в лимит: %v", err)
+	}
+
+	// Больше ссылок, чем лимит в минуту, принимается при пустом окне
+	clock.now = clock.now.Add(time.Minute)
+	if err := limiter.Allow(1, 7); !errors.As(err, &limitErr) || !limitErr.Daily {
+		t.Fatalf("Ожидалось исчерпание дневной квоты, получено %v", err)
+	}
+	if err := limiter.Allow(1, 3); err != nil {
+		t.Errorf("Остаток квоты должен быть доступен: %v", err)
+	}
+}
+
+func TestDispatcherChargesPerURL(t *testing.T) {
+	d := NewDispatcher(nil, &Config{
+		WorkerCount:     1,
+		JobQueueSize:    1,
+		DedupTTL:        1,
+		DedupMaxEntries: 10,
+		UserDailyQuota:  5,
+	})
+
+	message := pendingTestMessage("без ссылок")
+	if d.limited(message) {
+		t.Fatal("Сообщение без ссылок не должно ограничиваться")
+	}
+	if _, exists := d.limiter.users[messageUserID(message)]; exists {
+		t.Error("Сообщение без ссылок не должно расходовать квоту")
+	}
+
+	message = pendingTestMessage("https://example.com/a https://example.com/b")
+	if d.limited(message) {
+		t.Fatal("Две ссылки должны уложиться в квоту")
+	}
+	if got := d.limiter.users[messageUserID(message)].dayCount; got != 2 {
+		t.Errorf("Ожидался учет двух ссылок, учтено %d", got)
+	}
+}
+
+func TestUserLimiterEvictsIdleUsers(t *testing.T) {
+	limiter, clock := newTestUserLimiter(5, 0, nil)
+
+	limiter.Allow(1, 1)
+	clock.now = clock.now.Add(userIdleTTL + 2*time.Hour)
+	limiter.Allow(2, 1)
+
+	if _, exists := limiter.users[1]; exists {
+		t.Error("Неактивный пользователь должен быть удален")
+	}
+}
+
+func TestParseUserIDs(t *testing.T) {
+	ids, err := ParseUserIDs("1, 42,,-100")
+	if err != nil {
+		t.Fatalf("Неожиданная ошибка: %v", err)
+	}
+	if len(ids) != 3 || ids[0] != 1 || ids[1] != 42 || ids[2] != -100 {
+		t.Errorf("Неверный список идентификаторов: %v", ids)
+	}
+
+	if _, err := ParseUserIDs("1,abc"); err == nil {
+		t.Error("Ожидалась ошибка для некорректного идентификатора")
+	}
+}
+
+func TestUserLimitMessage(t *testing.T) {
+	ru := locales["ru"]
+	en := locales["en"]
+
+	tests := []struct {
+		err      *UserLimitError
+		locale   Locale
+		expected string
+	}{
+		{&UserLimitError{RetryAfter: 1500 * time.Millisecond}, ru, "2 сек."},
+		{&UserLimitError{RetryAfter: 90 * time.Second}, en, "2 min"},
+		{&UserLimitError{Daily: true, RetryAfter: 3*time.Hour + 20*time.Minute}, ru, "3 ч 20 мин."},
+	}
+
+	for _, tt := range tests {
+		message := userLimitMessage(tt.err, tt.locale)
+		if !strings.Contains(message, tt.expected) {
+			t.Errorf("Сообщение %q должно содержать %q", message, tt.expected)
+		}
+	}
+}
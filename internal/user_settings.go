@@ -0,0 +1,118 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+)
+
+// Режимы отправки результата для сообщения с несколькими ссылками
+const (
+	BatchModeFiles = "files" // отдельный файл на каждую ссылку
+	BatchModeZip   = "zip"   // один zip архив
+)
+
+// UserSettings содержит настройки пользователя
+type UserSettings struct {
+	BatchMode string `json:"batch_mode,omitempty"`
+	Format    string `json:"format,omitempty"`
+	Images    bool   `json:"images,omitempty"` // сохранять изображения статьи в zip архив
+
+	// FrontMatter - вариант front matter для markdown файлов, пустая строка - без него
+	FrontMatter string `json:"front_matter,omitempty"`
+
+	// Template - шаблон оформления markdown файлов, пустая строка - стандартное оформление
+	Template string `json:"template,omitempty"`
+}
+
+// withDefaults заполняет незаданные настройки значениями по умолчанию
+func (s UserSettings) withDefaults() UserSettings {
+	if s.BatchMode == "" {
+		s.BatchMode = BatchModeFiles
+	}
+	if _, ok := GetOutputFormat(s.Format); !ok {
+		s.Format = DefaultOutputFormat
+	}
+	return s
+}
+
+// OutputFormat возвращает выбранный формат файлов
+func (s UserSettings) OutputFormat() OutputFormat {
+	format, ok := GetOutputFormat(s.Format)
+	if !ok {
+		format, _ = GetOutputFormat(DefaultOutputFormat)
+	}
+
+	if _, isMarkdown := format.(markdownFormat); isMarkdown {
+		return markdownFormat{frontMatter: s.FrontMatter, template: s.Template}
+	}
+	return format
+}
+
+// SettingsStore хранит настройки пользователей в памяти
+// и, если задан путь, сохраняет их в JSON файл
+type SettingsStore struct {
+	mu    sync.Mutex
+	path  string // пустой путь - без сохранения на диск
+	users map[int64]UserSettings
+}
+
+// NewSettingsStore создает хранилище настроек, загружая их из файла
+func NewSettingsStore(path string) (*SettingsStore, error) {
+	store := &SettingsStore{
+		path:  path,
+		users: make(map[int64]UserSettings),
+	}
+
+	if path == "" {
+		return store, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return store, nil
+		}
+		return nil, fmt.Errorf("ошибка чтения настроек пользователей: %w", err)
+	}
+
+	if err := json.Unmarshal(data, &store.users); err != nil {
+		return nil, fmt.Errorf("ошибка разбора настроек пользователей: %w", err)
+	}
+
+	return store, nil
+}
+
+// Get возвращает настройки пользователя
+func (s *SettingsStore) Get(userID int64) UserSettings {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.users[userID].withDefaults()
+}
+
+// Update изменяет настройки пользователя и сохраняет их
+func (s *SettingsStore) Update(userID int64, update func(*UserSettings)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	settings := s.users[userID]
+	update(&settings)
+	s.users[userID] = settings
+
+	if s.path == "" {
+		return nil
+	}
+
+	data, err := json.Marshal(s.users)
+	if err != nil {
+		return fmt.Errorf("ошибка сериализации настроек пользователей: %w", err)
+	}
+
+	if err := writeFileAtomic(s.path, data); err != nil {
+		return fmt.Errorf("ошибка записи настроек пользователей: %w", err)
+	}
+
+	return nil
+}
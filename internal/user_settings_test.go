@@ -0,0 +1,42 @@
+package internal
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestSettingsStoreDefaults(t *testing.T) {
+	store, err := NewSettingsStore("")
+	if err != nil {
+		t.Fatalf("Ошибка создания хранилища: %v", err)
+	}
+
+	if mode := store.Get(1).BatchMode; mode != BatchModeFiles {
+		t.Errorf("Ожидался режим по умолчанию %s, получен %s", BatchModeFiles, mode)
+	}
+}
+
+func TestSettingsStorePersistence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "settings.json")
+
+	store, err := NewSettingsStore(path)
+	if err != nil {
+		t.Fatalf("Ошибка создания хранилища: %v", err)
+	}
+
+	if err := store.Update(42, func(s *UserSettings) { s.BatchMode = BatchModeZip }); err != nil {
+		t.Fatalf("Ошибка сохранения настроек: %v", err)
+	}
+
+	reopened, err := NewSettingsStore(path)
+	if err != nil {
+		t.Fatalf("Ошибка повторного открытия хранилища: %v", err)
+	}
+
+	if mode := reopened.Get(42).BatchMode; mode != BatchModeZip {
+		t.Errorf("Настройки должны сохраняться после перезапуска, получен режим %s", mode)
+	}
+	if mode := reopened.Get(7).BatchMode; mode != BatchModeFiles {
+		t.Errorf("Для другого пользователя ожидался режим по умолчанию, получен %s", mode)
+	}
+}
@@ -1,6 +1,7 @@
 package internal
 
 import (
+	"context"
 	"crypto/tls"
 	"encoding/json"
 	"fmt"
@@ -16,12 +17,13 @@ type WebhookServer struct {
 	bot         *tgbotapi.BotAPI
 	config      *Config
 	server      *http.Server
+	dispatcher  *Dispatcher
 	webhookURL  string
 	secretToken string
 }
 
 // NewWebhookServer создает новый webhook сервер
-func NewWebhookServer(bot *tgbotapi.BotAPI, config *Config) *WebhookServer {
+func NewWebhookServer(bot *tgbotapi.BotAPI, config *Config, dispatcher *Dispatcher) *WebhookServer {
 	// Получаем секретный токен из переменной окружения
 	secretToken := os.Getenv("WEBHOOK_SECRET_TOKEN")
 	if secretToken == "" {
@@ -33,6 +35,7 @@ func NewWebhookServer(bot *tgbotapi.BotAPI, config *Config) *WebhookServer {
 	return &WebhookServer{
 		bot:         bot,
 		config:      config,
+		dispatcher:  dispatcher,
 		secretToken: secretToken,
 	}
 }
@@ -56,6 +59,7 @@ func (ws *WebhookServer) Start() error {
 	mux := http.NewServeMux()
 	mux.HandleFunc("/telegram/webhook", ws.handleWebhook)
 	mux.HandleFunc("/healthz", ws.handleHealth)
+	mux.Handle("/metrics", MetricsHandler(ws.config.MetricsToken))
 
 	// Создаем сервер
 	ws.server = &http.Server{
@@ -124,19 +128,21 @@ func (ws *WebhookServer) startHTTP() error {
 	return nil
 }
 
-// Stop останавливает webhook сервер
-func (ws *WebhookServer) Stop() error {
-	// Удаляем webhook только если бот доступен
-	if ws.bot != nil {
-		if err := ws.deleteWebhook(); err != nil {
-			logger.Errorf("Ошибка удаления webhook: %v", err)
-		}
+// Stop прекращает прием запросов и ждет завершения начатых до истечения ctx.
+// Webhook в Telegram не удаляется: пока бот перезапускается, Telegram
+// накапливает обновления и доставит их новому экземпляру
+func (ws *WebhookServer) Stop(ctx context.Context) error {
+	if ws.server == nil {
+		return nil
 	}
 
-	// Останавливаем сервер
-	if ws.server != nil {
-		return ws.server.Close()
+	if err := ws.server.Shutdown(ctx); err != nil {
+		// Соединения, не завершенные вовремя, закрываем принудительно
+		ws.server.Close()
+		return fmt.Errorf("webhook сервер остановлен принудительно: %w", err)
 	}
+
+	logger.Info("Webhook сервер остановлен")
 	return nil
 }
 
@@ -164,27 +170,18 @@ func (ws *WebhookServer) SetWebhook() error {
 	return nil
 }
 
-// deleteWebhook удаляет webhook
-func (ws *WebhookServer) deleteWebhook() error {
-	_, err := ws.bot.Request(tgbotapi.DeleteWebhookConfig{})
-	if err != nil {
-		return fmt.Errorf("не удалось удалить webhook: %w", err)
-	}
-
-	logger.Info("Webhook удален")
-	return nil
-}
-
 // handleWebhook обрабатывает входящие webhook запросы
 func (ws *WebhookServer) handleWebhook(w http.ResponseWriter, r *http.Request) {
 	// Проверяем метод
 	if r.Method != http.MethodPost {
+		webhookRequests.WithLabelValues("bad_method").Inc()
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
 	// Проверяем Content-Type
 	if !strings.Contains(r.Header.Get("Content-Type"), "application/json") {
+		webhookRequests.WithLabelValues("bad_content_type").Inc()
 		http.Error(w, "Invalid content type", http.StatusBadRequest)
 		return
 	}
@@ -194,6 +191,7 @@ func (ws *WebhookServer) handleWebhook(w http.ResponseWriter, r *http.Request) {
 		token := r.Header.Get("X-Telegram-Bot-Api-Secret-Token")
 		if token != ws.secretToken {
 			logger.Warn("Неверный секретный токен webhook")
+			webhookRequests.WithLabelValues("bad_secret").Inc()
 			http.Error(w, "Unauthorized", http.StatusUnauthorized)
 			return
 		}
@@ -203,24 +201,27 @@ func (ws *WebhookServer) handleWebhook(w http.ResponseWriter, r *http.Request) {
 	var update tgbotapi.Update
 	if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
 		logger.Errorf("Ошибка декодирования webhook: %v", err)
+		webhookRequests.WithLabelValues("bad_json").Inc()
 		http.Error(w, "Invalid JSON", http.StatusBadRequest)
 		return
 	}
 
 	// Проверяем, что это сообщение
 	if update.Message == nil {
+		webhookRequests.WithLabelValues("ignored").Inc()
 		w.WriteHeader(http.StatusOK)
 		return
 	}
 
 	// Логируем входящее сообщение
+	webhookRequests.WithLabelValues("accepted").Inc()
 	logger.Infof("Получено webhook сообщение от пользователя %d", update.Message.Chat.ID)
 
-	// Обрабатываем сообщение напрямую только если бот доступен
-	if ws.bot != nil {
-		HandleMessage(ws.bot, update.Message)
+	// Ставим сообщение в очередь, обработка идет после ответа Telegram
+	if ws.dispatcher != nil {
+		ws.dispatcher.Dispatch(update)
 	} else {
-		logger.Debug("Bot не доступен, пропускаем обработку сообщения")
+		logger.Debug("Диспетчер не настроен, пропускаем обработку сообщения")
 	}
 
 	// Отправляем успешный ответ
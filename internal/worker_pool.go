@@ -0,0 +1,155 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// ErrQueueFull возвращается, когда в очереди нет места для новой задачи
+var ErrQueueFull = errors.New("очередь задач заполнена")
+
+// ErrPoolStopped возвращается при добавлении задачи в остановленный пул
+var ErrPoolStopped = errors.New("пул обработчиков остановлен")
+
+// Job представляет задачу на обработку входящего сообщения
+type Job struct {
+	UpdateID int
+	Message  *tgbotapi.Message
+
+	ctx         context.Context // отменяется командой /cancel
+	sendStarted atomic.Bool     // файл начал отправляться в Telegram
+}
+
+// Context возвращает контекст задачи; задача без контекста не отменяется
+func (j *Job) Context() context.Context {
+	if j.ctx == nil {
+		return context.Background()
+	}
+	return j.ctx
+}
+
+// JobHandler обрабатывает одну задачу
+type JobHandler func(job *Job)
+
+// WorkerPool обрабатывает задачи в ограниченном числе горутин
+type WorkerPool struct {
+	jobs    chan *Job
+	handler JobHandler
+	workers int
+
+	mu      sync.RWMutex
+	started bool
+	stopped bool
+	wg      sync.WaitGroup
+}
+
+// NewWorkerPool создает пул обработчиков с очередью заданного размера
+func NewWorkerPool(workers, queueSize int, handler JobHandler) *WorkerPool {
+	if workers < 1 {
+		workers = 1
+	}
+	if queueSize < 0 {
+		queueSize = 0
+	}
+
+	return &WorkerPool{
+		jobs:    make(chan *Job, queueSize),
+		handler: handler,
+		workers: workers,
+	}
+}
+
+// Start запускает обработчики
+func (p *WorkerPool) Start() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.started || p.stopped {
+		return
+	}
+	p.started = true
+
+	for i := 0; i < p.workers; i++ {
+		p.wg.Add(1)
+		go p.worker()
+	}
+
+	logger.Infof("Запущено %d обработчиков, размер очереди %d", p.workers, cap(p.jobs))
+}
+
+// Submit добавляет задачу в очередь без ожидания
+func (p *WorkerPool) Submit(job *Job) error {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if p.stopped {
+		return ErrPoolStopped
+	}
+
+	select {
+	case p.jobs <- job:
+		return nil
+	default:
+		return ErrQueueFull
+	}
+}
+
+// SubmitWait добавляет задачу в очередь, ожидая свободного места до истечения ctx
+func (p *WorkerPool) SubmitWait(ctx context.Context, job *Job) error {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if p.stopped {
+		return ErrPoolStopped
+	}
+
+	select {
+	case p.jobs <- job:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// QueueLength возвращает количество задач, ожидающих обработки
+func (p *WorkerPool) QueueLength() int {
+	return len(p.jobs)
+}
+
+// Stop прекращает прием задач и ждет завершения уже принятых
+func (p *WorkerPool) Stop() {
+	p.mu.Lock()
+	if p.stopped {
+		p.mu.Unlock()
+		return
+	}
+	p.stopped = true
+	close(p.jobs)
+	p.mu.Unlock()
+
+	p.wg.Wait()
+}
+
+// worker выполняет задачи из очереди
+func (p *WorkerPool) worker() {
+	defer p.wg.Done()
+
+	for job := range p.jobs {
+		p.run(job)
+	}
+}
+
+// run выполняет задачу, не давая панике остановить обработчик
+func (p *WorkerPool) run(job *Job) {
+	defer func() {
+		if r := recover(); r != nil {
+			logger.Errorf("Паника при обработке обновления %d: %v", job.UpdateID, r)
+		}
+	}()
+
+	p.handler(job)
+}
@@ -0,0 +1,80 @@
+package internal
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+)
+
+func TestWorkerPoolProcessesJobs(t *testing.T) {
+	var processed int32
+	pool := NewWorkerPool(2, 10, func(job *Job) {
+		atomic.AddInt32(&processed, 1)
+	})
+	pool.Start()
+
+	for i := 0; i < 5; i++ {
+		if err := pool.Submit(&Job{UpdateID: i}); err != nil {
+			t.Fatalf("Не удалось добавить задачу: %v", err)
+		}
+	}
+
+	pool.Stop()
+
+	if got := atomic.LoadInt32(&processed); got != 5 {
+		t.Errorf("Ожидалось 5 обработанных задач, обработано %d", got)
+	}
+}
+
+func TestWorkerPoolQueueFull(t *testing.T) {
+	release := make(chan struct{})
+	started := make(chan struct{}, 1)
+
+	pool := NewWorkerPool(1, 1, func(job *Job) {
+		started <- struct{}{}
+		<-release
+	})
+	pool.Start()
+
+	// Первая задача занимает обработчик
+	if err := pool.Submit(&Job{UpdateID: 1}); err != nil {
+		t.Fatalf("Не удалось добавить первую задачу: %v", err)
+	}
+	<-started
+
+	// Вторая задача занимает единственное место в очереди
+	if err := pool.Submit(&Job{UpdateID: 2}); err != nil {
+		t.Fatalf("Не удалось добавить вторую задачу: %v", err)
+	}
+
+	// Третья задача не помещается
+	if err := pool.Submit(&Job{UpdateID: 3}); !errors.Is(err, ErrQueueFull) {
+		t.Errorf("Ожидалась ErrQueueFull, получено %v", err)
+	}
+
+	close(release)
+	pool.Stop()
+
+	if err := pool.Submit(&Job{UpdateID: 4}); !errors.Is(err, ErrPoolStopped) {
+		t.Errorf("Ожидалась ErrPoolStopped, получено %v", err)
+	}
+}
+
+func TestWorkerPoolRecoversFromPanic(t *testing.T) {
+	var processed int32
+	pool := NewWorkerPool(1, 2, func(job *Job) {
+		if job.UpdateID == 1 {
+			panic("test panic")
+		}
+		atomic.AddInt32(&processed, 1)
+	})
+	pool.Start()
+
+	pool.Submit(&Job{UpdateID: 1})
+	pool.Submit(&Job{UpdateID: 2})
+	pool.Stop()
+
+	if got := atomic.LoadInt32(&processed); got != 1 {
+		t.Errorf("Обработчик должен продолжить работу после паники, обработано %d", got)
+	}
+}
@@ -1,7 +1,11 @@
 package main
 
 import (
+	"context"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 	"github.com/joho/godotenv"
@@ -12,6 +16,9 @@ import (
 
 var logger *logrus.Logger
 
+// updatesStopTimeout ограничивает остановку получения обновлений при завершении
+const updatesStopTimeout = 3 * time.Second
+
 func init() {
 	// Инициализация логгера
 	logger = logrus.New()
@@ -50,16 +57,59 @@ func main() {
 		logger.SetLevel(logrus.InfoLevel)
 	}
 
+	// Источник загрузки страниц
+	internal.SetNetworkPolicy(internal.NewNetworkPolicy(config.AllowPrivateNetworks, config.AllowedPorts))
+	internal.SetPageLimits(internal.PageLimits{
+		MaxBodySize:  int64(config.MaxBodySizeMB) * 1024 * 1024,
+		ContentTypes: config.AllowedContentTypes,
+	})
+	internal.SetFetcher(internal.NewFetcher(config))
+	internal.SetStageTimeouts(internal.StageTimeouts{
+		Fetch:   time.Duration(config.FetchTimeout) * time.Second,
+		Process: time.Duration(config.ProcessTimeout) * time.Second,
+		Send:    time.Duration(config.SendTimeout) * time.Second,
+	})
+	logger.Infof("Загрузка страниц через %s", config.FetcherBackend)
+
+	// Настройки пользователей и обработка нескольких ссылок
+	settingsStore, err := internal.NewSettingsStore(config.SettingsFile)
+	if err != nil {
+		logger.Fatalf("Не удалось загрузить настройки пользователей: %v", err)
+	}
+	internal.SetSettingsStore(settingsStore)
+	internal.SetBatchLimits(config.BatchConcurrency, config.BatchMaxURLs)
+	// Шаблоны с ошибками пропускаются, файлы оформляются стандартно
+	if config.TemplatesDir != "" {
+		templates, err := internal.LoadMarkdownTemplates(config.TemplatesDir)
+		if err != nil {
+			logger.Warnf("Шаблоны не загружены: %v", err)
+		} else {
+			logger.Infof("Загружены шаблоны markdown: %v", templates.Names())
+			internal.SetMarkdownTemplates(templates)
+		}
+	}
+	internal.SetReferenceLinksMin(config.ReferenceLinksMin)
+	internal.SetImageOptions(internal.ImageOptions{
+		MaxImageSize: int64(config.ImageMaxSizeKB) * 1024,
+		MaxImages:    config.ImageMaxCount,
+		MaxTotalSize: int64(config.ImageMaxTotalMB) * 1024 * 1024,
+	})
+
 	// Получение токена бота
 	token := os.Getenv("TELEGRAM_BOT_TOKEN")
 	if token == "" {
 		logger.Fatal("TELEGRAM_BOT_TOKEN не установлен")
 	}
 
+	// Проверяем режим работы
+	if config.BotMode != "webhook" && config.BotMode != "polling" {
+		logger.Fatalf("Неизвестный BOT_MODE=%s, допустимые значения: webhook, polling", config.BotMode)
+	}
+
 	// Получение URL для webhook
 	webhookURL := os.Getenv("WEBHOOK_URL")
-	if webhookURL == "" {
-		logger.Fatal("WEBHOOK_URL не установлен. Бот работает только в webhook режиме")
+	if config.BotMode == "webhook" && webhookURL == "" {
+		logger.Fatal("WEBHOOK_URL не установлен. Установите его или используйте BOT_MODE=polling")
 	}
 
 	// Проверяем, не является ли токен тестовым
@@ -77,14 +127,64 @@ func main() {
 		logger.Infof("Бот %s запущен", bot.Self.UserName)
 	}
 
-	// Запуск webhook сервера
+	// SIGTERM присылает fly.io при деплое, SIGINT - Ctrl+C
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Запуск обработчиков сообщений
+	dispatcher := internal.NewDispatcher(bot, config)
+	dispatcher.Start()
+
+	var stopUpdates func(context.Context)
+	if config.BotMode == "polling" {
+		stopUpdates = runPolling(bot, config, dispatcher)
+	} else {
+		stopUpdates = runWebhook(bot, config, dispatcher, webhookURL)
+	}
+
+	// Ждем сигнала завершения
+	<-ctx.Done()
+	stop()
+	logger.Infof("Получен сигнал завершения, ожидаем обработки сообщений до %d сек", config.ShutdownTimeout)
+
+	// Сначала перестаем принимать обновления. Запрос getUpdates может длиться
+	// до POLLING_TIMEOUT, поэтому его ожидание ограничено отдельно и не
+	// расходует время обработки принятых сообщений
+	updatesCtx, cancelUpdates := context.WithTimeout(context.Background(), updatesStopTimeout)
+	stopUpdates(updatesCtx)
+	cancelUpdates()
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), time.Duration(config.ShutdownTimeout)*time.Second)
+	defer cancel()
+
+	if err := dispatcher.Shutdown(shutdownCtx); err != nil {
+		logger.Warnf("Остановка с незавершенными задачами: %v", err)
+	}
+
+	logger.Info("Бот остановлен")
+}
+
+// runPolling запускает получение обновлений через long polling
+// и возвращает функцию его остановки
+func runPolling(bot *tgbotapi.BotAPI, config *internal.Config, dispatcher *internal.Dispatcher) func(context.Context) {
+	logger.Info("Запуск в режиме polling")
+	poller := internal.NewPoller(bot, config, dispatcher)
+
+	if err := poller.Start(); err != nil {
+		logger.Fatal("Ошибка запуска polling: ", err)
+	}
+
+	return poller.Stop
+}
+
+// runWebhook запускает webhook сервер и возвращает функцию его остановки
+func runWebhook(bot *tgbotapi.BotAPI, config *internal.Config, dispatcher *internal.Dispatcher, webhookURL string) func(context.Context) {
 	logger.Info("Запуск webhook сервера")
-	webhookServer := internal.NewWebhookServer(bot, config)
+	webhookServer := internal.NewWebhookServer(bot, config, dispatcher)
 
 	if err := webhookServer.Start(); err != nil {
 		logger.Fatal("Ошибка запуска webhook сервера: ", err)
 	}
-	defer webhookServer.Stop()
 
 	// Webhook должен быть установлен вручную через curl или Telegram API
 	// Код не устанавливает webhook автоматически
@@ -93,6 +193,9 @@ func main() {
 	logger.Infof("  -H 'Content-Type: application/json' \\")
 	logger.Infof("  -d '{\"url\": \"%s/telegram/webhook\", \"secret_token\": \"%s\"}'", webhookURL, os.Getenv("WEBHOOK_SECRET_TOKEN"))
 
-	// Ждем сигнала завершения
-	select {}
+	return func(ctx context.Context) {
+		if err := webhookServer.Stop(ctx); err != nil {
+			logger.Errorf("Ошибка остановки webhook сервера: %v", err)
+		}
+	}
 }